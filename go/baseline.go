@@ -0,0 +1,200 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/skeema/tengo"
+)
+
+// baselineDateFormat is the format of the expiry dates in a baseline file
+const baselineDateFormat = "2006-01-02"
+
+// BaselineEntry is a known difference between the two schemas, which is
+// accepted until it expires.
+type BaselineEntry struct {
+	// ID is the ID of the accepted change, see Change.ID
+	ID     string
+	Owner  string
+	Reason string
+	// Expires is the last day the entry is valid. A zero Expires never expires.
+	Expires time.Time
+}
+
+// Expired returns true if the entry is no longer valid at the given time
+func (e BaselineEntry) Expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires.AddDate(0, 0, 1))
+}
+
+// Baseline is the list of accepted differences between two schemas.
+//
+// Unlike a Filter, which hides differences, a baseline keeps track of them:
+// accepted changes are left out of the diff only while their entry is not
+// expired, and entries that no longer match any change are reported.
+type Baseline []BaselineEntry
+
+// BaselineReport tells which entries of a baseline accepted a change, which
+// ones expired, and which ones no longer match any change.
+type BaselineReport struct {
+	Accepted []BaselineEntry
+	Expired  []BaselineEntry
+	Stale    []BaselineEntry
+}
+
+// LoadBaseline reads the baseline in the file at path.
+// See ParseBaseline for the format of the file.
+func LoadBaseline(path string) (Baseline, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseBaseline(file)
+}
+
+// ParseBaseline reads a baseline from r. Entries are given one per line,
+// as the change ID, its owner, its expiry date (or - if it doesn't expire)
+// and the reason the change is accepted:
+//
+//	add_index:tasks.title_index  @dba   2019-12-31  Being rolled out with gh-ost
+//	drop_column:users.legacy_id  @core  -           Kept until the v1 API is retired
+//
+// Empty lines and lines starting with # are ignored.
+func ParseBaseline(r io.Reader) (Baseline, error) {
+	baseline := Baseline{}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("Invalid baseline entry in line %d: expected an id, an owner, an expiry date and a reason", n)
+		}
+		entry := BaselineEntry{
+			ID:     fields[0],
+			Owner:  fields[1],
+			Reason: strings.Join(fields[3:], " "),
+		}
+		if seen[entry.ID] {
+			return nil, fmt.Errorf("Invalid baseline entry in line %d: duplicated id %s", n, entry.ID)
+		}
+		seen[entry.ID] = true
+		if fields[2] != "-" {
+			expires, err := time.Parse(baselineDateFormat, fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid baseline entry in line %d: expiry date must be in the form YYYY-MM-DD, got %s", n, fields[2])
+			}
+			entry.Expires = expires
+		}
+		baseline = append(baseline, entry)
+	}
+	return baseline, scanner.Err()
+}
+
+// Check classifies the entries of the baseline given the changes in a diff
+// at the given time.
+func (b Baseline) Check(changes []Change, now time.Time) BaselineReport {
+	ids := map[string]bool{}
+	for _, c := range changes {
+		ids[c.ID] = true
+	}
+	var report BaselineReport
+	for _, e := range b {
+		switch {
+		case e.Expired(now):
+			report.Expired = append(report.Expired, e)
+		case !ids[e.ID]:
+			report.Stale = append(report.Stale, e)
+		default:
+			report.Accepted = append(report.Accepted, e)
+		}
+	}
+	return report
+}
+
+// reversed returns the baseline accepting the opposite changes of the ones
+// accepted by the receiver, for the diff in the other direction.
+func (b Baseline) reversed() Baseline {
+	if b == nil {
+		return nil
+	}
+	res := make(Baseline, len(b))
+	for i, e := range b {
+		res[i] = e
+		if sep := strings.Index(e.ID, ":"); sep > 0 {
+			res[i].ID = string(ChangeKind(e.ID[:sep]).opposite()) + e.ID[sep:]
+		}
+	}
+	return res
+}
+
+// Apply removes from ods the object diffs and alter clauses whose change is
+// accepted by an entry of the baseline not expired at the given time. Table
+// diffs whose reported alter clauses are all accepted are removed altogether.
+func (b Baseline) Apply(ods []tengo.ObjectDiff, now time.Time) []tengo.ObjectDiff {
+	if len(b) == 0 {
+		return ods
+	}
+	accepted := map[string]bool{}
+	for _, e := range b {
+		if !e.Expired(now) {
+			accepted[e.ID] = true
+		}
+	}
+
+	res := make([]tengo.ObjectDiff, 0, len(ods))
+	for _, od := range ods {
+		if td, ok := od.(*TableDiff); ok && td.DiffType() == tengo.DiffTypeAlter && td.AlterClauses() != nil {
+			var clauses []tengo.TableAlterClause
+			var reported bool
+			for _, c := range td.AlterClauses() {
+				change, ok := clauseChange(c, td)
+				if ok {
+					change.Table = td.From.Name
+					if accepted[change.id()] {
+						continue
+					}
+					reported = true
+				}
+				clauses = append(clauses, c)
+			}
+			if reported {
+				td.SetAlterClauses(clauses)
+				res = append(res, td)
+			}
+			continue
+		}
+
+		keep := true
+		for _, c := range changes([]tengo.ObjectDiff{od}) {
+			if accepted[c.ID] {
+				keep = false
+			}
+		}
+		if keep {
+			res = append(res, od)
+		}
+	}
+	return res
+}
@@ -0,0 +1,119 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestParseBaseline(t *testing.T) {
+	baseline, err := ParseBaseline(strings.NewReader(`
+		# id                          owner  expires     reason
+		add_index:tasks.title_index   @dba   2019-12-31  Being rolled out with gh-ost
+		drop_column:users.legacy_id   @core  -           Kept until the v1 API is retired
+	`))
+	Nil(t, err)
+	Equal(t, Baseline{
+		{ID: "add_index:tasks.title_index", Owner: "@dba", Reason: "Being rolled out with gh-ost", Expires: time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{ID: "drop_column:users.legacy_id", Owner: "@core", Reason: "Kept until the v1 API is retired"},
+	}, baseline)
+
+	False(t, baseline[0].Expired(time.Date(2019, 12, 31, 23, 59, 0, 0, time.UTC)))
+	True(t, baseline[0].Expired(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+	False(t, baseline[1].Expired(time.Now()))
+
+	_, err = ParseBaseline(strings.NewReader("add_index:tasks.title_index @dba -"))
+	Error(t, err)
+	_, err = ParseBaseline(strings.NewReader("add_index:tasks.title_index @dba 31/12/2019 reason"))
+	Error(t, err)
+	_, err = ParseBaseline(strings.NewReader("drop_table:tasks @dba - reason\ndrop_table:tasks @core - reason"))
+	Error(t, err)
+}
+
+func TestDiff_Baseline(t *testing.T) {
+	sql1 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+	}
+
+	sql2 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			updated_at DATETIME,
+			PRIMARY KEY (id),
+			KEY title_index (title)
+		)  ENGINE=INNODB;`,
+	}
+
+	s1Name, s2Name := TestCluster.LoadSchemas(t, sql1, sql2)
+	from := NewServer1Schema(s1Name)
+	to := NewServer2Schema(s2Name)
+
+	diff := NewDiff(DSN1, DSN2, from, to, false, "")
+	changes := diff.Changes()
+	Equal(t, 2, len(changes))
+	Equal(t, "add_index:tasks.title_index", changes[1].ID)
+
+	baseline, err := ParseBaseline(strings.NewReader(`
+		add_index:tasks.title_index  @dba   -           Being rolled out with gh-ost
+		add_column:tasks.updated_at  @core  2000-01-01  Pending migration
+		drop_table:users             @core  -           Users moved to another service
+	`))
+	Nil(t, err)
+	diff = NewDiff(DSN1, DSN2, from, to, false, "")
+	diff.Baseline = baseline
+
+	changes = diff.Changes()
+	Equal(t, 1, len(changes))
+	Equal(t, ChangeAddColumn, changes[0].Kind)
+
+	report := diff.BaselineReport()
+	Equal(t, []BaselineEntry{baseline[0]}, report.Accepted)
+	Equal(t, []BaselineEntry{baseline[1]}, report.Expired)
+	Equal(t, []BaselineEntry{baseline[2]}, report.Stale)
+
+	compactFmt, _ := NewFormatter("compact")
+	out := compactFmt.Format(diff).(string)
+	Regexp(t, "Differences found \\(1\\)", out)
+	Regexp(t, "Differences accepted by the baseline: 1", out)
+	Regexp(t, "\t- add_column:tasks.updated_at expired on 2000-01-01, owned by @core: Pending migration", out)
+	Regexp(t, "\t- drop_table:users, owned by @core: Users moved to another service", out)
+}
+
+func TestBaseline_Routines(t *testing.T) {
+	routines := []*tengo.Routine{
+		{Name: "next_id", Type: tengo.ObjectTypeProc, CreateStatement: "CREATE PROCEDURE `next_id`() SELECT 1"},
+		{Name: "next_id", Type: tengo.ObjectTypeFunc, CreateStatement: "CREATE FUNCTION `next_id`() RETURNS int RETURN 1"},
+	}
+	ods := tengo.NewSchemaDiff(&tengo.Schema{Name: "shop"}, &tengo.Schema{Name: "shop", Routines: routines}).ObjectDiffs()
+	ids := []string{}
+	for _, c := range changes(ods) {
+		ids = append(ids, c.ID)
+	}
+	ElementsMatch(t, []string{"create_routine:procedure:next_id", "create_routine:function:next_id"}, ids)
+
+	baseline := Baseline{{ID: "create_routine:function:next_id", Owner: "@core"}}
+	ods = baseline.Apply(ods, time.Now())
+	Equal(t, 1, len(ods))
+	Equal(t, tengo.ObjectTypeProc, ods[0].ObjectKey().Type)
+}
@@ -0,0 +1,182 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// LowerCaseTableNames returns the lower_case_table_names setting of a server
+func LowerCaseTableNames(instance *tengo.Instance) (int, error) {
+	db, err := instance.Connect("", "")
+	if err != nil {
+		return 0, err
+	}
+	var res int
+	err = db.QueryRow("SELECT @@lower_case_table_names").Scan(&res)
+	return res, err
+}
+
+// foldCase returns a copy of schema s whose tables, foreign key references
+// and routines are named after the ones in the names schema, when their
+// names only differ in case.
+//
+// It's meant for servers with different lower_case_table_names settings,
+// where the same schema can have differently cased names in each server.
+// The second schema of a diff is named after the first one, so objects are
+// matched ignoring case, and the statements of the diff still use the names
+// in the first server.
+func foldCase(s, names *tengo.Schema) *tengo.Schema {
+	res := *s
+	namesTables := foldedNames(tableNames(names))
+	sTables := foldedNames(tableNames(s))
+	res.Tables = make([]*tengo.Table, len(s.Tables))
+	for i, t := range s.Tables {
+		res.Tables[i] = t
+		if name, ok := foldedName(t.Name, namesTables, sTables); ok {
+			res.Tables[i] = renamedTable(t, name)
+		}
+		res.Tables[i] = foldReferences(res.Tables[i], namesTables)
+	}
+
+	namesRoutines := map[tengo.ObjectType]map[string][]string{
+		tengo.ObjectTypeProc: foldedNames(routineNames(names.ProceduresByName())),
+		tengo.ObjectTypeFunc: foldedNames(routineNames(names.FunctionsByName())),
+	}
+	sRoutines := map[tengo.ObjectType]map[string][]string{
+		tengo.ObjectTypeProc: foldedNames(routineNames(s.ProceduresByName())),
+		tengo.ObjectTypeFunc: foldedNames(routineNames(s.FunctionsByName())),
+	}
+	res.Routines = make([]*tengo.Routine, len(s.Routines))
+	for i, r := range s.Routines {
+		res.Routines[i] = r
+		if name, ok := foldedName(r.Name, namesRoutines[r.Type], sRoutines[r.Type]); ok {
+			renamed := *r
+			renamed.Name = name
+			renamed.CreateStatement = strings.Replace(r.CreateStatement, tengo.EscapeIdentifier(r.Name), tengo.EscapeIdentifier(name), 1)
+			res.Routines[i] = &renamed
+		}
+	}
+	return &res
+}
+
+// foldReferences returns table t, or a copy of it whose foreign keys
+// reference the tables in the given folded names, if they only differ in case.
+func foldReferences(t *tengo.Table, folded map[string][]string) *tengo.Table {
+	var fks []*tengo.ForeignKey
+	for i, fk := range t.ForeignKeys {
+		names := folded[strings.ToLower(fk.ReferencedTableName)]
+		if fk.ReferencedSchemaName != "" || len(names) != 1 || names[0] == fk.ReferencedTableName {
+			continue
+		}
+		if fks == nil {
+			fks = make([]*tengo.ForeignKey, len(t.ForeignKeys))
+			copy(fks, t.ForeignKeys)
+		}
+		referenced := *fk
+		referenced.ReferencedTableName = names[0]
+		fks[i] = &referenced
+	}
+	if fks == nil {
+		return t
+	}
+	res := *t
+	res.ForeignKeys = fks
+	// The CREATE TABLE statements of both tables still differ, which tengo
+	// takes as an unsupported difference when no other one is found.
+	res.CreateStatement = ""
+	return &res
+}
+
+// foldedName returns the only name in folded that matches name ignoring
+// case, as long as name is also the only one matching it in names, so
+// objects whose names collide are never taken as the same.
+func foldedName(name string, folded, names map[string][]string) (string, bool) {
+	matches := folded[strings.ToLower(name)]
+	if len(matches) != 1 || matches[0] == name || len(names[strings.ToLower(name)]) != 1 {
+		return "", false
+	}
+	return matches[0], true
+}
+
+// foldedNames groups names by their lowercase version
+func foldedNames(names []string) map[string][]string {
+	res := map[string][]string{}
+	for _, name := range names {
+		folded := strings.ToLower(name)
+		res[folded] = append(res[folded], name)
+	}
+	return res
+}
+
+func tableNames(s *tengo.Schema) []string {
+	names := make([]string, len(s.Tables))
+	for i, t := range s.Tables {
+		names[i] = t.Name
+	}
+	return names
+}
+
+func routineNames(routines map[string]*tengo.Routine) []string {
+	var names []string
+	for name := range routines {
+		names = append(names, name)
+	}
+	return names
+}
+
+// caseWarnings returns warnings about the names of the tables in schema s,
+// in the server at addr, that would collide or break if the schema was
+// moved to the server at otherAddr, with the given lower_case_table_names.
+func caseWarnings(s *tengo.Schema, addr, otherAddr string, lowerCaseTableNames, otherLowerCaseTableNames int) []string {
+	if lowerCaseTableNames == otherLowerCaseTableNames {
+		return nil
+	}
+
+	var warnings []string
+	folded := foldedNames(tableNames(s))
+	if otherLowerCaseTableNames != 0 {
+		var collisions []string
+		for _, names := range folded {
+			if len(names) > 1 {
+				sort.Strings(names)
+				collisions = append(collisions, fmt.Sprintf("Tables %s in %s.%s would collide in %s, which has lower_case_table_names=%d", strings.Join(names, ", "), s.Name, addr, otherAddr, otherLowerCaseTableNames))
+			}
+		}
+		sort.Strings(collisions)
+		warnings = append(warnings, collisions...)
+	}
+	if otherLowerCaseTableNames == 1 {
+		for _, t := range s.Tables {
+			if lower := strings.ToLower(t.Name); lower != t.Name {
+				warnings = append(warnings, fmt.Sprintf("Table %s in %s.%s would be named %s in %s, which has lower_case_table_names=1", t.Name, s.Name, addr, lower, otherAddr))
+			}
+		}
+	}
+	if otherLowerCaseTableNames == 0 {
+		for _, t := range s.Tables {
+			for _, fk := range t.ForeignKeys {
+				names := folded[strings.ToLower(fk.ReferencedTableName)]
+				if fk.ReferencedSchemaName == "" && len(names) == 1 && names[0] != fk.ReferencedTableName {
+					warnings = append(warnings, fmt.Sprintf("Foreign key %s of table %s in %s.%s references %s, which would not be found in %s as the table is named %s, and it has lower_case_table_names=0", fk.Name, t.Name, s.Name, addr, fk.ReferencedTableName, otherAddr, names[0]))
+				}
+			}
+		}
+	}
+	return warnings
+}
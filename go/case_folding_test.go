@@ -0,0 +1,91 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+// caseSchemas returns the same schema as loaded from a server with
+// lower_case_table_names=0 and from a server with lower_case_table_names=1.
+func caseSchemas() (*tengo.Schema, *tengo.Schema) {
+	id := &tengo.Column{Name: "id", TypeInDB: "int(11)", Default: tengo.ColumnDefaultNull}
+	userID := &tengo.Column{Name: "user_id", TypeInDB: "int(11)", Default: tengo.ColumnDefaultNull}
+	table := func(name, referenced string) []*tengo.Table {
+		return []*tengo.Table{
+			{Name: name, Engine: "InnoDB", CharSet: "latin1", Collation: "latin1_swedish_ci", Columns: []*tengo.Column{id},
+				CreateStatement: "CREATE TABLE `" + name + "` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1"},
+			{Name: "orders", Engine: "InnoDB", CharSet: "latin1", Collation: "latin1_swedish_ci", Columns: []*tengo.Column{id, userID},
+				ForeignKeys:     []*tengo.ForeignKey{{Name: "orders_user", Columns: []*tengo.Column{userID}, ReferencedTableName: referenced, ReferencedColumnNames: []string{"id"}}},
+				CreateStatement: "CREATE TABLE `orders` (\n  ...\n  CONSTRAINT `orders_user` FOREIGN KEY (`user_id`) REFERENCES `" + referenced + "` (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=latin1"},
+		}
+	}
+	routine := func(name string) []*tengo.Routine {
+		return []*tengo.Routine{{Name: name, Type: tengo.ObjectTypeProc, Body: "SELECT 1", CreateStatement: "CREATE PROCEDURE `" + name + "`()\nSELECT 1"}}
+	}
+	sensitive := &tengo.Schema{Name: "shop", CharSet: "latin1", Collation: "latin1_swedish_ci", Tables: table("Users", "Users"), Routines: routine("ArchiveOrders")}
+	insensitive := &tengo.Schema{Name: "shop", CharSet: "latin1", Collation: "latin1_swedish_ci", Tables: table("users", "users"), Routines: routine("archiveorders")}
+	return sensitive, insensitive
+}
+
+func TestDiff_LowerCaseTableNames(t *testing.T) {
+	from, to := caseSchemas()
+	diff := &Diff{DSN1: ParseDSN(DSN1), DSN2: ParseDSN(DSN2), From: from, To: to, objects1: &Objects{}, objects2: &Objects{}}
+
+	kinds := []ChangeKind{}
+	for _, c := range diff.Changes() {
+		kinds = append(kinds, c.Kind)
+	}
+	ElementsMatch(t, []ChangeKind{ChangeRenameTable, ChangeModifyForeignKey, ChangeDropRoutine, ChangeCreateRoutine}, kinds)
+	Empty(t, diff.Warnings())
+
+	diff = &Diff{DSN1: ParseDSN(DSN1), DSN2: ParseDSN(DSN2), From: from, To: to, LowerCaseNames2: 1, objects1: &Objects{}, objects2: &Objects{}}
+	Empty(t, diff.Changes())
+	Equal(t, []string{"Table Users in shop.127.0.0.1:33060 would be named users in 127.0.0.1:33062, which has lower_case_table_names=1"}, diff.Warnings())
+
+	compactFmt, _ := NewFormatter("compact")
+	Regexp(t, "No differences found\nWarnings \\(1\\):\n\t- Table Users in shop", compactFmt.Format(diff))
+}
+
+func TestDiff_LowerCaseTableNamesStatements(t *testing.T) {
+	from, to := caseSchemas()
+	users := *to.Tables[0]
+	users.Columns = []*tengo.Column{users.Columns[0], {Name: "name", TypeInDB: "varchar(255)", Nullable: true, Default: tengo.ColumnDefaultNull, CharSet: "latin1", Collation: "latin1_swedish_ci", CollationIsDefault: true}}
+	users.CreateStatement = "CREATE TABLE `users` (\n  `id` int(11) NOT NULL,\n  `name` varchar(255) DEFAULT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	to.Tables[0] = &users
+	diff := &Diff{DSN1: ParseDSN(DSN1), DSN2: ParseDSN(DSN2), From: from, To: to, LowerCaseNames2: 1, objects1: &Objects{}, objects2: &Objects{}}
+
+	// the statements are run in server1, where the table is named Users
+	sqlFmt, _ := NewFormatter("sql")
+	Contains(t, sqlFmt.Format(diff), "\nALTER TABLE `Users` ADD COLUMN `name` varchar(255) DEFAULT NULL;\n")
+	Contains(t, sqlFmt.Format(diff.Reverse()), "\nALTER TABLE `users` DROP COLUMN `name`;\n")
+}
+
+func TestCaseWarnings(t *testing.T) {
+	from, _ := caseSchemas()
+	from.Tables = append(from.Tables, &tengo.Table{Name: "USERS"})
+	Equal(t, []string{
+		"Tables USERS, Users in shop.server1 would collide in server2, which has lower_case_table_names=2",
+	}, caseWarnings(from, "server1", "server2", 0, 2))
+
+	_, to := caseSchemas()
+	to.Tables[0].Name = "Users"
+	Equal(t, []string{
+		"Foreign key orders_user of table orders in shop.server2 references users, which would not be found in server1 as the table is named Users, and it has lower_case_table_names=0",
+	}, caseWarnings(to, "server2", "server1", 2, 0))
+	Empty(t, caseWarnings(to, "server2", "server1", 2, 2))
+}
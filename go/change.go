@@ -0,0 +1,464 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// ChangeKind denotes the kind of a single difference between two schemas
+type ChangeKind string
+
+// Kinds of changes computed by a Diff
+const (
+	ChangeCreateTable      ChangeKind = "create_table"
+	ChangeDropTable        ChangeKind = "drop_table"
+	ChangeRenameTable      ChangeKind = "rename_table"
+	ChangeAlterTable       ChangeKind = "alter_table"
+	ChangeAddColumn        ChangeKind = "add_column"
+	ChangeDropColumn       ChangeKind = "drop_column"
+	ChangeModifyColumn     ChangeKind = "modify_column"
+	ChangeRenameColumn     ChangeKind = "rename_column"
+	ChangeColumnGeneration ChangeKind = "change_column_generation"
+	ChangeMoveColumn       ChangeKind = "move_column"
+	ChangeAddIndex         ChangeKind = "add_index"
+	ChangeDropIndex        ChangeKind = "drop_index"
+	ChangeModifyIndex      ChangeKind = "modify_index"
+	ChangeAddForeignKey    ChangeKind = "add_foreign_key"
+	ChangeDropForeignKey   ChangeKind = "drop_foreign_key"
+	ChangeModifyForeignKey ChangeKind = "modify_foreign_key"
+	ChangeAddCheck         ChangeKind = "add_check"
+	ChangeDropCheck        ChangeKind = "drop_check"
+	ChangeModifyCheck      ChangeKind = "modify_check"
+	ChangeCharSet          ChangeKind = "change_charset"
+	ChangeCreateOptions    ChangeKind = "change_create_options"
+	ChangeComment          ChangeKind = "change_comment"
+	ChangeStorageEngine    ChangeKind = "change_engine"
+	ChangeAlterDatabase    ChangeKind = "alter_database"
+	ChangeCreateRoutine    ChangeKind = "create_routine"
+	ChangeDropRoutine      ChangeKind = "drop_routine"
+	ChangeCreateTrigger    ChangeKind = "create_trigger"
+	ChangeDropTrigger      ChangeKind = "drop_trigger"
+	ChangeAlterTrigger     ChangeKind = "alter_trigger"
+	ChangeCreateView       ChangeKind = "create_view"
+	ChangeDropView         ChangeKind = "drop_view"
+	ChangeAlterView        ChangeKind = "alter_view"
+	ChangeCreateEvent      ChangeKind = "create_event"
+	ChangeDropEvent        ChangeKind = "drop_event"
+	ChangeAlterEvent       ChangeKind = "alter_event"
+)
+
+// Kinds of changes to the partitioning of tables
+const (
+	ChangePartitioning       ChangeKind = "change_partitioning"
+	ChangeRemovePartitioning ChangeKind = "remove_partitioning"
+	ChangeAddPartition       ChangeKind = "add_partition"
+	ChangeDropPartition      ChangeKind = "drop_partition"
+	ChangeModifyPartition    ChangeKind = "modify_partition"
+)
+
+// oppositeKinds are the kinds of changes undoing each other
+var oppositeKinds = map[ChangeKind]ChangeKind{
+	ChangeCreateTable:        ChangeDropTable,
+	ChangeAddColumn:          ChangeDropColumn,
+	ChangeAddIndex:           ChangeDropIndex,
+	ChangeAddForeignKey:      ChangeDropForeignKey,
+	ChangeAddCheck:           ChangeDropCheck,
+	ChangeCreateRoutine:      ChangeDropRoutine,
+	ChangeCreateTrigger:      ChangeDropTrigger,
+	ChangeCreateView:         ChangeDropView,
+	ChangeCreateEvent:        ChangeDropEvent,
+	ChangeAddPartition:       ChangeDropPartition,
+	ChangePartitioning:       ChangeRemovePartitioning,
+	ChangeDropTable:          ChangeCreateTable,
+	ChangeDropColumn:         ChangeAddColumn,
+	ChangeDropIndex:          ChangeAddIndex,
+	ChangeDropForeignKey:     ChangeAddForeignKey,
+	ChangeDropCheck:          ChangeAddCheck,
+	ChangeDropRoutine:        ChangeCreateRoutine,
+	ChangeDropTrigger:        ChangeCreateTrigger,
+	ChangeDropView:           ChangeCreateView,
+	ChangeDropEvent:          ChangeCreateEvent,
+	ChangeDropPartition:      ChangeAddPartition,
+	ChangeRemovePartitioning: ChangePartitioning,
+}
+
+// opposite returns the kind of the changes undoing the changes of kind k,
+// which is k itself for changes like modify_column.
+func (k ChangeKind) opposite() ChangeKind {
+	if opposite, ok := oppositeKinds[k]; ok {
+		return opposite
+	}
+	return k
+}
+
+// Change is a single difference between the two schemas of a Diff.
+//
+// Whereas the tengo.ObjectDiff values returned by Diff.Compute are meant to
+// be turned into DDL statements, changes are meant to be reported: each
+// of them corresponds to one line of the compact output, in a structure
+// that can be consumed by other tools.
+type Change struct {
+	// ID identifies the change across runs of the diff, see Baseline.
+	ID   string     `json:"id"`
+	Kind ChangeKind `json:"kind"`
+	// Severity tells how much the change can affect the applications using
+	// the schema.
+	Severity Severity `json:"severity"`
+	// Object is the type of the object changed: table, procedure, function...
+	Object tengo.ObjectType `json:"object_type"`
+	// Table is the name of the object changed.
+	Table string `json:"table"`
+	// Name is the name of the part of the table changed, like a column
+	// or an index. It's empty for changes affecting the whole object.
+	Name string `json:"name,omitempty"`
+	// From and To are the definitions of what changed in the first and
+	// second schema of the diff respectively.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// RenamedTo is the new name of renamed tables and columns
+	RenamedTo string `json:"renamed_to,omitempty"`
+	// Probable is true for renames that were guessed and not confirmed
+	// by a rename hint.
+	Probable bool `json:"probable,omitempty"`
+	// Origin is the tengo.ObjectDiff or tengo.TableAlterClause the change
+	// was computed from.
+	Origin interface{} `json:"-"`
+}
+
+// Changes computes the difference between the two schemas, returning it as a
+// list of changes. Migrations are not included, see MigrationsDiff.
+func (d *Diff) Changes() []Change {
+	return changes(d.Compute())
+}
+
+func changes(ods []tengo.ObjectDiff) []Change {
+	res := []Change{}
+	for _, od := range ods {
+		switch od := od.(type) {
+		case *TableDiff:
+			res = append(res, tableChanges(od)...)
+		case *RenameTableDiff:
+			res = append(res, Change{
+				Kind:      ChangeRenameTable,
+				Object:    tengo.ObjectTypeTable,
+				Table:     od.From.Name,
+				RenamedTo: od.To.Name,
+				Probable:  !od.Hinted,
+				Origin:    od,
+			})
+		case *tengo.RoutineDiff:
+			res = append(res, routineChange(od))
+		case *tengo.DatabaseDiff:
+			res = append(res, Change{
+				Kind:   ChangeAlterDatabase,
+				Object: tengo.ObjectTypeDatabase,
+				Table:  od.ObjectKey().Name,
+				From:   fmt.Sprintf("%s %s", od.From.CharSet, od.From.Collation),
+				To:     fmt.Sprintf("%s %s", od.To.CharSet, od.To.Collation),
+				Origin: od,
+			})
+		case *TriggerDiff:
+			res = append(res, triggerChange(od))
+		case *ViewDiff:
+			res = append(res, viewChange(od))
+		case *EventDiff:
+			res = append(res, eventChange(od))
+		case *PartitionDiff:
+			res = append(res, partitionChanges(od)...)
+		case *MigrationsDiff:
+			// Migrations are not part of the schema, and are reported separately
+		default:
+			log.Errorf("Unexpected Object Diff computing changes: %T. Ignoring", od)
+		}
+	}
+	for i := range res {
+		res[i].ID = res[i].id()
+		res[i].Severity = res[i].severity()
+	}
+	return res
+}
+
+// id returns an identifier of the change in the form kind:table[.name],
+// i.e. add_index:tasks.title_index, which only depends on what changed and
+// not on how, so it remains stable when the definitions involved vary.
+// Procedures and functions can share names, so the ids of routines also
+// include their type, i.e. create_routine:function:next_id.
+func (c Change) id() string {
+	if c.Object == tengo.ObjectTypeProc || c.Object == tengo.ObjectTypeFunc {
+		return fmt.Sprintf("%s:%s:%s", c.Kind, c.Object, c.Table)
+	}
+	if c.Name == "" {
+		return fmt.Sprintf("%s:%s", c.Kind, c.Table)
+	}
+	return fmt.Sprintf("%s:%s.%s", c.Kind, c.Table, c.Name)
+}
+
+// object returns the type and name of the object a change belongs to, i.e.
+// table tasks. Triggers and partitions belong to their table.
+func (c Change) object() string {
+	object := c.Object
+	if object == ObjectTypeTrigger || object == ObjectTypePartition {
+		object = tengo.ObjectTypeTable
+	}
+	return fmt.Sprintf("%s %s", object, c.Table)
+}
+
+// objectChanges are the changes of an object, see Change.object, along with
+// the highest of their severities.
+type objectChanges struct {
+	Name     string
+	Severity Severity
+	Changes  []Change
+}
+
+// changeGroups are changes grouped by the object they belong to, and counted
+// by kind, as the formatters reporting them by object show them.
+type changeGroups struct {
+	// Objects are the changes of each object, in the order they were computed
+	Objects []objectChanges
+	// Kinds are the kinds of the changes, in the order they were computed
+	Kinds []ChangeKind
+	// Counts are the number of changes of each kind
+	Counts map[ChangeKind]int
+}
+
+// groupChanges groups changes by the object they belong to, and counts them
+// by kind.
+func groupChanges(cs []Change) changeGroups {
+	res := changeGroups{Counts: map[ChangeKind]int{}}
+	objects := map[string]int{}
+	for _, c := range cs {
+		if res.Counts[c.Kind] == 0 {
+			res.Kinds = append(res.Kinds, c.Kind)
+		}
+		res.Counts[c.Kind]++
+
+		i, ok := objects[c.object()]
+		if !ok {
+			i = len(res.Objects)
+			objects[c.object()] = i
+			res.Objects = append(res.Objects, objectChanges{Name: c.object()})
+		}
+		res.Objects[i].Changes = append(res.Objects[i].Changes, c)
+		if c.Severity > res.Objects[i].Severity {
+			res.Objects[i].Severity = c.Severity
+		}
+	}
+	return res
+}
+
+// description describes the change in a few words, i.e. drop column title or
+// rename table tasks to todos.
+func (c Change) description() string {
+	res := strings.Replace(string(c.Kind), "_", " ", -1)
+	if c.Name != "" {
+		res += " " + c.Name
+	}
+	if c.RenamedTo != "" {
+		res += " to " + c.RenamedTo
+	}
+	return res
+}
+
+func tableChanges(td *TableDiff) []Change {
+	switch td.DiffType() {
+	case tengo.DiffTypeCreate:
+		return []Change{{Kind: ChangeCreateTable, Object: tengo.ObjectTypeTable, Table: td.To.Name, To: td.To.CreateStatement, Origin: td}}
+	case tengo.DiffTypeDrop:
+		return []Change{{Kind: ChangeDropTable, Object: tengo.ObjectTypeTable, Table: td.From.Name, From: td.From.CreateStatement, Origin: td}}
+	}
+
+	clauses := td.AlterClauses()
+	if clauses == nil {
+		// tengo could not break down the differences of the tables into clauses,
+		// as some of them use features tengo doesn't support.
+		return []Change{{Kind: ChangeAlterTable, Object: tengo.ObjectTypeTable, Table: td.From.Name, From: td.From.CreateStatement, To: td.To.CreateStatement, Origin: td}}
+	}
+
+	res := []Change{}
+	for _, c := range clauses {
+		if change, ok := clauseChange(c, td); ok {
+			change.Object = tengo.ObjectTypeTable
+			change.Table = td.From.Name
+			change.Origin = c
+			res = append(res, change)
+		}
+	}
+	return res
+}
+
+// clauseChange returns the change corresponding to an alter clause, or false
+// if the clause isn't reported as a change. That's the case of index and
+// foreign key clauses only there to reorder or rename them, of the clauses
+// adding back modified foreign keys, and of changes in the next
+// auto-increment value of a table. The change of the clauses adding back
+// foreign keys is returned anyway, so they are filtered out along with the
+// clauses dropping them.
+func clauseChange(c tengo.TableAlterClause, td *TableDiff) (Change, bool) {
+	mods := tengo.StatementModifiers{StrictForeignKeyNaming: td.StrictForeignKeyNaming, Flavor: td.FromFlavor}
+	switch c := c.(type) {
+	case tengo.AddColumn:
+		return Change{Kind: ChangeAddColumn, Name: c.Column.Name, To: c.Column.Definition(td.ToFlavor, c.Table)}, true
+	case tengo.DropColumn:
+		return Change{Kind: ChangeDropColumn, Name: c.Column.Name, From: c.Column.Definition(td.FromFlavor, td.From)}, true
+	case tengo.ModifyColumn:
+		return Change{Kind: ChangeModifyColumn, Name: c.OldColumn.Name, From: c.OldColumn.Definition(td.FromFlavor, td.From), To: c.NewColumn.Definition(td.ToFlavor, c.Table)}, true
+	case RenameColumn:
+		return Change{Kind: ChangeRenameColumn, Name: c.OldColumn.Name, From: c.OldColumn.Definition(td.FromFlavor, td.From), To: c.NewColumn.Definition(td.ToFlavor, c.Table), RenamedTo: c.NewColumn.Name, Probable: !c.Hinted}, true
+	case MoveColumn:
+		return Change{Kind: ChangeMoveColumn, Name: c.modifyColumn().NewColumn.Name, From: c.From, To: c.To}, true
+	case AlterColumn:
+		change, reported := clauseChange(c.Alter, td)
+		change.From, change.To = c.fromDefinition(td.FromFlavor, td.From), c.toDefinition(td.ToFlavor)
+		if change.Kind == ChangeModifyColumn && (c.FromGeneration == nil) != (c.ToGeneration == nil) {
+			change.Kind = ChangeColumnGeneration
+		}
+		return change, reported
+	case tengo.AddIndex:
+		if c.Clause(mods) == "" {
+			return Change{}, false
+		}
+		return Change{Kind: ChangeAddIndex, Name: c.Index.Name, To: c.Index.Definition(td.ToFlavor)}, true
+	case tengo.DropIndex:
+		if c.Clause(mods) == "" {
+			return Change{}, false
+		}
+		return Change{Kind: ChangeDropIndex, Name: c.Index.Name, From: c.Index.Definition(td.FromFlavor)}, true
+	case AlterIndex:
+		switch {
+		case c.From == nil:
+			return Change{Kind: ChangeAddIndex, Name: c.To.Name, To: c.To.Definition(td.ToFlavor)}, true
+		case c.To == nil:
+			return Change{Kind: ChangeDropIndex, Name: c.From.Name, From: c.From.Definition(td.FromFlavor)}, true
+		case c.Clause(mods) == "":
+			return Change{}, false
+		}
+		return Change{Kind: ChangeModifyIndex, Name: c.From.Name, From: c.From.Definition(td.FromFlavor), To: c.To.Definition(td.ToFlavor)}, true
+	case tengo.AddForeignKey:
+		if c.Clause(mods) == "" {
+			return Change{}, false
+		}
+		return Change{Kind: ChangeAddForeignKey, Name: c.ForeignKey.Name, To: c.ForeignKey.Definition(td.ToFlavor)}, true
+	case tengo.DropForeignKey:
+		if c.Clause(mods) == "" {
+			return Change{}, false
+		}
+		return Change{Kind: ChangeDropForeignKey, Name: c.ForeignKey.Name, From: c.ForeignKey.Definition(td.FromFlavor)}, true
+	case AlterForeignKey:
+		change := Change{Kind: ChangeModifyForeignKey, Name: c.From.Name, From: c.From.Definition(td.FromFlavor), To: c.To.Definition(td.ToFlavor)}
+		return change, !c.Added
+	case AlterCheck:
+		switch {
+		case c.From == nil:
+			return Change{Kind: ChangeAddCheck, Name: c.To.Name, To: c.To.Definition()}, true
+		case c.To == nil:
+			return Change{Kind: ChangeDropCheck, Name: c.From.Name, From: c.From.Definition()}, true
+		}
+		return Change{Kind: ChangeModifyCheck, Name: c.From.Name, From: c.From.Definition(), To: c.To.Definition()}, true
+	case tengo.ChangeCharSet:
+		return Change{Kind: ChangeCharSet, From: fmt.Sprintf("%s %s", td.From.CharSet, td.From.Collation), To: fmt.Sprintf("%s %s", c.CharSet, c.Collation)}, true
+	case tengo.ChangeCreateOptions:
+		return Change{Kind: ChangeCreateOptions, From: c.OldCreateOptions, To: c.NewCreateOptions}, true
+	case tengo.ChangeComment:
+		return Change{Kind: ChangeComment, From: td.From.Comment, To: c.NewComment}, true
+	case tengo.ChangeStorageEngine:
+		return Change{Kind: ChangeStorageEngine, From: td.From.Engine, To: c.NewStorageEngine}, true
+	case tengo.ChangeAutoIncrement:
+		return Change{}, false
+	default:
+		log.Errorf("Unexpected Table Alter Clause computing changes: %T. Ignoring", c)
+		return Change{}, false
+	}
+}
+
+func routineChange(rd *tengo.RoutineDiff) Change {
+	key := rd.ObjectKey()
+	change := Change{Object: key.Type, Table: key.Name, Origin: rd}
+	if rd.DiffType() == tengo.DiffTypeCreate {
+		change.Kind = ChangeCreateRoutine
+		change.To = rd.To.CreateStatement
+	} else {
+		change.Kind = ChangeDropRoutine
+		change.From = rd.From.CreateStatement
+	}
+	return change
+}
+
+// triggerChange returns the change corresponding to a trigger diff. As
+// triggers belong to tables, the table of the change is the trigger's, and
+// its name is the trigger's name.
+func triggerChange(td *TriggerDiff) Change {
+	change := Change{Object: ObjectTypeTrigger, Origin: td}
+	switch td.DiffType() {
+	case tengo.DiffTypeCreate:
+		change.Kind = ChangeCreateTrigger
+	case tengo.DiffTypeDrop:
+		change.Kind = ChangeDropTrigger
+	default:
+		change.Kind = ChangeAlterTrigger
+	}
+	if td.From != nil {
+		change.Table, change.Name, change.From = td.From.Table, td.From.Name, td.From.CreateStatement()
+	}
+	if td.To != nil {
+		change.Table, change.Name, change.To = td.To.Table, td.To.Name, td.To.CreateStatement()
+	}
+	return change
+}
+
+// viewChange returns the change corresponding to a view diff. The table of
+// the change is the view itself.
+func viewChange(vd *ViewDiff) Change {
+	change := Change{Object: ObjectTypeView, Origin: vd}
+	switch vd.DiffType() {
+	case tengo.DiffTypeCreate:
+		change.Kind = ChangeCreateView
+	case tengo.DiffTypeDrop:
+		change.Kind = ChangeDropView
+	default:
+		change.Kind = ChangeAlterView
+	}
+	if vd.From != nil {
+		change.Table, change.From = vd.From.Name, vd.From.CreateStatement()
+	}
+	if vd.To != nil {
+		change.Table, change.To = vd.To.Name, vd.To.CreateStatement()
+	}
+	return change
+}
+
+// eventChange returns the change corresponding to an event diff
+func eventChange(ed *EventDiff) Change {
+	change := Change{Object: ObjectTypeEvent, Origin: ed}
+	switch ed.DiffType() {
+	case tengo.DiffTypeCreate:
+		change.Kind = ChangeCreateEvent
+	case tengo.DiffTypeDrop:
+		change.Kind = ChangeDropEvent
+	default:
+		change.Kind = ChangeAlterEvent
+	}
+	if ed.From != nil {
+		change.Table, change.From = ed.From.Name, ed.From.CreateStatement()
+	}
+	if ed.To != nil {
+		change.Table, change.To = ed.To.Name, ed.To.CreateStatement()
+	}
+	return change
+}
@@ -0,0 +1,149 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// checkLine matches the definition of a CHECK constraint in the output of
+// SHOW CREATE TABLE, capturing its name, its clause, and whether it's not
+// enforced.
+var checkLine = regexp.MustCompile("^  CONSTRAINT `((?:[^`]|``)+)` CHECK (\\(.*\\))( /\\*!80016 NOT ENFORCED \\*/)?,?$")
+
+// Check is a CHECK constraint of a table, which tengo doesn't support.
+type Check struct {
+	Name string
+	// Clause is the condition of the constraint, enclosed in parentheses
+	Clause   string
+	Enforced bool
+}
+
+// Definition returns the definition of the constraint as in a CREATE TABLE
+// statement.
+func (c *Check) Definition() string {
+	def := fmt.Sprintf("CONSTRAINT %s CHECK %s", tengo.EscapeIdentifier(c.Name), c.Clause)
+	if !c.Enforced {
+		def += " /*!80016 NOT ENFORCED */"
+	}
+	return def
+}
+
+// differences returns the names of the attributes of the constraints that
+// differ
+func (c *Check) differences(other *Check) []string {
+	var res []string
+	if c.Clause != other.Clause {
+		res = append(res, "clause")
+	}
+	if c.Enforced != other.Enforced {
+		res = append(res, "enforcement")
+	}
+	return res
+}
+
+// liftChecks returns the given CREATE TABLE statement without its CHECK
+// constraints, along with them.
+func liftChecks(createStatement string) (string, []*Check) {
+	var checks []*Check
+	lines := strings.Split(createStatement, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		m := checkLine.FindStringSubmatch(line)
+		if m == nil {
+			kept = append(kept, line)
+			continue
+		}
+		name := strings.Replace(m[1], "``", "`", -1)
+		checks = append(checks, &Check{Name: name, Clause: m[2], Enforced: m[3] == ""})
+	}
+	if len(checks) == 0 {
+		return createStatement, nil
+	}
+	// CHECK constraints are the last definitions of the table, so the
+	// definition now last is no longer followed by a comma.
+	for i := 1; i < len(kept); i++ {
+		if strings.HasPrefix(kept[i], ")") {
+			kept[i-1] = strings.TrimSuffix(kept[i-1], ",")
+			break
+		}
+	}
+	return strings.Join(kept, "\n"), checks
+}
+
+// AlterCheck is a tengo.TableAlterClause representing a CHECK constraint that
+// differs between the tables. The constraint is added if From is nil, dropped
+// if To is nil, and otherwise modified.
+type AlterCheck struct {
+	From, To *Check
+}
+
+// Clause returns the clauses of an ALTER TABLE statement that drop the
+// constraint, add it, or both. Constraints only differing in enforcement are
+// altered in place.
+func (ac AlterCheck) Clause(mods tengo.StatementModifiers) string {
+	if ac.From == nil {
+		return fmt.Sprintf("ADD %s", ac.To.Definition())
+	}
+	drop := fmt.Sprintf("DROP CHECK %s", tengo.EscapeIdentifier(ac.From.Name))
+	if mods.Flavor.Vendor == tengo.VendorMariaDB {
+		drop = fmt.Sprintf("DROP CONSTRAINT %s", tengo.EscapeIdentifier(ac.From.Name))
+	}
+	if ac.To == nil {
+		return drop
+	}
+	differences := ac.From.differences(ac.To)
+	switch {
+	case len(differences) == 0:
+		return ""
+	case len(differences) == 1 && differences[0] == "enforcement" && ac.To.Enforced:
+		return fmt.Sprintf("ALTER CHECK %s ENFORCED", tengo.EscapeIdentifier(ac.To.Name))
+	case len(differences) == 1 && differences[0] == "enforcement":
+		return fmt.Sprintf("ALTER CHECK %s NOT ENFORCED", tengo.EscapeIdentifier(ac.To.Name))
+	}
+	return fmt.Sprintf("%s, ADD %s", drop, ac.To.Definition())
+}
+
+// alterChecks appends to the table diff the AlterCheck clauses of the CHECK
+// constraints that differ between the versions of a lifted table.
+func alterChecks(td *TableDiff, lt *liftedTable) {
+	fromChecks := map[string]*Check{}
+	for _, c := range lt.fromFeatures.checks {
+		fromChecks[c.Name] = c
+	}
+	toChecks := map[string]*Check{}
+	for _, c := range lt.toFeatures.checks {
+		toChecks[c.Name] = c
+	}
+
+	clauses := td.AlterClauses()
+	for _, c := range lt.fromFeatures.checks {
+		if _, ok := toChecks[c.Name]; !ok {
+			clauses = append(clauses, AlterCheck{From: c})
+		}
+	}
+	for _, c := range lt.toFeatures.checks {
+		from, ok := fromChecks[c.Name]
+		if !ok {
+			clauses = append(clauses, AlterCheck{To: c})
+		} else if len(from.differences(c)) > 0 {
+			clauses = append(clauses, AlterCheck{From: from, To: c})
+		}
+	}
+	td.SetAlterClauses(clauses)
+}
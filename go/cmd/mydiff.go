@@ -15,8 +15,12 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	mydiff "github.com/miguelff/mydiff/go"
 
@@ -26,13 +30,42 @@ import (
 
 const driver = "mysql"
 
+// Exit codes, see the description of the app. The values are part of the
+// interface of mydiff, so new codes are appended rather than renumbered. 17
+// was the status of the differences found by --check, which got its own code
+// space.
 const (
-	ESchemaNameNotProvided = iota + 1
-	EServInvalid
-	EMissingSchema
-	EUnkownFormatter
+	ESchemaNameNotProvided  = 1
+	EServInvalid            = 2
+	EMissingSchema          = 3
+	EUnkownFormatter        = 4
+	EConnectionFailed       = 5
+	EMigrationsUnavailable  = 6
+	EInvalidRenameHints     = 7
+	EInvalidFilter          = 8
+	EInvalidBaseline        = 9
+	EBaselineExpired        = 10
+	EInvalidSeverity        = 11
+	EInvalidTemplate        = 12
+	EUnknownMigrationTool   = 13
+	EMigrationNotWritten    = 14
+	EInvalidOnlineThreshold = 15
+	EObjectsUnavailable     = 16
+	EMissingTemplate        = 18
+	EFunctionalIndexes      = 19
 )
 
+// Exit codes of --check, which has its own code space so CI pipelines can
+// tell differences apart from errors: differences exit with status 1, as
+// diff(1) does, and errors with ECheckError plus their usual status.
+const (
+	EDifferencesFound = 1
+	ECheckError       = 100
+)
+
+// errDifferencesFound is returned by --check when differences are found.
+var errDifferencesFound = cli.NewExitError("", EDifferencesFound)
+
 func main() {
 	app := cli.NewApp()
 	app.Name = "mydiff"
@@ -40,6 +73,28 @@ func main() {
 	app.Usage = "Compute the differences between two MySQL schemas"
 	app.Copyright = "Copyright 2019 Miguel Fernández. Licensed under MIT license"
 	app.UsageText = "mydiff --server1=user:pass@tcp(host:port)/ --server2=user:pass@tcp(host:port)/ GLOBAL OPTIONS schema_name"
+	app.Description = `Exits with status 0 on success, or with one of these statuses otherwise:
+     1  schema_name not provided
+     2  invalid server DSN
+     3  schema missing in a server
+     4  unknown --diff-type
+     5  cannot connect to a server
+     6  cannot list the migrations of a server
+     7  invalid --rename-hints
+     8  invalid filter rules
+     9  invalid --baseline
+    10  expired baseline entries
+    11  invalid severity
+    12  invalid --format-template, or failing to execute it
+    13  unknown --emit-migration tool
+    14  cannot write the migration files
+    15  invalid --online-threshold
+    16  cannot load the triggers, views or events of a schema
+    18  --diff-type=template without --format-template
+    19  schema with functional key parts, which are not supported
+
+   With --check, exits with status 1 if differences are found, or with 100
+   plus one of the statuses above otherwise.`
 
 	app.HideHelp = true
 	app.HideVersion = true
@@ -56,17 +111,98 @@ func main() {
 		cli.StringFlag{
 			Name:  "d, diff-type",
 			Value: "compact",
-			Usage: "display differences in one of the following formats: [sql|compact]",
+			Usage: "display differences in one of the following formats: [sql|compact|json|junit|markdown|html|template|rails|liquibase|gh-ost|pt-osc]",
+		},
+		cli.StringFlag{
+			Name:  "format-template",
+			Usage: "display differences by executing this Go text/template file, implies --diff-type=template",
+		},
+		cli.StringFlag{
+			Name:  "online-threshold",
+			Value: "1GiB",
+			Usage: "with --diff-type=gh-ost or pt-osc, the size from which tables are altered with the online schema change tool rather than with ALTER TABLE (i.e. 512MiB)",
+		},
+		cli.StringFlag{
+			Name:  "emit-migration",
+			Usage: "instead of displaying differences, write the files of a migration applying them, and undoing them, for one of the following tools: [golang-migrate|flyway]",
+		},
+		cli.StringFlag{
+			Name:  "migration-name",
+			Value: "reconcile_schemas",
+			Usage: "with --emit-migration, the name of the migration, which follows its version in the names of the files",
+		},
+		cli.StringFlag{
+			Name:  "out",
+			Value: ".",
+			Usage: "with --emit-migration, the directory to write the files of the migration to",
 		},
 		cli.BoolFlag{
 			Name:  "diff-migrations",
-			Usage: "if the schema has a migrations table, compute its difference. Works only with compact formatting",
+			Usage: "if the schema has a migrations table, compute its difference. Works only with compact, json, junit, markdown, html, template, rails and liquibase formatting, and with --check",
 		},
 		cli.StringFlag{
 			Name:  "diff-migrations-column",
 			Value: "schema_migrations.version",
 			Usage: "if --diff-migrations is enabled, this flag will determine which column values to compare in both schemas",
 		},
+		cli.StringFlag{
+			Name:  "rename-hints",
+			Usage: "file confirming or denying the renames of tables and columns, one per line, in the form 'table <old> <new>' or 'column <table>.<old> <new>'. Prefix a line with ! to deny a rename",
+		},
+		cli.StringSliceFlag{
+			Name:  mydiff.IncludeTables,
+			Usage: "only diff the tables matching this regexp. Can be repeated",
+		},
+		cli.StringSliceFlag{
+			Name:  mydiff.ExcludeTables,
+			Usage: "don't diff the tables matching this regexp (i.e. '^_.*_gho$'). Can be repeated",
+		},
+		cli.StringSliceFlag{
+			Name:  mydiff.IgnoreColumns,
+			Usage: "don't diff the columns matching this table.column glob pattern (i.e. '*.updated_at'). Can be repeated",
+		},
+		cli.StringSliceFlag{
+			Name:  mydiff.IgnoreChanges,
+			Usage: "don't report this kind of change (i.e. add_index) or group of them: [tables|columns|indexes|foreign_keys|comments|charsets|create_options|engines|routines|databases|triggers|views|events|partitions|checks]. Can be repeated",
+		},
+		cli.StringFlag{
+			Name:  mydiff.MinSeverity,
+			Usage: "only report the changes of this severity or higher: [cosmetic|additive|risky|breaking]",
+		},
+		cli.StringFlag{
+			Name:  "ignore-file",
+			Value: mydiff.DefaultIgnoreFile,
+			Usage: "file to read the include-tables, exclude-tables, ignore-columns, ignore-changes and min-severity rules from, one per line in the form '<rule> <value>...'. Ignored if missing, unless explicitly given",
+		},
+		cli.StringFlag{
+			Name:  "baseline",
+			Usage: "file listing accepted differences, which are not reported until they expire, one per line in the form '<change id> <owner> <YYYY-MM-DD|-> <reason>'. Exits with an error if any entry expired",
+		},
+		cli.BoolFlag{
+			Name:  "strict",
+			Usage: "report the differences that are only due to the flavors or versions of the servers, like int(11) and int, utf8 and utf8mb3, or the default collations of each server",
+		},
+		cli.BoolFlag{
+			Name:  "strict-foreign-key-naming",
+			Usage: "report the foreign keys that only differ in name, which are otherwise taken as the same foreign key",
+		},
+		cli.BoolFlag{
+			Name:  "check",
+			Usage: "only print a summary of the differences, exiting with status 1 if any of them is of --check-severity or higher, or if there are pending migrations (see --diff-migrations)",
+		},
+		cli.StringFlag{
+			Name:  "check-severity",
+			Value: "cosmetic",
+			Usage: "with --check, the lowest severity of the differences making mydiff exit with status 1: [cosmetic|additive|risky|breaking]",
+		},
+		cli.BoolFlag{
+			Name:  "migrations-matrix",
+			Usage: "instead of diffing schemas, print which migrations (see --diff-migrations-column) are applied, missing or dirty in each of the servers given by --server1, --server2 and --server",
+		},
+		cli.StringSliceFlag{
+			Name:  "server",
+			Usage: "connection information for an additional server to include in --migrations-matrix, in the form of a DSN. Can be repeated",
+		},
 		cli.BoolFlag{
 			Name:  "r, reverse",
 			Usage: "show diff in reverse direction, from server2 to server1",
@@ -81,7 +217,7 @@ func main() {
 		},
 	}
 
-	app.Action = func(c *cli.Context) error {
+	run := func(c *cli.Context) error {
 		if c.GlobalBool("help") {
 			return cli.ShowAppHelp(c)
 		}
@@ -100,6 +236,30 @@ func main() {
 		if schema1 == "" {
 			return cli.NewExitError("schema_name has to be provided", ESchemaNameNotProvided)
 		}
+
+		if c.GlobalBool("migrations-matrix") {
+			var DSNs []string
+			for _, DSN := range append([]string{c.GlobalString("server1"), c.GlobalString("server2")}, c.GlobalStringSlice("server")...) {
+				if DSN != "" {
+					DSNs = append(DSNs, DSN)
+				}
+			}
+			matrix, err := mydiff.NewMigrationsMatrix(DSNs, schema1, c.GlobalString("diff-migrations-column"))
+			if err != nil {
+				return cli.NewExitError(err.Error(), EMigrationsUnavailable)
+			}
+			fmt.Print(matrix)
+			return nil
+		}
+
+		var threshold mydiff.Severity
+		if c.GlobalBool("check") {
+			var err error
+			if threshold, err = mydiff.ParseSeverity(c.GlobalString("check-severity")); err != nil {
+				return cli.NewExitError(err.Error(), EInvalidSeverity)
+			}
+		}
+
 		server1, err := tengo.NewInstance(driver, mydiff.ParseDSN(c.GlobalString("server1")).FormatDSN())
 		if err != nil {
 			return cli.NewExitError(fmt.Sprintf("server1 has to be a server DSN. Error: %s", err.Error()), EServInvalid)
@@ -108,24 +268,46 @@ func main() {
 		if err != nil {
 			return cli.NewExitError(fmt.Sprintf("server2 has to be a server DSN. Error: %s", err.Error()), EServInvalid)
 		}
-		from, err := server1.Schema(schema1)
+		defer server1.CloseAll()
+		defer server2.CloseAll()
+		if ok, err := server1.CanConnect(); !ok {
+			return cli.NewExitError(fmt.Sprintf("Cannot connect to server1. Error: %s", err), EConnectionFailed)
+		}
+		if ok, err := server2.CanConnect(); !ok {
+			return cli.NewExitError(fmt.Sprintf("Cannot connect to server2. Error: %s", err), EConnectionFailed)
+		}
+		from, err := loadSchema("server1", server1, schema1)
 		if err != nil {
-			return cli.NewExitError(fmt.Sprintf("server1 doesn't contain schema %s. Error: %s", schema1, err.Error()), EMissingSchema)
+			return err
 		}
-		to, err := server2.Schema(schema2)
+		to, err := loadSchema("server2", server2, schema2)
 		if err != nil {
-			return cli.NewExitError(fmt.Sprintf("server2 doesn't contain schema %s. Error: %s", schema2, err.Error()), EMissingSchema)
+			return err
 		}
 
-		formatter, err := mydiff.NewFormatter(c.GlobalString("diff-type"))
-		if err != nil {
+		var formatter mydiff.Formatter
+		if path := c.GlobalString("format-template"); path != "" {
+			tmpl, err := mydiff.ParseTemplate(path)
+			if err != nil {
+				return cli.NewExitError(fmt.Sprintf("Cannot read template from %s. Error: %s", path, err), EInvalidTemplate)
+			}
+			formatter = &mydiff.TemplateFormatter{Template: tmpl}
+		} else if strings.EqualFold(c.GlobalString("diff-type"), "template") {
+			return cli.NewExitError("--diff-type=template requires a template, see --format-template", EMissingTemplate)
+		} else if formatter, err = mydiff.NewFormatter(c.GlobalString("diff-type")); err != nil {
 			return cli.NewExitError(err, EUnkownFormatter)
 		}
+		var onlineThreshold int64
+		if _, ok := formatter.(*mydiff.OnlineSchemaChangeFormatter); ok {
+			if onlineThreshold, err = mydiff.ParseByteSize(c.GlobalString("online-threshold")); err != nil {
+				return cli.NewExitError(err.Error(), EInvalidOnlineThreshold)
+			}
+		}
 
 		var includeMigrations bool
 		var migrationsCol string
 
-		if formatter == mydiff.AvailableFormatters["compact"] {
+		if _, ok := formatter.(mydiff.MigrationsFormatter); ok || c.GlobalBool("check") {
 			includeMigrations = c.GlobalBool("diff-migrations")
 			migrationsCol = c.GlobalString("diff-migrations-column")
 		}
@@ -140,14 +322,166 @@ func main() {
 			server2 = sTmp
 		}
 
+		// The online schema change formatter is built for each run, as it
+		// tells the size of the tables in the server the diff is applied to.
+		if online, ok := formatter.(*mydiff.OnlineSchemaChangeFormatter); ok {
+			formatter = &mydiff.OnlineSchemaChangeFormatter{Tool: online.Tool, Threshold: onlineThreshold, TableSize: server1.TableSize}
+		}
+
 		diff := mydiff.NewDiff(server1.BaseDSN, server2.BaseDSN, from, to, includeMigrations, migrationsCol)
-		result := formatter.Format(diff)
-		fmt.Print(result)
+		diff.Flavor1, diff.Flavor2 = server1.Flavor(), server2.Flavor()
+		if diff.LowerCaseNames1, err = mydiff.LowerCaseTableNames(server1); err != nil {
+			return cli.NewExitError(fmt.Sprintf("Cannot read lower_case_table_names from server1. Error: %s", err), EConnectionFailed)
+		}
+		if diff.LowerCaseNames2, err = mydiff.LowerCaseTableNames(server2); err != nil {
+			return cli.NewExitError(fmt.Sprintf("Cannot read lower_case_table_names from server2. Error: %s", err), EConnectionFailed)
+		}
+		filter := mydiff.NewFilter()
+		ignoreFile := c.GlobalString("ignore-file")
+		if _, err := os.Stat(ignoreFile); err == nil || c.GlobalIsSet("ignore-file") {
+			if err := filter.LoadFile(ignoreFile); err != nil {
+				return cli.NewExitError(fmt.Sprintf("Cannot read filter rules from %s. Error: %s", ignoreFile, err), EInvalidFilter)
+			}
+		}
+		for _, directive := range []string{mydiff.IncludeTables, mydiff.ExcludeTables, mydiff.IgnoreColumns, mydiff.IgnoreChanges} {
+			if err := filter.Add(directive, c.GlobalStringSlice(directive)...); err != nil {
+				return cli.NewExitError(err.Error(), EInvalidFilter)
+			}
+		}
+		if c.GlobalIsSet(mydiff.MinSeverity) {
+			if err := filter.Add(mydiff.MinSeverity, c.GlobalString(mydiff.MinSeverity)); err != nil {
+				return cli.NewExitError(err.Error(), EInvalidFilter)
+			}
+		}
+		diff.Filter = filter
+		diff.Strict = c.GlobalBool("strict")
+		diff.StrictForeignKeyNaming = c.GlobalBool("strict-foreign-key-naming")
+
+		if path := c.GlobalString("rename-hints"); path != "" {
+			hints, err := mydiff.LoadRenameHints(path)
+			if err != nil {
+				return cli.NewExitError(fmt.Sprintf("Cannot read rename hints from %s. Error: %s", path, err), EInvalidRenameHints)
+			}
+			diff.RenameHints = hints
+		}
+		if path := c.GlobalString("baseline"); path != "" {
+			baseline, err := mydiff.LoadBaseline(path)
+			if err != nil {
+				return cli.NewExitError(fmt.Sprintf("Cannot read baseline from %s. Error: %s", path, err), EInvalidBaseline)
+			}
+			diff.Baseline = baseline
+		}
+		if err := diff.Err(); err != nil {
+			return cli.NewExitError(err.Error(), EObjectsUnavailable)
+		}
+		if c.GlobalBool("check") {
+			return check(diff, threshold)
+		}
+		if tool := c.GlobalString("emit-migration"); tool != "" {
+			return emitMigration(diff, tool, c.GlobalString("migration-name"), c.GlobalString("out"))
+		}
+		if tf, ok := formatter.(*mydiff.TemplateFormatter); ok {
+			result, err := tf.Execute(diff)
+			if err != nil {
+				return cli.NewExitError(err.Error(), EInvalidTemplate)
+			}
+			fmt.Print(result)
+		} else {
+			result := formatter.Format(diff)
+			fmt.Print(result)
+		}
+
+		if len(diff.Baseline) > 0 {
+			if expired := diff.BaselineReport().Expired; len(expired) > 0 {
+				return cli.NewExitError(fmt.Sprintf("%d baseline entries expired", len(expired)), EBaselineExpired)
+			}
+		}
 		return nil
 	}
+	app.Action = func(c *cli.Context) error {
+		err := run(c)
+		if e, ok := err.(cli.ExitCoder); ok && err != errDifferencesFound && c.GlobalBool("check") {
+			return cli.NewExitError(err.Error(), ECheckError+e.ExitCode())
+		}
+		return err
+	}
 
 	err := app.Run(os.Args)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// loadSchema returns the schema of the given name in a server, named as
+// given in the errors, telling schemas with functional key parts, which tengo
+// fails to load, apart from missing ones.
+func loadSchema(server string, instance *tengo.Instance, name string) (*tengo.Schema, error) {
+	schema, err := instance.Schema(name)
+	if err == nil {
+		return schema, nil
+	}
+	if indexes, _ := mydiff.FunctionalIndexes(instance, name); len(indexes) > 0 {
+		return nil, cli.NewExitError(fmt.Sprintf("%s schema %s has indexes with functional key parts, which are not supported: %s", server, name, strings.Join(indexes, ", ")), EFunctionalIndexes)
+	}
+	return nil, cli.NewExitError(fmt.Sprintf("%s doesn't contain schema %s. Error: %s", server, name, err.Error()), EMissingSchema)
+}
+
+// emitMigration writes the files of a migration applying and undoing the
+// differences of the diff to the out directory, printing their paths.
+func emitMigration(diff *mydiff.Diff, tool, name, out string) error {
+	files, err := mydiff.MigrationFiles(diff, tool, name, time.Now())
+	if err != nil {
+		return cli.NewExitError(err.Error(), EUnknownMigrationTool)
+	}
+	if len(files) == 0 {
+		fmt.Println(mydiff.Summary(nil))
+		return nil
+	}
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Cannot create directory %s. Error: %s", out, err), EMigrationNotWritten)
+	}
+	for _, file := range files {
+		path := filepath.Join(out, file.Name)
+		if err := ioutil.WriteFile(path, []byte(file.Content), 0644); err != nil {
+			return cli.NewExitError(fmt.Sprintf("Cannot write migration %s. Error: %s", path, err), EMigrationNotWritten)
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
+
+// check prints a summary of the differences of the diff, returning an exit
+// error if any of them has the given severity or a higher one, if there are
+// pending migrations or they cannot be listed, or if the baseline of the diff
+// has expired entries.
+func check(diff *mydiff.Diff, threshold mydiff.Severity) error {
+	changes := diff.Changes()
+	fmt.Println(mydiff.Summary(changes))
+
+	var pending bool
+	if diff.IncludeMigrations {
+		m, err := mydiff.NewMigrationsDiff(diff)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Cannot list the migrations of the servers. Error: %s", err), EMigrationsUnavailable)
+		}
+		if !m.IsEmpty() {
+			fmt.Printf("Pending migrations: %d missing in server1, %d missing in server2\n", len(m.Missing1), len(m.Missing2))
+			pending = true
+		}
+	}
+
+	if len(diff.Baseline) > 0 {
+		if expired := diff.BaselineReport().Expired; len(expired) > 0 {
+			return cli.NewExitError(fmt.Sprintf("%d baseline entries expired", len(expired)), EBaselineExpired)
+		}
+	}
+	if pending {
+		return errDifferencesFound
+	}
+	for _, change := range changes {
+		if change.Severity >= threshold {
+			return errDifferencesFound
+		}
+	}
+	return nil
+}
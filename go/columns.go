@@ -0,0 +1,407 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// columnInvisible matches the definition of an invisible column in the output
+// of SHOW CREATE TABLE, in MySQL 8 and MariaDB respectively, capturing its
+// name, and the definition before and after the visibility.
+var columnInvisible = regexp.MustCompile("(?m)^(  `((?:[^`]|``)+)` .*?)(?: /\\*!80023 INVISIBLE \\*/| INVISIBLE\\b)(.*)$")
+
+// columnGenerated matches the definition of a generated column in the output
+// of SHOW CREATE TABLE up to its generation expression, capturing its name.
+var columnGenerated = regexp.MustCompile("(?m)^  `((?:[^`]|``)+)` [^\n]*? GENERATED ALWAYS AS \\(")
+
+// columnGenerationKind matches the kind of a generated column, following its
+// generation expression. MariaDB calls STORED columns PERSISTENT.
+var columnGenerationKind = regexp.MustCompile(`^ (VIRTUAL|STORED|PERSISTENT)`)
+
+// Generation is how the values of a generated column are computed, which
+// tengo doesn't load.
+type Generation struct {
+	Expression string
+	Stored     bool
+}
+
+// Kind returns the kind of the generated column: STORED or VIRTUAL
+func (g *Generation) Kind() string {
+	if g.Stored {
+		return "STORED"
+	}
+	return "VIRTUAL"
+}
+
+// sameGeneration returns true if both columns are regular columns, or
+// generated the same way.
+func sameGeneration(a, b *Generation) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// liftGeneratedColumns returns the given CREATE TABLE statement of table t
+// with its generated columns defined as tengo would, as regular columns,
+// along with how they are generated.
+func liftGeneratedColumns(createStatement string, t *tengo.Table, flavor tengo.Flavor) (string, map[string]*Generation) {
+	generated := map[string]*Generation{}
+	columns := t.ColumnsByName()
+	var b strings.Builder
+	for {
+		m := columnGenerated.FindStringSubmatchIndex(createStatement)
+		if m == nil {
+			break
+		}
+		name := strings.Replace(createStatement[m[2]:m[3]], "``", "`", -1)
+		open := m[1] - 1
+		end := closingParen(createStatement[open:])
+		col, ok := columns[name]
+		if end < 0 || !ok {
+			b.WriteString(createStatement[:m[1]])
+			createStatement = createStatement[m[1]:]
+			continue
+		}
+		rest := createStatement[open+end+1:]
+		kind := columnGenerationKind.FindStringSubmatch(rest)
+		lineEnd := strings.IndexByte(rest, '\n')
+		if kind == nil || lineEnd < 0 {
+			b.WriteString(createStatement[:m[1]])
+			createStatement = createStatement[m[1]:]
+			continue
+		}
+		generated[name] = &Generation{Expression: createStatement[open+1 : open+end], Stored: kind[1] != "VIRTUAL"}
+		line := "  " + col.Definition(flavor, t)
+		if strings.HasSuffix(rest[:lineEnd], ",") {
+			line += ","
+		}
+		b.WriteString(createStatement[:m[0]] + line)
+		createStatement = rest[lineEnd:]
+	}
+	b.WriteString(createStatement)
+	return b.String(), generated
+}
+
+// liftInvisibleColumns returns the given CREATE TABLE statement with its
+// columns defined as visible, along with the names of the invisible ones.
+func liftInvisibleColumns(createStatement string) (string, map[string]bool) {
+	invisible := map[string]bool{}
+	for _, m := range columnInvisible.FindAllStringSubmatch(createStatement, -1) {
+		invisible[strings.Replace(m[2], "``", "`", -1)] = true
+	}
+	return columnInvisible.ReplaceAllString(createStatement, "$1$3"), invisible
+}
+
+// columnVisibility returns the visibility clause of an invisible column for
+// the given flavor.
+func columnVisibility(flavor tengo.Flavor) string {
+	if flavor.Vendor == tengo.VendorMariaDB {
+		return " INVISIBLE"
+	}
+	return " /*!80023 INVISIBLE */"
+}
+
+// columnDefinition returns the definition of a column as in a CREATE TABLE
+// statement, like tengo.Column.Definition does, accounting for how the column
+// is generated, if it is, and its visibility.
+func columnDefinition(col *tengo.Column, table *tengo.Table, generation *Generation, invisible bool, flavor tengo.Flavor) string {
+	def := col.Definition(flavor, table)
+	if generation != nil {
+		// Generated columns have no default value, and their nullability
+		// and comment follow the generation expression.
+		c := *col
+		c.Nullable, c.Default, c.Comment = true, tengo.ColumnDefaultNull, ""
+		def = strings.TrimSuffix(strings.TrimSuffix(c.Definition(flavor, table), " DEFAULT NULL"), " NULL")
+		def += fmt.Sprintf(" GENERATED ALWAYS AS (%s) %s", generation.Expression, generation.Kind())
+		if !col.Nullable {
+			def += " NOT NULL"
+		}
+		if col.Comment != "" {
+			def += fmt.Sprintf(" COMMENT '%s'", tengo.EscapeValueForCreateTable(col.Comment))
+		}
+	}
+	if invisible {
+		def += columnVisibility(flavor)
+	}
+	return def
+}
+
+// AlterColumn is a tengo.AddColumn or tengo.ModifyColumn clause accounting
+// for how the column is generated and its visibility, which tengo doesn't
+// load. Otherwise, the column would be made a regular visible column by the
+// clause.
+type AlterColumn struct {
+	Alter                        tengo.TableAlterClause
+	FromGeneration, ToGeneration *Generation
+	FromInvisible, ToInvisible   bool
+}
+
+// Clause returns the clause of the tengo.AddColumn or tengo.ModifyColumn,
+// with the definition of the column in To. Columns that cannot be modified
+// into it, which is the case of virtual columns made stored or regular and
+// the other way around, are dropped and added back in the same position.
+func (ac AlterColumn) Clause(mods tengo.StatementModifiers) string {
+	if mc, ok := ac.Alter.(tengo.ModifyColumn); ok && ac.rebuildsColumn() {
+		add := AlterColumn{Alter: addColumn(mc), ToGeneration: ac.ToGeneration, ToInvisible: ac.ToInvisible}
+		return fmt.Sprintf("DROP COLUMN %s, %s", tengo.EscapeIdentifier(mc.OldColumn.Name), add.Clause(mods))
+	}
+	col, table := ac.column()
+	return strings.Replace(ac.Alter.Clause(mods), col.Definition(mods.Flavor, table), ac.toDefinition(mods.Flavor), 1)
+}
+
+// rebuildsColumn returns true if the column must be dropped and added back
+func (ac AlterColumn) rebuildsColumn() bool {
+	from, to := ac.FromGeneration, ac.ToGeneration
+	virtual := (from != nil && !from.Stored) || (to != nil && !to.Stored)
+	return virtual && (from == nil || to == nil || from.Stored != to.Stored)
+}
+
+// rebuildsTable returns true if the clause rebuilds the table, as stored
+// generated columns are computed again when added or modified.
+func (ac AlterColumn) rebuildsTable() bool {
+	if _, ok := ac.Alter.(tengo.ModifyColumn); ok && ac.FromGeneration != nil && ac.FromGeneration.Stored {
+		return true
+	}
+	return ac.ToGeneration != nil && ac.ToGeneration.Stored
+}
+
+// column returns the column added or modified, and the table it belongs to
+func (ac AlterColumn) column() (*tengo.Column, *tengo.Table) {
+	switch c := ac.Alter.(type) {
+	case tengo.AddColumn:
+		return c.Column, c.Table
+	case tengo.ModifyColumn:
+		return c.NewColumn, c.Table
+	}
+	return nil, nil
+}
+
+// fromDefinition returns the definition of the modified column in From, or
+// an empty string if the column is added.
+func (ac AlterColumn) fromDefinition(flavor tengo.Flavor, table *tengo.Table) string {
+	mc, ok := ac.Alter.(tengo.ModifyColumn)
+	if !ok {
+		return ""
+	}
+	return columnDefinition(mc.OldColumn, table, ac.FromGeneration, ac.FromInvisible, flavor)
+}
+
+// toDefinition returns the definition of the column added or modified in To
+func (ac AlterColumn) toDefinition(flavor tengo.Flavor) string {
+	col, table := ac.column()
+	return columnDefinition(col, table, ac.ToGeneration, ac.ToInvisible, flavor)
+}
+
+// undo returns the clause modifying the column back to its definition in the
+// From table of the given table diff, in its former position if the clause
+// moves it. The clause must wrap a tengo.ModifyColumn.
+func (ac AlterColumn) undo(td *TableDiff) AlterColumn {
+	mc := ac.Alter.(tengo.ModifyColumn)
+	undo := tengo.ModifyColumn{Table: td.From, OldColumn: mc.NewColumn, NewColumn: mc.OldColumn}
+	if mc.PositionFirst || mc.PositionAfter != nil {
+		undo.PositionFirst, undo.PositionAfter = columnPredecessor(td.From, mc.OldColumn.Name)
+	}
+	return AlterColumn{Alter: undo, FromGeneration: ac.ToGeneration, ToGeneration: ac.FromGeneration, FromInvisible: ac.ToInvisible, ToInvisible: ac.FromInvisible}
+}
+
+// addColumn returns a tengo.AddColumn clause adding the column modified by mc
+// in the position it has in the table.
+func addColumn(mc tengo.ModifyColumn) tengo.AddColumn {
+	ac := tengo.AddColumn{Table: mc.Table, Column: mc.NewColumn}
+	for i, col := range mc.Table.Columns {
+		if col.Name != mc.NewColumn.Name {
+			continue
+		}
+		if i == 0 {
+			ac.PositionFirst = true
+		} else {
+			ac.PositionAfter = mc.Table.Columns[i-1]
+		}
+	}
+	return ac
+}
+
+// alterColumns replaces the tengo.AddColumn and tengo.ModifyColumn clauses of
+// the generated and invisible columns of a lifted table with AlterColumn
+// clauses, and adds AlterColumn clauses for the columns only differing in how
+// they are generated or in visibility.
+func alterColumns(td *TableDiff, lt *liftedTable) {
+	from, to := lt.fromFeatures, lt.toFeatures
+	clauses := td.AlterClauses()
+	modified := map[string]bool{}
+	for i, c := range clauses {
+		switch c := c.(type) {
+		case tengo.AddColumn:
+			name := c.Column.Name
+			if to.generatedColumns[name] != nil || to.invisibleColumns[name] {
+				clauses[i] = AlterColumn{Alter: c, ToGeneration: to.generatedColumns[name], ToInvisible: to.invisibleColumns[name]}
+			}
+		case tengo.ModifyColumn:
+			modified[c.OldColumn.Name] = true
+			ac := newAlterColumn(c, from, to)
+			if ac.FromGeneration != nil || ac.ToGeneration != nil || ac.FromInvisible || ac.ToInvisible {
+				clauses[i] = ac
+			}
+		}
+	}
+
+	fromColumns := td.From.ColumnsByName()
+	for _, col := range td.To.Columns {
+		fromCol, ok := fromColumns[col.Name]
+		if !ok || modified[col.Name] {
+			continue
+		}
+		ac := newAlterColumn(tengo.ModifyColumn{Table: td.To, OldColumn: fromCol, NewColumn: col}, from, to)
+		if !sameGeneration(ac.FromGeneration, ac.ToGeneration) || ac.FromInvisible != ac.ToInvisible {
+			clauses = append(clauses, ac)
+		}
+	}
+	td.SetAlterClauses(clauses)
+}
+
+func newAlterColumn(mc tengo.ModifyColumn, from, to *tableFeatures) AlterColumn {
+	return AlterColumn{
+		Alter:          mc,
+		FromGeneration: from.generatedColumns[mc.OldColumn.Name],
+		ToGeneration:   to.generatedColumns[mc.NewColumn.Name],
+		FromInvisible:  from.invisibleColumns[mc.OldColumn.Name],
+		ToInvisible:    to.invisibleColumns[mc.NewColumn.Name],
+	}
+}
+
+// generationWarnings warns about the stored generated columns added or
+// modified by the diffs, which rebuild their tables.
+func generationWarnings(ods []tengo.ObjectDiff) []string {
+	var warnings []string
+	for _, od := range ods {
+		td, ok := od.(*TableDiff)
+		if !ok || td.DiffType() != tengo.DiffTypeAlter {
+			continue
+		}
+		for _, c := range td.AlterClauses() {
+			if ac, ok := c.(AlterColumn); ok && ac.rebuildsTable() {
+				col, _ := ac.column()
+				warnings = append(warnings, fmt.Sprintf("Changing the stored generated column %s.%s rebuilds the table", td.From.Name, col.Name))
+			}
+		}
+	}
+	return warnings
+}
+
+// MoveColumn is a tengo.TableAlterClause representing a column that is only
+// in a different position in the tables. Alter is the tengo.ModifyColumn, or
+// the AlterColumn wrapping it, that moves the column, and From and To are the
+// positions of the column in each table, as returned by columnPosition.
+type MoveColumn struct {
+	Alter    tengo.TableAlterClause
+	From, To string
+}
+
+// Clause returns the MODIFY COLUMN clause of Alter
+func (mc MoveColumn) Clause(mods tengo.StatementModifiers) string {
+	return mc.Alter.Clause(mods)
+}
+
+// modifyColumn returns the tengo.ModifyColumn moving the column
+func (mc MoveColumn) modifyColumn() tengo.ModifyColumn {
+	if ac, ok := mc.Alter.(AlterColumn); ok {
+		return ac.Alter.(tengo.ModifyColumn)
+	}
+	return mc.Alter.(tengo.ModifyColumn)
+}
+
+// columnPosition returns the position of a column in a table, as in the
+// clauses of an ALTER TABLE statement: FIRST, or AFTER the previous column.
+func columnPosition(t *tengo.Table, name string) string {
+	for i, col := range t.Columns {
+		if col.Name != name {
+			continue
+		}
+		if i == 0 {
+			return "FIRST"
+		}
+		return fmt.Sprintf("AFTER %s", tengo.EscapeIdentifier(t.Columns[i-1].Name))
+	}
+	return ""
+}
+
+// columnPredecessor returns the position of a column in a table, as in the
+// fields of a tengo.AddColumn clause: whether it's the first one, or the
+// column it follows.
+func columnPredecessor(t *tengo.Table, name string) (bool, *tengo.Column) {
+	for i, col := range t.Columns {
+		if col.Name == name {
+			if i == 0 {
+				return true, nil
+			}
+			return false, t.Columns[i-1]
+		}
+	}
+	return false, nil
+}
+
+// detectColumnMoves replaces the clauses of a table diff that only move
+// columns with MoveColumn clauses, so they are reported as such rather than
+// as modifications.
+func detectColumnMoves(td *TableDiff) {
+	clauses := td.AlterClauses()
+	for i, c := range clauses {
+		if mc, ok := movesColumn(c); ok {
+			clauses[i] = MoveColumn{
+				Alter: c,
+				From:  columnPosition(td.From, mc.OldColumn.Name),
+				To:    columnPosition(td.To, mc.NewColumn.Name),
+			}
+		}
+	}
+	td.SetAlterClauses(clauses)
+}
+
+// movesColumn returns the tengo.ModifyColumn of the given clause, and whether
+// the clause only moves the column.
+func movesColumn(c tengo.TableAlterClause) (tengo.ModifyColumn, bool) {
+	switch c := c.(type) {
+	case tengo.ModifyColumn:
+		return c, (c.PositionFirst || c.PositionAfter != nil) && c.OldColumn.Equals(c.NewColumn)
+	case AlterColumn:
+		mc, ok := c.Alter.(tengo.ModifyColumn)
+		if !ok || !sameGeneration(c.FromGeneration, c.ToGeneration) || c.FromInvisible != c.ToInvisible {
+			return mc, false
+		}
+		return movesColumn(mc)
+	}
+	return tengo.ModifyColumn{}, false
+}
+
+// withoutPosition returns the given tengo.ModifyColumn clause, or AlterColumn
+// wrapping it, without moving the column.
+func withoutPosition(c tengo.TableAlterClause) tengo.TableAlterClause {
+	switch c := c.(type) {
+	case tengo.ModifyColumn:
+		c.PositionFirst, c.PositionAfter = false, nil
+		return c
+	case AlterColumn:
+		if _, ok := c.Alter.(tengo.ModifyColumn); ok {
+			c.Alter = withoutPosition(c.Alter)
+		}
+		return c
+	}
+	return c
+}
@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/tengo"
@@ -33,9 +34,14 @@ type CompactFormatter struct{}
 // first, an ADD KEY, and then ADD CONSTRAINT over that key.
 // `combine([]line) []string` combines M formatted alters into N <=M
 // strings each of which will be a difference outputted by the formatter.
+//
+// Table is the table altered by the clause, if any, and Severity the
+// severity of the difference, which is left untagged if zero.
 type line struct {
-	Origin interface{}
-	Text   string
+	Origin   interface{}
+	Text     string
+	Table    string
+	Severity Severity
 }
 
 // ignoredLine represents a line that is ignored by the formatter.
@@ -45,47 +51,124 @@ type line struct {
 // change. The latter is ignored by this formatter.
 var ignoredLine = line{}
 
+// FormatsMigrations (see MigrationsFormatter)
+func (f *CompactFormatter) FormatsMigrations() {}
+
 // Format returns a string with the formatted diff
 func (f *CompactFormatter) Format(diff *Diff) interface{} {
 	var lines []line
 	ods := diff.Compute()
 	for _, od := range ods {
-		switch od.DiffType() {
-		case tengo.DiffTypeAlter:
-			lines = append(lines, f.formatAlter(od, diff)...)
-		case tengo.DiffTypeCreate:
-			lines = append(lines, f.formatCreate(od.(*TableDiff), diff))
-		case tengo.DiffTypeDrop:
-			lines = append(lines, f.formatDrop(od.(*TableDiff), diff))
-		case DiffTypeMigrations:
-			lines = append(lines, f.formatMigrationsDiff(od.(*MigrationsDiff), diff))
+		odLines := f.formatObjectDiff(od, diff)
+		severity := objectSeverity(od)
+		for i := range odLines {
+			if odLines[i].Severity == 0 {
+				odLines[i].Severity = severity
+			}
 		}
+		lines = append(lines, odLines...)
+	}
+	out := f.summarize(lines)
+	if warnings := diff.Warnings(); len(warnings) > 0 {
+		out += f.formatWarnings(warnings)
 	}
-	return f.summarize(lines)
+	if len(diff.Baseline) > 0 {
+		out += f.formatBaselineReport(diff.BaselineReport())
+	}
+	return out
+}
+
+// formatObjectDiff returns the lines of an object diff. Only the lines of
+// alter clauses and partitions are tagged with their severity, the rest take
+// the severity of the object diff.
+func (f *CompactFormatter) formatObjectDiff(od tengo.ObjectDiff, context *Diff) []line {
+	switch od := od.(type) {
+	case *tengo.DatabaseDiff:
+		return []line{f.formatAlterDatabase(od, context)}
+	case *TriggerDiff:
+		return []line{f.formatTrigger(od, context)}
+	case *ViewDiff:
+		return []line{f.formatView(od, context)}
+	case *EventDiff:
+		return []line{f.formatEvent(od, context)}
+	case *PartitionDiff:
+		return f.formatPartitions(od, context)
+	}
+	switch od.DiffType() {
+	case tengo.DiffTypeAlter:
+		return f.formatAlter(od, context)
+	case tengo.DiffTypeCreate:
+		return []line{f.formatCreate(od.(*TableDiff), context)}
+	case tengo.DiffTypeDrop:
+		return []line{f.formatDrop(od.(*TableDiff), context)}
+	case tengo.DiffTypeRename:
+		return []line{f.formatRenameTable(od.(*RenameTableDiff), context)}
+	case DiffTypeMigrations:
+		return []line{f.formatMigrationsDiff(od.(*MigrationsDiff), context)}
+	}
+	return nil
 }
 
 // combine combines several line together into a list
 // of strings each of which is a line outputted by the formatter.
 //
 // AddForeignKey comes in two different alter clauses:
-// ADD KEY k followed by an ADD CONSTRAINT on k.
-// We only care about the last one, so we pop the previous line.
+// ADD KEY k followed by an ADD CONSTRAINT on k, which may be in
+// different table diffs. We only care about the last one, so we
+// leave out the lines of the keys named after the foreign keys
+// added or dropped in the same table.
 func (f *CompactFormatter) combine(lines []line) (s []string) {
+	foreignKeys := map[implicitIndex]bool{}
 	for _, fa := range lines {
-		if fa == ignoredLine {
-			continue
-		}
-		switch fa.Origin.(type) {
+		switch c := fa.Origin.(type) {
 		case tengo.AddForeignKey:
-			s = s[:len(s)-1]
+			foreignKeys[implicitIndex{fa.Table, c.ForeignKey.Name, true}] = true
 		case tengo.DropForeignKey:
-			s = s[:len(s)-1]
+			foreignKeys[implicitIndex{fa.Table, c.ForeignKey.Name, false}] = true
+		}
+	}
+	for _, fa := range lines {
+		if fa.Text == "" {
+			continue
+		}
+		if key, ok := implicitIndexOf(fa); ok && foreignKeys[key] {
+			continue
+		}
+		if fa.Severity > 0 {
+			s = append(s, fmt.Sprintf("[%s] %s", fa.Severity, fa.Text))
+			continue
 		}
 		s = append(s, fa.Text)
 	}
 	return s
 }
 
+// implicitIndex identifies the index that MySQL creates along with a foreign
+// key, named after it, if no other index of the table can be used by it.
+type implicitIndex struct {
+	table, name string
+	added       bool
+}
+
+// implicitIndexOf returns the implicit index that could be added or dropped
+// by the clause of a line.
+func implicitIndexOf(fa line) (implicitIndex, bool) {
+	switch c := fa.Origin.(type) {
+	case tengo.AddIndex:
+		return implicitIndex{fa.Table, c.Index.Name, true}, true
+	case tengo.DropIndex:
+		return implicitIndex{fa.Table, c.Index.Name, false}, true
+	case AlterIndex:
+		if c.From == nil {
+			return implicitIndex{fa.Table, c.To.Name, true}, true
+		}
+		if c.To == nil {
+			return implicitIndex{fa.Table, c.From.Name, false}, true
+		}
+	}
+	return implicitIndex{}, false
+}
+
 func (f *CompactFormatter) summarize(fas []line) string {
 	lines := f.combine(fas)
 	var buffer bytes.Buffer
@@ -100,6 +183,38 @@ func (f *CompactFormatter) summarize(fas []line) string {
 	return buffer.String()
 }
 
+func (f *CompactFormatter) formatWarnings(warnings []string) string {
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("\nWarnings (%d):\n", len(warnings)))
+	for _, w := range warnings {
+		buffer.WriteString(fmt.Sprintf("\t- %s\n", w))
+	}
+	return buffer.String()
+}
+
+// formatBaselineReport informs about the baseline entries that expired or
+// no longer apply, which need the attention of their owners.
+func (f *CompactFormatter) formatBaselineReport(report BaselineReport) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	if count := len(report.Accepted); count > 0 {
+		buffer.WriteString(fmt.Sprintf("Differences accepted by the baseline: %d\n", count))
+	}
+	if count := len(report.Expired); count > 0 {
+		buffer.WriteString(fmt.Sprintf("Expired baseline entries (%d):\n", count))
+		for _, e := range report.Expired {
+			buffer.WriteString(fmt.Sprintf("\t- %s expired on %s, owned by %s: %s\n", e.ID, e.Expires.Format(baselineDateFormat), e.Owner, e.Reason))
+		}
+	}
+	if count := len(report.Stale); count > 0 {
+		buffer.WriteString(fmt.Sprintf("Baseline entries no longer applying (%d):\n", count))
+		for _, e := range report.Stale {
+			buffer.WriteString(fmt.Sprintf("\t- %s, owned by %s: %s\n", e.ID, e.Owner, e.Reason))
+		}
+	}
+	return buffer.String()
+}
+
 func (f *CompactFormatter) formatAlter(diff tengo.ObjectDiff, context *Diff) []line {
 	tableDiff := diff.(*TableDiff)
 	tableName := tableDiff.From.Name
@@ -108,6 +223,11 @@ func (f *CompactFormatter) formatAlter(diff tengo.ObjectDiff, context *Diff) []l
 	lines := make([]line, len(clauses))
 	for i, c := range clauses {
 		lines[i] = f.formatAlterClause(c, context, tableName)
+		lines[i].Table = tableName
+		if change, ok := clauseChange(c, tableDiff); ok {
+			change.Origin = c
+			lines[i].Severity = change.severity()
+		}
 	}
 	return lines
 }
@@ -135,6 +255,11 @@ func (f *CompactFormatter) formatAlterClause(c tengo.TableAlterClause, context *
 			Text:   f.formatDropIndex(c.(tengo.DropIndex), context, tableName),
 			Origin: c,
 		}
+	case AlterIndex:
+		l = line{
+			Text:   f.formatAlterIndex(c.(AlterIndex), context, tableName),
+			Origin: c,
+		}
 	case tengo.AddForeignKey:
 		l = line{
 			Text:   f.formatAddForeignKey(c.(tengo.AddForeignKey), context, tableName),
@@ -145,11 +270,36 @@ func (f *CompactFormatter) formatAlterClause(c tengo.TableAlterClause, context *
 			Text:   f.formatDropForeignKey(c.(tengo.DropForeignKey), context, tableName),
 			Origin: c,
 		}
+	case AlterForeignKey:
+		l = line{
+			Text:   f.formatAlterForeignKey(c.(AlterForeignKey), context, tableName),
+			Origin: c,
+		}
+	case RenameColumn:
+		l = line{
+			Text:   f.formatRenameColumn(c.(RenameColumn), context, tableName),
+			Origin: c,
+		}
 	case tengo.ModifyColumn:
 		l = line{
 			Text:   f.formatModifyColumn(c.(tengo.ModifyColumn), context, tableName),
 			Origin: c,
 		}
+	case AlterColumn:
+		l = line{
+			Text:   f.formatAlterColumn(c.(AlterColumn), context, tableName),
+			Origin: c,
+		}
+	case MoveColumn:
+		l = line{
+			Text:   f.formatMoveColumn(c.(MoveColumn), context, tableName),
+			Origin: c,
+		}
+	case AlterCheck:
+		l = line{
+			Text:   f.formatAlterCheck(c.(AlterCheck), context, tableName),
+			Origin: c,
+		}
 	case tengo.ChangeCharSet:
 		l = line{
 			Text:   f.formatChangeCharset(c.(tengo.ChangeCharSet), context, tableName),
@@ -175,70 +325,176 @@ func (f *CompactFormatter) formatDropColumn(dc tengo.DropColumn, context *Diff,
 }
 
 func (f *CompactFormatter) formatAddIndex(idx tengo.AddIndex, context *Diff, tableName string) string {
-	var idxType string
-	if idx.Index.Unique {
-		idxType = "UNIQUE KEY"
-	} else {
-		idxType = "KEY"
-	}
-	idxName := idx.Index.Name
-	colNames := make([]string, len(idx.Index.Columns))
-	for i, c := range idx.Index.Columns {
-		colNames[i] = c.Name
-	}
-	return fmt.Sprintf("Table %s differs: missing %s %s(%s) in %s.%s", tableName, idxType, idxName, strings.Join(colNames, ", "), context.From.Name, context.DSN1.Addr)
+	return fmt.Sprintf("Table %s differs: missing %s in %s.%s", tableName, f.indexDef(&Index{Index: idx.Index}), context.From.Name, context.DSN1.Addr)
 }
 
 func (f *CompactFormatter) formatDropIndex(idx tengo.DropIndex, context *Diff, tableName string) string {
-	var idxType string
-	if idx.Index.Unique {
-		idxType = "UNIQUE KEY"
-	} else {
-		idxType = "KEY"
+	return fmt.Sprintf("Table %s differs: missing %s in %s.%s", tableName, f.indexDef(&Index{Index: idx.Index}), context.To.Name, context.DSN2.Addr)
+}
+
+func (f *CompactFormatter) formatAlterIndex(ai AlterIndex, context *Diff, tableName string) string {
+	switch {
+	case ai.From == nil:
+		return fmt.Sprintf("Table %s differs: missing %s in %s.%s", tableName, f.indexDef(ai.To), context.From.Name, context.DSN1.Addr)
+	case ai.To == nil:
+		return fmt.Sprintf("Table %s differs: missing %s in %s.%s", tableName, f.indexDef(ai.From), context.To.Name, context.DSN2.Addr)
+	case len(ai.From.differences(ai.To)) == 0:
+		// the index is only reordered
+		return ""
 	}
-	colNames := make([]string, len(idx.Index.Columns))
-	for i, c := range idx.Index.Columns {
-		colNames[i] = c.Name
+	return fmt.Sprintf("Table %s differs: index %s differs in %s: %s in %s.%s, %s in %s.%s", tableName, ai.From.Name, strings.Join(ai.From.differences(ai.To), ", "), f.indexDef(ai.From), context.From.Name, context.DSN1.Addr, f.indexDef(ai.To), context.To.Name, context.DSN2.Addr)
+}
+
+// indexDef describes an index, with the prefix length and order of its
+// columns, and its comment and visibility.
+func (f *CompactFormatter) indexDef(idx *Index) string {
+	def := fmt.Sprintf("%s %s(%s)", idx.Kind(), idx.Name, strings.Join(idx.parts(func(name string) string { return name }), ", "))
+	if idx.PrimaryKey {
+		def = fmt.Sprintf("%s(%s)", idx.Kind(), strings.Join(idx.parts(func(name string) string { return name }), ", "))
+	}
+	if idx.Parser != "" {
+		def += fmt.Sprintf(" WITH PARSER %s", idx.Parser)
 	}
-	idxName := idx.Index.Name
-	return fmt.Sprintf("Table %s differs: missing %s %s(%s) in %s.%s", tableName, idxType, idxName, strings.Join(colNames, ", "), context.To.Name, context.DSN2.Addr)
+	if idx.Comment != "" {
+		def += fmt.Sprintf(" COMMENT '%s'", tengo.EscapeValueForCreateTable(idx.Comment))
+	}
+	if idx.Invisible {
+		def += " INVISIBLE"
+	}
+	return def
 }
 
+// formatAddForeignKey and formatDropForeignKey return an empty text for the
+// foreign keys that are only renamed, unless the diff has strict foreign key
+// naming. The lines are kept anyway, so the lines of their implicit indexes
+// are left out too.
 func (f *CompactFormatter) formatAddForeignKey(key tengo.AddForeignKey, context *Diff, tableName string) string {
-	fkName := key.ForeignKey.Name
-	colNames := make([]string, len(key.ForeignKey.Columns))
-	for i, c := range key.ForeignKey.Columns {
-		colNames[i] = c.Name
+	if key.Clause(tengo.StatementModifiers{StrictForeignKeyNaming: context.StrictForeignKeyNaming}) == "" {
+		return ""
 	}
-	refName := key.ForeignKey.ReferencedTableName
-	refColNames := key.ForeignKey.ReferencedColumnNames
-	return fmt.Sprintf("Table %s differs: missing FOREIGN KEY %s(%s) REFERENCES %s(%s) in %s.%s", tableName, fkName, strings.Join(colNames, ", "), refName, strings.Join(refColNames, ","), context.From.Name, context.DSN1.Addr)
+	return fmt.Sprintf("Table %s differs: missing %s in %s.%s", tableName, f.foreignKeyDef(key.ForeignKey), context.From.Name, context.DSN1.Addr)
 }
 
 func (f *CompactFormatter) formatDropForeignKey(key tengo.DropForeignKey, context *Diff, tableName string) string {
-	fkName := key.ForeignKey.Name
-	colNames := make([]string, len(key.ForeignKey.Columns))
-	for i, c := range key.ForeignKey.Columns {
+	if key.Clause(tengo.StatementModifiers{StrictForeignKeyNaming: context.StrictForeignKeyNaming}) == "" {
+		return ""
+	}
+	return fmt.Sprintf("Table %s differs: missing %s in %s.%s", tableName, f.foreignKeyDef(key.ForeignKey), context.To.Name, context.DSN2.Addr)
+}
+
+func (f *CompactFormatter) formatAlterForeignKey(key AlterForeignKey, context *Diff, tableName string) string {
+	if key.Added {
+		return ""
+	}
+	return fmt.Sprintf("Table %s differs: foreign key %s differs in %s: %s in %s.%s, %s in %s.%s", tableName, key.From.Name, strings.Join(key.differences(), ", "), f.foreignKeyDef(key.From), context.From.Name, context.DSN1.Addr, f.foreignKeyDef(key.To), context.To.Name, context.DSN2.Addr)
+}
+
+// foreignKeyDef describes a foreign key, with its referential actions, and
+// the schema of the referenced table if it's not the schema of the table.
+func (f *CompactFormatter) foreignKeyDef(fk *tengo.ForeignKey) string {
+	colNames := make([]string, len(fk.Columns))
+	for i, c := range fk.Columns {
 		colNames[i] = c.Name
 	}
-	refName := key.ForeignKey.ReferencedTableName
-	refColNames := key.ForeignKey.ReferencedColumnNames
-	return fmt.Sprintf("Table %s differs: missing FOREIGN KEY %s(%s) REFERENCES %s(%s) in %s.%s", tableName, fkName, strings.Join(colNames, ", "), refName, strings.Join(refColNames, ","), context.To.Name, context.DSN2.Addr)
+	refName := fk.ReferencedTableName
+	if fk.ReferencedSchemaName != "" {
+		refName = fmt.Sprintf("%s.%s", fk.ReferencedSchemaName, refName)
+	}
+	def := fmt.Sprintf("FOREIGN KEY %s(%s) REFERENCES %s(%s)", fk.Name, strings.Join(colNames, ", "), refName, strings.Join(fk.ReferencedColumnNames, ", "))
+	// RESTRICT and NO ACTION are the same in MySQL, and the default
+	for _, action := range []struct{ event, rule string }{{"DELETE", fk.DeleteRule}, {"UPDATE", fk.UpdateRule}} {
+		if action.rule != "" && action.rule != "RESTRICT" && action.rule != "NO ACTION" {
+			def += fmt.Sprintf(" ON %s %s", action.event, action.rule)
+		}
+	}
+	return def
+}
+
+func (f *CompactFormatter) formatRenameColumn(rc RenameColumn, context *Diff, tableName string) string {
+	return fmt.Sprintf("Table %s differs: column %s in %s.%s %s to %s in %s.%s", tableName, rc.OldColumn.Name, context.From.Name, context.DSN1.Addr, f.renamed(rc.Hinted), rc.NewColumn.Name, context.To.Name, context.DSN2.Addr)
 }
 
 func (f *CompactFormatter) formatModifyColumn(mc tengo.ModifyColumn, context *Diff, tableName string) string {
 	colName := mc.OldColumn.Name
-	s1ColDef := f.colDef(mc.OldColumn)
-	s2ColDef := f.colDef(mc.NewColumn)
+	s1ColDef := f.colDef(mc.OldColumn, context.Flavor1)
+	s2ColDef := f.colDef(mc.NewColumn, context.Flavor2)
 	if s1ColDef != s2ColDef {
 		return fmt.Sprintf("Table %s differs: column %s differs in column type: %s in %s.%s, %s in %s.%s", tableName, colName, s1ColDef, context.From.Name, context.DSN1.Addr, s2ColDef, context.To.Name, context.DSN2.Addr)
 	}
 	return fmt.Sprintf("Table %s differs: column %s AUTO_INCREMENT value differs between  %s.%s, and %s.%s", tableName, colName, context.From.Name, context.DSN1.Addr, context.To.Name, context.DSN2.Addr)
 }
 
-func (f *CompactFormatter) colDef(c *tengo.Column) string {
-	// TODO: get the flavor From the context, based on information gathered From the instances.
-	colDef := c.Definition(tengo.FlavorUnknown, nil)
+func (f *CompactFormatter) formatAlterColumn(ac AlterColumn, context *Diff, tableName string) string {
+	mc, ok := ac.Alter.(tengo.ModifyColumn)
+	if !ok {
+		return f.formatAlterClause(ac.Alter, context, tableName).Text
+	}
+	colName := mc.OldColumn.Name
+	s1ColDef := f.colDef(mc.OldColumn, context.Flavor1)
+	s2ColDef := f.colDef(mc.NewColumn, context.Flavor2)
+	var differences []string
+	if s1ColDef != s2ColDef {
+		differences = append(differences, "column type")
+	}
+	if !sameGeneration(ac.FromGeneration, ac.ToGeneration) {
+		differences = append(differences, "generation")
+	}
+	if ac.FromInvisible != ac.ToInvisible {
+		if len(differences) == 0 {
+			return fmt.Sprintf("Table %s differs: column %s differs in visibility: %s in %s.%s, %s in %s.%s", tableName, colName, f.visibility(ac.FromInvisible), context.From.Name, context.DSN1.Addr, f.visibility(ac.ToInvisible), context.To.Name, context.DSN2.Addr)
+		}
+		differences = append(differences, "visibility")
+	}
+	s1ColDef = f.generatedColDef(mc.OldColumn, context.From.Table(tableName), ac.FromGeneration, ac.FromInvisible, context.Flavor1)
+	s2ColDef = f.generatedColDef(mc.NewColumn, mc.Table, ac.ToGeneration, ac.ToInvisible, context.Flavor2)
+	return fmt.Sprintf("Table %s differs: column %s differs in %s: %s in %s.%s, %s in %s.%s", tableName, colName, strings.Join(differences, ", "), s1ColDef, context.From.Name, context.DSN1.Addr, s2ColDef, context.To.Name, context.DSN2.Addr)
+}
+
+func (f *CompactFormatter) formatMoveColumn(mc MoveColumn, context *Diff, tableName string) string {
+	colName := mc.modifyColumn().NewColumn.Name
+	return fmt.Sprintf("Table %s differs: column %s is in a different position: %s in %s.%s, %s in %s.%s", tableName, colName, mc.From, context.From.Name, context.DSN1.Addr, mc.To, context.To.Name, context.DSN2.Addr)
+}
+
+// generatedColDef is like colDef, accounting for how the column is generated,
+// if it is, and its visibility. Its character set is only given if it's not
+// the one of the table the column belongs to.
+func (f *CompactFormatter) generatedColDef(c *tengo.Column, table *tengo.Table, generation *Generation, invisible bool, flavor tengo.Flavor) string {
+	colDef := columnDefinition(c, table, generation, false, flavor)
+	colDef = strings.Replace(colDef, "`"+c.Name+"` ", "", 1)
+	if invisible {
+		colDef += " INVISIBLE"
+	}
+	return colDef
+}
+
+func (f *CompactFormatter) visibility(invisible bool) string {
+	if invisible {
+		return "invisible"
+	}
+	return "visible"
+}
+
+func (f *CompactFormatter) formatAlterCheck(ac AlterCheck, context *Diff, tableName string) string {
+	switch {
+	case ac.From == nil:
+		return fmt.Sprintf("Table %s differs: missing %s in %s.%s", tableName, f.checkDef(ac.To), context.From.Name, context.DSN1.Addr)
+	case ac.To == nil:
+		return fmt.Sprintf("Table %s differs: missing %s in %s.%s", tableName, f.checkDef(ac.From), context.To.Name, context.DSN2.Addr)
+	}
+	return fmt.Sprintf("Table %s differs: check %s differs in %s: %s in %s.%s, %s in %s.%s", tableName, ac.From.Name, strings.Join(ac.From.differences(ac.To), ", "), f.checkDef(ac.From), context.From.Name, context.DSN1.Addr, f.checkDef(ac.To), context.To.Name, context.DSN2.Addr)
+}
+
+// checkDef describes a CHECK constraint, and whether it's not enforced
+func (f *CompactFormatter) checkDef(c *Check) string {
+	def := fmt.Sprintf("CHECK %s%s", c.Name, c.Clause)
+	if !c.Enforced {
+		def += " NOT ENFORCED"
+	}
+	return def
+}
+
+func (f *CompactFormatter) colDef(c *tengo.Column, flavor tengo.Flavor) string {
+	colDef := c.Definition(flavor, nil)
 	colDef = strings.Replace(colDef, "`"+c.Name+"` ", "", 1)
 	return colDef
 }
@@ -261,6 +517,102 @@ func (f *CompactFormatter) formatDrop(td *TableDiff, context *Diff) line {
 	}
 }
 
+func (f *CompactFormatter) formatAlterDatabase(dd *tengo.DatabaseDiff, context *Diff) line {
+	return line{
+		Text:   fmt.Sprintf("Schema differs: default encoding is %s %s in %s.%s, %s %s in %s.%s", dd.From.CharSet, dd.From.Collation, context.From.Name, context.DSN1.Addr, dd.To.CharSet, dd.To.Collation, context.To.Name, context.DSN2.Addr),
+		Origin: dd,
+	}
+}
+
+func (f *CompactFormatter) formatTrigger(td *TriggerDiff, context *Diff) line {
+	var text string
+	switch td.DiffType() {
+	case tengo.DiffTypeCreate:
+		text = fmt.Sprintf("Trigger %s on table %s is absent in %s.%s", td.To.Name, td.To.Table, context.From.Name, context.DSN1.Addr)
+	case tengo.DiffTypeDrop:
+		text = fmt.Sprintf("Trigger %s on table %s is absent in %s.%s", td.From.Name, td.From.Table, context.To.Name, context.DSN2.Addr)
+	default:
+		text = fmt.Sprintf("Trigger %s on table %s differs in %s between %s.%s and %s.%s", td.From.Name, td.From.Table, strings.Join(td.From.differences(td.To), ", "), context.From.Name, context.DSN1.Addr, context.To.Name, context.DSN2.Addr)
+	}
+	return line{Text: text, Origin: td}
+}
+
+func (f *CompactFormatter) formatView(vd *ViewDiff, context *Diff) line {
+	var text string
+	switch vd.DiffType() {
+	case tengo.DiffTypeCreate:
+		text = fmt.Sprintf("View %s is absent in %s.%s", vd.To.Name, context.From.Name, context.DSN1.Addr)
+	case tengo.DiffTypeDrop:
+		text = fmt.Sprintf("View %s is absent in %s.%s", vd.From.Name, context.To.Name, context.DSN2.Addr)
+	default:
+		text = fmt.Sprintf("View %s differs in %s between %s.%s and %s.%s", vd.From.Name, strings.Join(vd.From.differences(vd.To), ", "), context.From.Name, context.DSN1.Addr, context.To.Name, context.DSN2.Addr)
+	}
+	return line{Text: text, Origin: vd}
+}
+
+func (f *CompactFormatter) formatEvent(ed *EventDiff, context *Diff) line {
+	var text string
+	switch ed.DiffType() {
+	case tengo.DiffTypeCreate:
+		text = fmt.Sprintf("Event %s is absent in %s.%s", ed.To.Name, context.From.Name, context.DSN1.Addr)
+	case tengo.DiffTypeDrop:
+		text = fmt.Sprintf("Event %s is absent in %s.%s", ed.From.Name, context.To.Name, context.DSN2.Addr)
+	default:
+		text = fmt.Sprintf("Event %s differs in %s between %s.%s and %s.%s", ed.From.Name, strings.Join(ed.From.differences(ed.To, time.Now()), ", "), context.From.Name, context.DSN1.Addr, context.To.Name, context.DSN2.Addr)
+	}
+	return line{Text: text, Origin: ed}
+}
+
+func (f *CompactFormatter) formatPartitions(pd *PartitionDiff, context *Diff) []line {
+	var lines []line
+	for _, c := range partitionChanges(pd) {
+		var text string
+		switch c.Kind {
+		case ChangeRemovePartitioning:
+			text = fmt.Sprintf("Table %s differs: %s in %s.%s, not partitioned in %s.%s", c.Table, f.partitioning(pd.From), context.From.Name, context.DSN1.Addr, context.To.Name, context.DSN2.Addr)
+		case ChangePartitioning:
+			if pd.From == nil {
+				text = fmt.Sprintf("Table %s differs: not partitioned in %s.%s, %s in %s.%s", c.Table, context.From.Name, context.DSN1.Addr, f.partitioning(pd.To), context.To.Name, context.DSN2.Addr)
+			} else {
+				text = fmt.Sprintf("Table %s differs: %s in %s.%s, %s in %s.%s", c.Table, f.partitioning(pd.From), context.From.Name, context.DSN1.Addr, f.partitioning(pd.To), context.To.Name, context.DSN2.Addr)
+			}
+		case ChangeAddPartition:
+			text = fmt.Sprintf("Table %s differs: missing partition %s in %s.%s", c.Table, c.Name, context.From.Name, context.DSN1.Addr)
+		case ChangeDropPartition:
+			text = fmt.Sprintf("Table %s differs: missing partition %s in %s.%s", c.Table, c.Name, context.To.Name, context.DSN2.Addr)
+		case ChangeModifyPartition:
+			text = fmt.Sprintf("Table %s differs: partition %s differs in definition: %s in %s.%s, %s in %s.%s", c.Table, c.Name, c.From, context.From.Name, context.DSN1.Addr, c.To, context.To.Name, context.DSN2.Addr)
+		}
+		lines = append(lines, line{Text: text, Origin: pd, Severity: c.severity()})
+	}
+	return lines
+}
+
+// partitioning describes the partitioning method of a table, with the number
+// of partitions if they are not listed.
+func (f *CompactFormatter) partitioning(p *Partitioning) string {
+	if p.Count > 0 {
+		return fmt.Sprintf("%s PARTITIONS %d", p.Method, p.Count)
+	}
+	return p.Method
+}
+
+func (f *CompactFormatter) formatRenameTable(rd *RenameTableDiff, context *Diff) line {
+	return line{
+		Text:   fmt.Sprintf("Table %s in %s.%s %s to %s in %s.%s", rd.From.Name, context.From.Name, context.DSN1.Addr, f.renamed(rd.Hinted), rd.To.Name, context.To.Name, context.DSN2.Addr),
+		Origin: tengo.DiffTypeRename,
+	}
+}
+
+// renamed tells whether a rename was confirmed by the rename hints
+// or only guessed.
+func (f *CompactFormatter) renamed(hinted bool) string {
+	if hinted {
+		return "was renamed"
+	}
+	return "was probably renamed"
+}
+
 func (f *CompactFormatter) formatMigrationsDiff(md *MigrationsDiff, context *Diff) line {
 	buf := bytes.NewBufferString("Some migrations are missing:\n")
 	if len(md.Missing1) > 0 {
@@ -43,7 +43,7 @@ func TestCompactFormatter_Format(t *testing.T) {
 			},
 			expected: []string{
 				"Differences found \\(1\\)",
-				"Table tasks differs: missing column owner_id in schema1_\\d+.127.0.0.1:33060",
+				"\\[additive\\] Table tasks differs: missing column owner_id in schema1_\\d+.127.0.0.1:33060",
 			},
 		},
 		"Drop Column": {
@@ -64,7 +64,7 @@ func TestCompactFormatter_Format(t *testing.T) {
 			},
 			expected: []string{
 				"Differences found \\(1\\)",
-				"Table tasks differs: missing column owner_id in schema2_\\d+.127.0.0.1:33062",
+				"\\[breaking\\] Table tasks differs: missing column owner_id in schema2_\\d+.127.0.0.1:33062",
 			},
 		},
 		//	Add Index
@@ -173,9 +173,51 @@ func TestCompactFormatter_Format(t *testing.T) {
 				)  ENGINE=INNODB;`,
 			},
 			expected: []string{
-				"Differences found \\(2\\)",
-				"Table tasks differs: missing KEY title_index\\(title\\) in schema2_\\d+.127.0.0.1:33062",
-				"Table tasks differs: missing UNIQUE KEY title_index\\(title\\) in schema1_\\d+.127.0.0.1:33060",
+				"Differences found \\(1\\)",
+				"Table tasks differs: index title_index differs in type: KEY title_index\\(title\\) in schema1_\\d+.127.0.0.1:33060, UNIQUE KEY title_index\\(title\\) in schema2_\\d+.127.0.0.1:33062",
+			},
+		},
+		"Change Index Prefix Length": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					title VARCHAR(255) NOT NULL,
+					PRIMARY KEY (id),
+					KEY title_index (title(10))
+				)  ENGINE=INNODB;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					title VARCHAR(255) NOT NULL,
+					PRIMARY KEY (id),
+					KEY title_index (title(20)) COMMENT 'by the task''s title'
+				)  ENGINE=INNODB;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"Table tasks differs: index title_index differs in prefix length, comment: KEY title_index\\(title\\(10\\)\\) in schema1_\\d+.127.0.0.1:33060, KEY title_index\\(title\\(20\\)\\) COMMENT 'by the task''s title' in schema2_\\d+.127.0.0.1:33062",
+			},
+		},
+		"Add Fulltext Index": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					title VARCHAR(255) NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					title VARCHAR(255) NOT NULL,
+					PRIMARY KEY (id),
+					FULLTEXT KEY title_index (title)
+				)  ENGINE=INNODB;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"Table tasks differs: missing FULLTEXT KEY title_index\\(title\\) in schema1_\\d+.127.0.0.1:33060",
 			},
 		},
 		"Add Foreign Key": {
@@ -196,7 +238,7 @@ func TestCompactFormatter_Format(t *testing.T) {
 			},
 			expected: []string{
 				"Differences found \\(1\\)",
-				"Table tasks differs: missing FOREIGN KEY tasks_ibfk_1\\(parent_id\\) REFERENCES tasks\\(id\\) in schema1_\\d+.127.0.0.1:33060",
+				"Table tasks differs: missing FOREIGN KEY tasks_ibfk_1\\(parent_id\\) REFERENCES tasks\\(id\\) ON UPDATE CASCADE in schema1_\\d+.127.0.0.1:33060",
 			},
 		},
 		//	Drop Foreign Key
@@ -218,7 +260,29 @@ func TestCompactFormatter_Format(t *testing.T) {
 			},
 			expected: []string{
 				"Differences found \\(1\\)",
-				"Table tasks differs: missing FOREIGN KEY tasks_ibfk_1\\(parent_id\\) REFERENCES tasks\\(id\\) in schema2_\\d+.127.0.0.1:33062",
+				"Table tasks differs: missing FOREIGN KEY tasks_ibfk_1\\(parent_id\\) REFERENCES tasks\\(id\\) ON UPDATE CASCADE in schema2_\\d+.127.0.0.1:33062",
+			},
+		},
+		"Change Foreign Key Action": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					parent_id BIGINT NOT NULL,
+					PRIMARY KEY (id),
+					FOREIGN KEY tasks_ibfk_1(parent_id) REFERENCES tasks(id)
+				)  ENGINE=INNODB;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					parent_id BIGINT NOT NULL,
+					PRIMARY KEY (id),
+					FOREIGN KEY tasks_ibfk_1(parent_id) REFERENCES tasks(id) ON DELETE CASCADE
+				)  ENGINE=INNODB;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"Table tasks differs: foreign key tasks_ibfk_1 differs in on delete: FOREIGN KEY tasks_ibfk_1\\(parent_id\\) REFERENCES tasks\\(id\\) in schema1_\\d+.127.0.0.1:33060, FOREIGN KEY tasks_ibfk_1\\(parent_id\\) REFERENCES tasks\\(id\\) ON DELETE CASCADE in schema2_\\d+.127.0.0.1:33062",
 			},
 		},
 		"Rename column": {
@@ -259,6 +323,28 @@ func TestCompactFormatter_Format(t *testing.T) {
 				"Table tasks differs: column id differs in column type: bigint\\(20\\) NOT NULL in schema1_\\d+.127.0.0.1:33060, bigint\\(20\\) NOT NULL AUTO_INCREMENT in schema2_\\d+.127.0.0.1:33062",
 			},
 		},
+		"Move Column": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					title VARCHAR(255),
+					parent_id BIGINT NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					parent_id BIGINT NOT NULL,
+					title VARCHAR(255),
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"Table tasks differs: column parent_id is in a different position: AFTER `title` in schema1_\\d+.127.0.0.1:33060, AFTER `id` in schema2_\\d+.127.0.0.1:33062",
+			},
+		},
 		"Change Charset": {
 			schema1: []string{
 				`CREATE TABLE IF NOT EXISTS tasks (
@@ -303,6 +389,48 @@ func TestCompactFormatter_Format(t *testing.T) {
 				"Table tasks is absent in schema1_\\d+.127.0.0.1:33060",
 			},
 		},
+		"Rename Table": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					title VARCHAR(255) NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS todos (
+					id BIGINT AUTO_INCREMENT,
+					title VARCHAR(255) NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"Table tasks in schema1_\\d+.127.0.0.1:33060 was probably renamed to todos in schema2_\\d+.127.0.0.1:33062",
+			},
+		},
+		"Rename Column": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					title VARCHAR(255) NOT NULL,
+					PRIMARY KEY (id),
+					KEY title_index (title)
+				)  ENGINE=INNODB;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					name VARCHAR(255) NOT NULL,
+					PRIMARY KEY (id),
+					KEY title_index (name)
+				)  ENGINE=INNODB;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"Table tasks differs: column title in schema1_\\d+.127.0.0.1:33060 was probably renamed to name in schema2_\\d+.127.0.0.1:33062",
+			},
+		},
 		"Schema Migrations": {
 			schema1: []string{
 				`CREATE TABLE IF NOT EXISTS schema_migrations (
@@ -330,6 +458,185 @@ func TestCompactFormatter_Format(t *testing.T) {
 				"\t\t\t- 20190817000000",
 			},
 		},
+		"Missing Trigger": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+				`CREATE TRIGGER tasks_audit AFTER INSERT ON tasks FOR EACH ROW SET @last_task = NEW.id;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"Trigger tasks_audit on table tasks is absent in schema1_\\d+.127.0.0.1:33060",
+			},
+		},
+		"Trigger Differs": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+				`CREATE TRIGGER tasks_audit AFTER INSERT ON tasks FOR EACH ROW SET @last_task = NEW.id;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+				`CREATE TRIGGER tasks_audit BEFORE INSERT ON tasks FOR EACH ROW SET @last_task = NEW.id;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"Trigger tasks_audit on table tasks differs in timing between schema1_\\d+.127.0.0.1:33060 and schema2_\\d+.127.0.0.1:33062",
+			},
+		},
+		"Missing View": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					done TINYINT NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					done TINYINT NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+				`CREATE VIEW pending_tasks AS SELECT id FROM tasks WHERE done = 0;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"View pending_tasks is absent in schema1_\\d+.127.0.0.1:33060",
+			},
+		},
+		"View Differs": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					done TINYINT NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+				`CREATE VIEW pending_tasks AS SELECT id FROM tasks WHERE done = 0;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					done TINYINT NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+				`CREATE ALGORITHM=MERGE VIEW pending_tasks AS SELECT id FROM tasks WHERE done = 0 WITH CHECK OPTION;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"View pending_tasks differs in algorithm, check option between schema1_\\d+.127.0.0.1:33060 and schema2_\\d+.127.0.0.1:33062",
+			},
+		},
+		"Missing Event": {
+			schema1: []string{},
+			schema2: []string{
+				`CREATE EVENT purge_tasks ON SCHEDULE EVERY 1 DAY DO DELETE FROM tasks;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"Event purge_tasks is absent in schema1_\\d+.127.0.0.1:33060",
+			},
+		},
+		"Event Differs": {
+			schema1: []string{
+				`CREATE EVENT purge_tasks ON SCHEDULE EVERY 1 DAY DO DELETE FROM tasks;`,
+			},
+			schema2: []string{
+				`CREATE EVENT purge_tasks ON SCHEDULE EVERY 1 HOUR DISABLE DO DELETE FROM tasks;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"Event purge_tasks differs in schedule, status between schema1_\\d+.127.0.0.1:33060 and schema2_\\d+.127.0.0.1:33062",
+			},
+		},
+		"Missing Partition": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS events (
+					id INT NOT NULL,
+					year INT NOT NULL,
+					PRIMARY KEY (id, year)
+				)  ENGINE=INNODB
+				PARTITION BY RANGE (year) (
+					PARTITION p2019 VALUES LESS THAN (2020),
+					PARTITION pmax VALUES LESS THAN MAXVALUE
+				);`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS events (
+					id INT NOT NULL,
+					year INT NOT NULL,
+					name VARCHAR(255),
+					PRIMARY KEY (id, year)
+				)  ENGINE=INNODB
+				PARTITION BY RANGE (year) (
+					PARTITION p2019 VALUES LESS THAN (2020),
+					PARTITION p2020 VALUES LESS THAN (2021),
+					PARTITION pmax VALUES LESS THAN MAXVALUE
+				);`,
+			},
+			expected: []string{
+				"Differences found \\(2\\)",
+				"Table events differs: missing column name in schema1_\\d+.127.0.0.1:33060",
+				"Table events differs: missing partition p2020 in schema1_\\d+.127.0.0.1:33060",
+			},
+		},
+		"Partitioning Differs": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS events (
+					id INT NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB
+				PARTITION BY HASH (id) PARTITIONS 4;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS events (
+					id INT NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB
+				PARTITION BY KEY (id) PARTITIONS 4;`,
+			},
+			expected: []string{
+				"Differences found \\(1\\)",
+				"Table events differs: PARTITION BY HASH \\(`id`\\) PARTITIONS 4 in schema1_\\d+.127.0.0.1:33060, PARTITION BY KEY \\(`id`\\) PARTITIONS 4 in schema2_\\d+.127.0.0.1:33062",
+			},
+		},
+		"Reformatted View": {
+			schema1: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					done TINYINT NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+				`CREATE VIEW pending_tasks AS SELECT id FROM tasks WHERE done = 0;`,
+			},
+			schema2: []string{
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id BIGINT AUTO_INCREMENT,
+					done TINYINT NOT NULL,
+					PRIMARY KEY (id)
+				)  ENGINE=INNODB;`,
+				`CREATE VIEW pending_tasks AS
+					select id
+					from tasks
+					where done = 0;`,
+			},
+			expected: []string{
+				"No differences found",
+			},
+		},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
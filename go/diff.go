@@ -14,6 +14,8 @@
 package mydiff
 
 import (
+	"time"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/skeema/tengo"
@@ -25,11 +27,44 @@ const DiffTypeMigrations = 10 ^ 6 + iota
 
 // Diff encapsulates the data necessary to compute a diff between two schemas
 // in servers denoted by DSN1, and DSN2
+//
+// The differences are computed once, the first time they are needed, so the
+// fields of a Diff are not meant to be changed afterwards.
 type Diff struct {
-	DSN1, DSN2        *ParsedDSN
-	From, To          *tengo.Schema
+	DSN1, DSN2 *ParsedDSN
+	From, To   *tengo.Schema
+	// Flavor1 and Flavor2 are the flavors of the servers denoted by DSN1 and
+	// DSN2, see tengo.Instance.Flavor. They are tengo.FlavorUnknown unless given.
+	Flavor1, Flavor2  tengo.Flavor
 	IncludeMigrations bool
 	MigrationsCol     string
+	// RenameHints confirm or override the renames of tables and
+	// columns guessed while computing the diff.
+	RenameHints RenameHints
+	// Filter leaves tables, columns and kinds of changes out of the diff
+	Filter *Filter
+	// Baseline lists the differences accepted between the two schemas,
+	// which are left out of the diff until they expire.
+	Baseline Baseline
+	// Strict disables the normalization of the differences that are only due
+	// to the flavors of the servers, like int(11) and int, or utf8 and utf8mb3.
+	Strict bool
+	// LowerCaseNames1 and LowerCaseNames2 are the lower_case_table_names
+	// settings of the servers denoted by DSN1 and DSN2, see LowerCaseTableNames
+	LowerCaseNames1, LowerCaseNames2 int
+	// StrictForeignKeyNaming reports the foreign keys that only differ in
+	// name, which are otherwise taken as the same foreign key.
+	StrictForeignKeyNaming bool
+
+	// objects1 and objects2 cache the objects of From and To that tengo
+	// doesn't load, see Diff.objects
+	objects1, objects2 *Objects
+	// computed tells if the differences between the schemas were already
+	// computed, and cached in accepted, changes and err, see Diff.differences
+	computed bool
+	accepted []tengo.ObjectDiff
+	changes  []Change
+	err      error
 }
 
 // NewDiff creates a new Diff
@@ -44,6 +79,35 @@ func NewDiff(DSN1, DSN2 string, from, to *tengo.Schema, includeMigrations bool,
 	}
 }
 
+// Reverse returns the diff between the same schemas in the other direction,
+// from To to From, whose statements undo the ones of the receiver.
+//
+// The kinds of changes ignored by the filter, and the changes accepted by the
+// baseline, are ignored and accepted in their opposite kind, i.e. drop_index
+// for add_index. The minimum severity of the filter is kept as it is, even if
+// a change and its opposite may have different severities.
+func (d *Diff) Reverse() *Diff {
+	return &Diff{
+		DSN1:                   d.DSN2,
+		DSN2:                   d.DSN1,
+		From:                   d.To,
+		To:                     d.From,
+		Flavor1:                d.Flavor2,
+		Flavor2:                d.Flavor1,
+		IncludeMigrations:      d.IncludeMigrations,
+		MigrationsCol:          d.MigrationsCol,
+		RenameHints:            d.RenameHints,
+		Filter:                 d.Filter.reversed(),
+		Baseline:               d.Baseline.reversed(),
+		Strict:                 d.Strict,
+		LowerCaseNames1:        d.LowerCaseNames2,
+		LowerCaseNames2:        d.LowerCaseNames1,
+		StrictForeignKeyNaming: d.StrictForeignKeyNaming,
+		objects1:               d.objects2,
+		objects2:               d.objects1,
+	}
+}
+
 // Raw returns the tengo.SchemaDiff between the receiver's
 // From an To fields
 func (d *Diff) Raw() *tengo.SchemaDiff {
@@ -51,19 +115,12 @@ func (d *Diff) Raw() *tengo.SchemaDiff {
 }
 
 // Compute computes the difference between the two schemas
-// returning an Difference object.
+// returning an Difference object. Changes accepted by the
+// baseline are left out.
 func (d *Diff) Compute() []tengo.ObjectDiff {
-	objectDiffs := d.Raw().ObjectDiffs()
-
-	var res []tengo.ObjectDiff = make([]tengo.ObjectDiff, len(objectDiffs))
-	for i, od := range objectDiffs {
-		switch od.(type) {
-		case *tengo.TableDiff:
-			res[i] = &TableDiff{od.(*tengo.TableDiff)}
-		default:
-			res[i] = od
-		}
-	}
+	accepted, _ := d.differences()
+	res := make([]tengo.ObjectDiff, len(accepted), len(accepted)+1)
+	copy(res, accepted)
 
 	if d.IncludeMigrations {
 		migrationsDiff, err := NewMigrationsDiff(d)
@@ -78,3 +135,102 @@ func (d *Diff) Compute() []tengo.ObjectDiff {
 
 	return res
 }
+
+// BaselineReport checks the baseline of the diff against the differences
+// between the two schemas.
+func (d *Diff) BaselineReport() BaselineReport {
+	_, changes := d.differences()
+	return d.Baseline.Check(changes, time.Now())
+}
+
+// differences returns the differences between the two schemas left after
+// applying the baseline, and the changes of all of them, which the baseline
+// is checked against. They are only computed the first time.
+func (d *Diff) differences() ([]tengo.ObjectDiff, []Change) {
+	if !d.computed {
+		ods, err := d.compute()
+		d.err = err
+		d.changes = changes(ods)
+		d.accepted = d.Baseline.Apply(ods, time.Now())
+		d.computed = true
+	}
+	return d.accepted, d.changes
+}
+
+// Err returns the error that prevented computing the differences between the
+// two schemas, if any, like failing to introspect the objects that tengo
+// doesn't load. Then, no differences are reported.
+func (d *Diff) Err() error {
+	d.differences()
+	return d.err
+}
+
+// Warnings returns the names in each schema that would collide or break if
+// the schema was moved to the other server, because of their different
+// lower_case_table_names settings, and the tables that the differences
+// would rebuild because of their stored generated columns.
+func (d *Diff) Warnings() []string {
+	warnings := caseWarnings(d.From, d.DSN1.Addr, d.DSN2.Addr, d.LowerCaseNames1, d.LowerCaseNames2)
+	warnings = append(warnings, caseWarnings(d.To, d.DSN2.Addr, d.DSN1.Addr, d.LowerCaseNames2, d.LowerCaseNames1)...)
+	accepted, _ := d.differences()
+	return append(warnings, generationWarnings(accepted)...)
+}
+
+// compute computes the differences between the two schemas, before
+// leaving out the ones accepted by the baseline. Unless the diff is strict,
+// the differences that are only due to the flavors of the servers are not
+// computed. If the servers have different lower_case_table_names settings,
+// objects whose names only differ in case are taken as the same.
+// The features of tables that tengo doesn't support, like partitioning, are
+// compared apart from the rest of their definitions.
+func (d *Diff) compute() ([]tengo.ObjectDiff, error) {
+	to := d.To
+	if d.LowerCaseNames1 != d.LowerCaseNames2 {
+		to = foldCase(to, d.From)
+	}
+	from, to, lifted := splitFeatures(d.From, to, d.Flavor1, d.Flavor2)
+	if !d.Strict {
+		from = normalizer{from: d.Flavor1, to: d.Flavor2}.schema(from, to)
+	}
+	objectDiffs := tengo.NewSchemaDiff(from, to).ObjectDiffs()
+
+	var res []tengo.ObjectDiff = make([]tengo.ObjectDiff, len(objectDiffs))
+	for i, od := range objectDiffs {
+		switch od.(type) {
+		case *tengo.TableDiff:
+			res[i] = &TableDiff{TableDiff: od.(*tengo.TableDiff), FromFlavor: d.Flavor1, ToFlavor: d.Flavor2, StrictForeignKeyNaming: d.StrictForeignKeyNaming}
+		default:
+			res[i] = od
+		}
+	}
+	for _, lt := range lifted {
+		res = append(res, comparePartitioning(lt.from.Name, lt.fromFeatures.partitioning, lt.toFeatures.partitioning)...)
+	}
+	objects1, objects2, err := d.objects()
+	if err != nil {
+		return nil, err
+	}
+	res = append(res, compareTriggers(objects1.Triggers, objects2.Triggers)...)
+	res = append(res, compareViews(objects1.Views, objects2.Views)...)
+	res = append(res, compareEvents(objects1.Events, objects2.Events, time.Now())...)
+
+	// The tables and columns left out by the filter are left out before
+	// detecting renames, so they are not mistaken for renamed ones, and the
+	// whole filter is applied after it, so renames can be filtered out as
+	// well.
+	res = d.Filter.scope().Apply(res)
+	res = detectTableRenames(res, d.RenameHints)
+	for _, od := range res {
+		if td, ok := od.(*TableDiff); ok {
+			detectColumnRenames(td, d.RenameHints)
+		}
+	}
+	res = detectFeatureChanges(res, lifted, d.Flavor1, d.Flavor2)
+	for _, od := range res {
+		if td, ok := od.(*TableDiff); ok && td.DiffType() == tengo.DiffTypeAlter {
+			detectColumnMoves(td)
+		}
+	}
+	detectForeignKeyChanges(res)
+	return d.Filter.Apply(res), nil
+}
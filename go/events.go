@@ -0,0 +1,254 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skeema/tengo"
+)
+
+// ObjectTypeEvent is the object type of scheduled events, which tengo
+// doesn't define
+const ObjectTypeEvent tengo.ObjectType = "event"
+
+// eventTimeFormat is the format of the times of an event schedule
+const eventTimeFormat = "2006-01-02 15:04:05"
+
+// Event represents a scheduled event, as introspected from
+// information_schema.EVENTS
+type Event struct {
+	Name          string
+	Type          string // ONE TIME or RECURRING
+	ExecuteAt     string // only for ONE TIME events
+	IntervalValue string // only for RECURRING events, and so the rest
+	IntervalField string
+	Starts        string
+	Ends          string
+	Status        string // ENABLED, DISABLED or SLAVESIDE_DISABLED
+	OnCompletion  string // PRESERVE or NOT PRESERVE
+	Body          string
+	Comment       string
+	Definer       string
+	SQLMode       string // sql_mode in effect at creation time
+}
+
+// Schedule returns the ON SCHEDULE clause of the event
+func (e *Event) Schedule() string {
+	if e.Type == "ONE TIME" {
+		return fmt.Sprintf("AT '%s'", e.ExecuteAt)
+	}
+	schedule := fmt.Sprintf("EVERY '%s' %s", e.IntervalValue, e.IntervalField)
+	if e.Starts != "" {
+		schedule += fmt.Sprintf(" STARTS '%s'", e.Starts)
+	}
+	if e.Ends != "" {
+		schedule += fmt.Sprintf(" ENDS '%s'", e.Ends)
+	}
+	return schedule
+}
+
+// CreateStatement returns a SQL statement that, if run, would create the event
+func (e *Event) CreateStatement() string {
+	return fmt.Sprintf("CREATE %s EVENT %s %s", definerClause(e.Definer), tengo.EscapeIdentifier(e.Name), e.clauses())
+}
+
+// AlterStatement returns a SQL statement that, if run, would make an existing
+// event with the same name identical to this one.
+func (e *Event) AlterStatement() string {
+	return fmt.Sprintf("ALTER %s EVENT %s %s", definerClause(e.Definer), tengo.EscapeIdentifier(e.Name), e.clauses())
+}
+
+// DropStatement returns a SQL statement that, if run, would drop the event
+func (e *Event) DropStatement() string {
+	return fmt.Sprintf("DROP EVENT IF EXISTS %s", tengo.EscapeIdentifier(e.Name))
+}
+
+// clauses returns the clauses shared by the CREATE and ALTER EVENT statements
+func (e *Event) clauses() string {
+	var status string
+	switch e.Status {
+	case "ENABLED":
+		status = "ENABLE"
+	case "DISABLED":
+		status = "DISABLE"
+	default:
+		status = "DISABLE ON SLAVE"
+	}
+	var comment string
+	if e.Comment != "" {
+		comment = fmt.Sprintf(" COMMENT '%s'", strings.Replace(e.Comment, "'", "''", -1))
+	}
+	return fmt.Sprintf("ON SCHEDULE %s ON COMPLETION %s %s%s DO %s", e.Schedule(), e.OnCompletion, status, comment, e.Body)
+}
+
+// Equals returns true if two events are equivalent at the given time,
+// false otherwise.
+func (e *Event) Equals(other *Event, now time.Time) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+	return len(e.differences(other, now)) == 0
+}
+
+// differences returns the names of the attributes of the events that differ
+// at the given time.
+func (e *Event) differences(other *Event, now time.Time) []string {
+	var res []string
+	if e.Type != other.Type || e.ExecuteAt != other.ExecuteAt ||
+		e.IntervalValue != other.IntervalValue || e.IntervalField != other.IntervalField ||
+		e.Ends != other.Ends || !sameStart(e.Starts, other.Starts, now) {
+		res = append(res, "schedule")
+	}
+	if e.Status != other.Status {
+		res = append(res, "status")
+	}
+	if e.OnCompletion != other.OnCompletion {
+		res = append(res, "on completion")
+	}
+	if e.Body != other.Body {
+		res = append(res, "body")
+	}
+	if e.Definer != other.Definer {
+		res = append(res, "definer")
+	}
+	return res
+}
+
+// sameStart returns true if two recurring events starting at the given
+// times are scheduled the same way from now on. STARTS defaults to the time
+// the event is created, so it usually differs between servers, even if
+// the events are identical otherwise.
+func sameStart(start, otherStart string, now time.Time) bool {
+	if start == otherStart {
+		return true
+	}
+	t1, err1 := time.ParseInLocation(eventTimeFormat, start, time.Local)
+	t2, err2 := time.ParseInLocation(eventTimeFormat, otherStart, time.Local)
+	return err1 == nil && err2 == nil && t1.Before(now) && t2.Before(now)
+}
+
+// EventDiff is an implementation of tengo.ObjectDiff representing an event
+// that is missing in one of the schemas, or that differs between them,
+// in which case both From and To are set.
+type EventDiff struct {
+	From, To *Event
+}
+
+// DiffType (see tengo.ObjectDiff)
+func (ed *EventDiff) DiffType() tengo.DiffType {
+	switch {
+	case ed.From == nil:
+		return tengo.DiffTypeCreate
+	case ed.To == nil:
+		return tengo.DiffTypeDrop
+	}
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey (see tengo.ObjectDiff)
+func (ed *EventDiff) ObjectKey() tengo.ObjectKey {
+	if ed.To != nil {
+		return tengo.ObjectKey{Type: ObjectTypeEvent, Name: ed.To.Name}
+	}
+	return tengo.ObjectKey{Type: ObjectTypeEvent, Name: ed.From.Name}
+}
+
+// Statement (see tengo.ObjectDiff) returns the statements returned by
+// Statements separated by semicolons and new lines.
+func (ed *EventDiff) Statement(tengo.StatementModifiers) (string, error) {
+	return strings.Join(ed.Statements(), ";\n"), nil
+}
+
+// Statements returns the statements that, if run, would make the event in
+// the From schema identical to the one in the To schema. Like triggers,
+// events are created or altered with the sql_mode of the To schema's event,
+// which is restored afterwards.
+func (ed *EventDiff) Statements() []string {
+	var stmt string
+	switch ed.DiffType() {
+	case tengo.DiffTypeDrop:
+		return []string{ed.From.DropStatement()}
+	case tengo.DiffTypeCreate:
+		stmt = ed.To.CreateStatement()
+	default:
+		stmt = ed.To.AlterStatement()
+	}
+	return []string{
+		"SET @mydiff_sql_mode = @@SESSION.sql_mode",
+		fmt.Sprintf("SET SESSION sql_mode = '%s'", ed.To.SQLMode),
+		stmt,
+		"SET SESSION sql_mode = @mydiff_sql_mode",
+	}
+}
+
+// compareEvents returns the diffs of the events missing in either list,
+// or that differ between them at the given time.
+func compareEvents(from, to []*Event, now time.Time) []tengo.ObjectDiff {
+	var res []tengo.ObjectDiff
+	toByName := map[string]*Event{}
+	for _, e := range to {
+		toByName[e.Name] = e
+	}
+	fromByName := map[string]*Event{}
+	for _, e := range from {
+		fromByName[e.Name] = e
+		if other, ok := toByName[e.Name]; !ok {
+			res = append(res, &EventDiff{From: e})
+		} else if !e.Equals(other, now) {
+			res = append(res, &EventDiff{From: e, To: other})
+		}
+	}
+	for _, e := range to {
+		if _, ok := fromByName[e.Name]; !ok {
+			res = append(res, &EventDiff{To: e})
+		}
+	}
+	return res
+}
+
+// loadEvents returns the events of the given schema
+func loadEvents(db *sql.DB, schema string) ([]*Event, error) {
+	rows, err := db.Query(`
+		SELECT event_name, event_type, CAST(execute_at AS CHAR), interval_value,
+		       interval_field, CAST(starts AS CHAR), CAST(ends AS CHAR), status,
+		       on_completion, event_definition, event_comment, definer, sql_mode
+		FROM   information_schema.events
+		WHERE  event_schema = ?
+		ORDER BY event_name`, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		e := &Event{}
+		var executeAt, intervalValue, intervalField, starts, ends sql.NullString
+		if err := rows.Scan(&e.Name, &e.Type, &executeAt, &intervalValue, &intervalField, &starts, &ends, &e.Status, &e.OnCompletion, &e.Body, &e.Comment, &e.Definer, &e.SQLMode); err != nil {
+			return nil, err
+		}
+		e.ExecuteAt, e.IntervalValue, e.IntervalField = executeAt.String, intervalValue.String, intervalField.String
+		e.Starts, e.Ends = starts.String, ends.String
+		if e.Status == "REPLICA_SIDE_DISABLED" {
+			// MySQL 8.0.22 renamed SLAVESIDE_DISABLED
+			e.Status = "SLAVESIDE_DISABLED"
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
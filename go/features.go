@@ -0,0 +1,154 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"github.com/skeema/tengo"
+)
+
+// FunctionalIndexes returns the indexes of a schema in a server with
+// functional key parts, like KEY ((lower(title))), named as table.index.
+// tengo expects every key part to name a column, so schemas with these
+// indexes fail to load.
+func FunctionalIndexes(instance *tengo.Instance, schema string) ([]string, error) {
+	db, err := instance.Connect("information_schema", "")
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	err = db.Select(&res, "SELECT DISTINCT CONCAT(table_name, '.', index_name) FROM statistics WHERE table_schema = ? AND column_name IS NULL ORDER BY 1", schema)
+	return res, err
+}
+
+// tableFeatures are the parts of the definition of a table that tengo
+// doesn't support, lifted from its CREATE TABLE statement.
+type tableFeatures struct {
+	partitioning     *Partitioning
+	indexes          map[string]IndexAttributes
+	checks           []*Check
+	invisibleColumns map[string]bool
+	generatedColumns map[string]*Generation
+}
+
+// liftFeatures returns the CREATE TABLE statement of table t without the
+// parts tengo doesn't support, along with these parts.
+func liftFeatures(t *tengo.Table, flavor tengo.Flavor) (string, *tableFeatures) {
+	partitioning, stmt := parsePartitioning(t.CreateStatement)
+	stmt, indexes := liftIndexAttributes(stmt)
+	stmt, checks := liftChecks(stmt)
+	stmt, invisibleColumns := liftInvisibleColumns(stmt)
+	stmt, generatedColumns := liftGeneratedColumns(stmt, t, flavor)
+	return stmt, &tableFeatures{
+		partitioning:     partitioning,
+		indexes:          indexes,
+		checks:           checks,
+		invisibleColumns: invisibleColumns,
+		generatedColumns: generatedColumns,
+	}
+}
+
+// liftedTable is a table present in both schemas of a diff whose features
+// unsupported by tengo were lifted from its definitions.
+type liftedTable struct {
+	from, to                 *tengo.Table
+	fromFeatures, toFeatures *tableFeatures
+}
+
+// splitFeatures returns copies of the from and to schemas where the tables
+// present in both are stripped of the features tengo doesn't support, which
+// are returned apart, so they can be compared by mydiff. Otherwise, tengo
+// would take any difference between these tables as unsupported.
+//
+// Tables using other features unsupported by tengo are left as they are.
+func splitFeatures(from, to *tengo.Schema, fromFlavor, toFlavor tengo.Flavor) (*tengo.Schema, *tengo.Schema, []*liftedTable) {
+	var lifted []*liftedTable
+	resFrom, resTo := *from, *to
+	resFrom.Tables = make([]*tengo.Table, len(from.Tables))
+	copy(resFrom.Tables, from.Tables)
+	resTo.Tables = make([]*tengo.Table, len(to.Tables))
+	copy(resTo.Tables, to.Tables)
+
+	toIndexes := map[string]int{}
+	for i, t := range to.Tables {
+		toIndexes[t.Name] = i
+	}
+	for i, t := range from.Tables {
+		j, ok := toIndexes[t.Name]
+		if !ok || (!t.UnsupportedDDL && !to.Tables[j].UnsupportedDDL) {
+			continue
+		}
+		fromStatement, fromFeatures := liftFeatures(t, fromFlavor)
+		toStatement, toFeatures := liftFeatures(to.Tables[j], toFlavor)
+		fromTable := withCreateStatement(t, fromStatement, fromFlavor)
+		toTable := withCreateStatement(to.Tables[j], toStatement, toFlavor)
+		if fromTable.UnsupportedDDL || toTable.UnsupportedDDL {
+			continue
+		}
+		resFrom.Tables[i], resTo.Tables[j] = fromTable, toTable
+		lifted = append(lifted, &liftedTable{from: fromTable, to: toTable, fromFeatures: fromFeatures, toFeatures: toFeatures})
+	}
+	return &resFrom, &resTo, lifted
+}
+
+// withCreateStatement returns table t, or a copy of it with the given CREATE
+// TABLE statement, whose support by tengo is reconsidered accordingly.
+func withCreateStatement(t *tengo.Table, createStatement string, flavor tengo.Flavor) *tengo.Table {
+	if createStatement == t.CreateStatement {
+		return t
+	}
+	res := *t
+	res.CreateStatement = createStatement
+	actual, _ := tengo.ParseCreateAutoInc(createStatement)
+	expected, _ := tengo.ParseCreateAutoInc(res.GeneratedCreateStatement(flavor))
+	res.UnsupportedDDL = actual != expected
+	return &res
+}
+
+// detectFeatureChanges replaces and adds the alter clauses of the table diffs
+// accounting for the features of the lifted tables. Tables only differing in
+// these features are missed by tengo, so table diffs are added for them.
+func detectFeatureChanges(ods []tengo.ObjectDiff, lifted []*liftedTable, fromFlavor, toFlavor tengo.Flavor) []tengo.ObjectDiff {
+	liftedByName := map[string]*liftedTable{}
+	for _, lt := range lifted {
+		liftedByName[lt.from.Name] = lt
+	}
+	altered := map[string]bool{}
+	for _, od := range ods {
+		if td, ok := od.(*TableDiff); ok && td.DiffType() == tengo.DiffTypeAlter {
+			altered[td.From.Name] = true
+			alterFeatures(td, liftedByName[td.From.Name])
+		}
+	}
+	for _, lt := range lifted {
+		if altered[lt.from.Name] {
+			continue
+		}
+		td := newAlterTableDiff(lt.from, lt.to, []tengo.TableAlterClause{}, fromFlavor, toFlavor)
+		if alterFeatures(td, lt); len(td.AlterClauses()) > 0 {
+			ods = append(ods, td)
+		}
+	}
+	return ods
+}
+
+// alterFeatures alters the clauses of a table diff, given the lifted table if
+// any. Index clauses are replaced anyway, see alterIndexes.
+func alterFeatures(td *TableDiff, lt *liftedTable) {
+	alterIndexes(td, lt)
+	if lt == nil {
+		return
+	}
+	alterColumns(td, lt)
+	alterChecks(td, lt)
+}
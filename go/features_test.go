@@ -0,0 +1,220 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+// mysql80Table returns a table as loaded from MySQL 8.0, whose CREATE TABLE
+// statement is given the definitions of the features tengo doesn't load by
+// replacing the ones tengo generates.
+func mysql80Table(replacements ...string) *tengo.Table {
+	id := &tengo.Column{Name: "id", TypeInDB: "int", Default: tengo.ColumnDefaultNull}
+	position := &tengo.Column{Name: "position", TypeInDB: "int", Nullable: true, Default: tengo.ColumnDefaultNull}
+	table := &tengo.Table{
+		Name: "tasks", Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true,
+		Columns:    []*tengo.Column{id, position},
+		PrimaryKey: &tengo.Index{Name: "PRIMARY", Columns: []*tengo.Column{id}, SubParts: []uint16{0}, PrimaryKey: true, Unique: true},
+		SecondaryIndexes: []*tengo.Index{
+			{Name: "position", Columns: []*tengo.Column{position}, SubParts: []uint16{0}},
+		},
+	}
+	stmt := table.GeneratedCreateStatement(tengo.FlavorMySQL80)
+	stmt = strings.NewReplacer(replacements...).Replace(stmt)
+	table.UnsupportedDDL = stmt != table.CreateStatement
+	table.CreateStatement = stmt
+	return table
+}
+
+func TestDiff_MySQL80Features(t *testing.T) {
+	from := mysql80Table(
+		"(`position`)\n", "(`position`),\n  CONSTRAINT `tasks_chk_1` CHECK ((`position` > 0))\n",
+	)
+	to := mysql80Table(
+		"`position` int DEFAULT NULL,", "`position` int DEFAULT NULL /*!80023 INVISIBLE */,",
+		"(`position`)\n", "(`position` DESC) /*!80000 INVISIBLE */,\n  CONSTRAINT `tasks_chk_1` CHECK ((`position` > 0)) /*!80016 NOT ENFORCED */,\n  CONSTRAINT `tasks_chk_2` CHECK ((`id` > 0))\n",
+	)
+	diff := &Diff{
+		DSN1:     ParseDSN(DSN1),
+		DSN2:     ParseDSN(DSN2),
+		From:     &tengo.Schema{Name: "tasks", Tables: []*tengo.Table{from}},
+		To:       &tengo.Schema{Name: "tasks", Tables: []*tengo.Table{to}},
+		Flavor1:  tengo.FlavorMySQL80,
+		Flavor2:  tengo.FlavorMySQL80,
+		objects1: &Objects{},
+		objects2: &Objects{},
+	}
+
+	ids := []string{}
+	for _, c := range diff.Changes() {
+		ids = append(ids, c.ID)
+	}
+	Equal(t, []string{
+		"modify_index:tasks.position",
+		"modify_column:tasks.position",
+		"modify_check:tasks.tasks_chk_1",
+		"add_check:tasks.tasks_chk_2",
+	}, ids)
+
+	sqlFmt, _ := NewFormatter("sql")
+	Equal(t, "-- Severity: risky\n"+
+		"ALTER TABLE `tasks` DROP KEY `position`, ADD KEY `position` (`position` DESC) /*!80000 INVISIBLE */, "+
+		"MODIFY COLUMN `position` int DEFAULT NULL /*!80023 INVISIBLE */, "+
+		"ALTER CHECK `tasks_chk_1` NOT ENFORCED, "+
+		"ADD CONSTRAINT `tasks_chk_2` CHECK ((`id` > 0));\n", sqlFmt.Format(diff))
+
+	compactFmt, _ := NewFormatter("compact")
+	out := compactFmt.Format(diff).(string)
+	Contains(t, out, "Table tasks differs: index position differs in order, visibility: KEY position(position) in tasks.127.0.0.1:33060, KEY position(position DESC) INVISIBLE in tasks.127.0.0.1:33062")
+	Contains(t, out, "Table tasks differs: column position differs in visibility: visible in tasks.127.0.0.1:33060, invisible in tasks.127.0.0.1:33062")
+	Contains(t, out, "Table tasks differs: check tasks_chk_1 differs in enforcement: CHECK tasks_chk_1((`position` > 0)) in tasks.127.0.0.1:33060, CHECK tasks_chk_1((`position` > 0)) NOT ENFORCED in tasks.127.0.0.1:33062")
+	Contains(t, out, "Table tasks differs: missing CHECK tasks_chk_2((`id` > 0)) in tasks.127.0.0.1:33060")
+}
+
+// mariaDBTable returns a table as loaded from MariaDB 10.3, like mysql80Table
+func mariaDBTable(replacements ...string) *tengo.Table {
+	id := &tengo.Column{Name: "id", TypeInDB: "int(11)", Default: tengo.ColumnDefaultNull}
+	position := &tengo.Column{Name: "position", TypeInDB: "int(11)", Nullable: true, Default: tengo.ColumnDefaultNull}
+	table := &tengo.Table{
+		Name: "tasks", Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_general_ci", CollationIsDefault: true,
+		Columns:    []*tengo.Column{id, position},
+		PrimaryKey: &tengo.Index{Name: "PRIMARY", Columns: []*tengo.Column{id}, SubParts: []uint16{0}, PrimaryKey: true, Unique: true},
+	}
+	stmt := table.GeneratedCreateStatement(tengo.FlavorMariaDB103)
+	stmt = strings.NewReplacer(replacements...).Replace(stmt)
+	table.UnsupportedDDL = stmt != table.CreateStatement
+	table.CreateStatement = stmt
+	return table
+}
+
+func TestDiff_MariaDBFeatures(t *testing.T) {
+	from := mariaDBTable(
+		"(`id`)\n", "(`id`),\n  CONSTRAINT `positive_position` CHECK (`position` > 0)\n",
+	)
+	to := mariaDBTable(
+		"`position` int(11) DEFAULT NULL,", "`position` int(11) DEFAULT NULL INVISIBLE,",
+	)
+	diff := &Diff{
+		DSN1:     ParseDSN(DSN1),
+		DSN2:     ParseDSN(DSN2),
+		From:     &tengo.Schema{Name: "tasks", Tables: []*tengo.Table{from}},
+		To:       &tengo.Schema{Name: "tasks", Tables: []*tengo.Table{to}},
+		Flavor1:  tengo.FlavorMariaDB103,
+		Flavor2:  tengo.FlavorMariaDB103,
+		objects1: &Objects{},
+		objects2: &Objects{},
+	}
+
+	sqlFmt, _ := NewFormatter("sql")
+	Equal(t, "-- Severity: risky\n"+
+		"ALTER TABLE `tasks` MODIFY COLUMN `position` int(11) DEFAULT NULL INVISIBLE, "+
+		"DROP CONSTRAINT `positive_position`;\n", sqlFmt.Format(diff))
+}
+
+func TestLiftFeatures(t *testing.T) {
+	stmt, features := liftFeatures(&tengo.Table{CreateStatement: "CREATE TABLE `t` (\n" +
+		"  `a` int DEFAULT NULL /*!80023 INVISIBLE */,\n" +
+		"  `b` int DEFAULT NULL,\n" +
+		"  KEY `ab` (`a`,`b` DESC),\n" +
+		"  FULLTEXT KEY `c` (`c`) /*!50100 WITH PARSER `ngram` */ ,\n" +
+		"  CONSTRAINT `t_chk_1` CHECK ((`a` > 0))\n" +
+		") ENGINE=InnoDB"}, tengo.FlavorMySQL80)
+
+	Equal(t, "CREATE TABLE `t` (\n"+
+		"  `a` int DEFAULT NULL,\n"+
+		"  `b` int DEFAULT NULL,\n"+
+		"  KEY `ab` (`a`,`b`),\n"+
+		"  KEY `c` (`c`)\n"+
+		") ENGINE=InnoDB", stmt)
+	Equal(t, map[string]bool{"a": true}, features.invisibleColumns)
+	Equal(t, []bool{false, true}, features.indexes["ab"].Desc)
+	Equal(t, IndexAttributes{Type: "FULLTEXT", Parser: "ngram"}, features.indexes["c"])
+	Equal(t, []*Check{{Name: "t_chk_1", Clause: "((`a` > 0))", Enforced: true}}, features.checks)
+}
+
+func TestDiff_GeneratedColumns(t *testing.T) {
+	table := func(replacements ...string) *tengo.Table {
+		columns := []*tengo.Column{
+			{Name: "id", TypeInDB: "int", Default: tengo.ColumnDefaultNull},
+			{Name: "total", TypeInDB: "int", Nullable: true, Default: tengo.ColumnDefaultNull},
+			{Name: "taxed", TypeInDB: "int", Nullable: true, Default: tengo.ColumnDefaultNull},
+			{Name: "doubled", TypeInDB: "int", Nullable: true, Default: tengo.ColumnDefaultNull},
+			{Name: "rounded", TypeInDB: "int", Nullable: true, Default: tengo.ColumnDefaultNull},
+			{Name: "code", TypeInDB: "varchar(20)", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true, Nullable: true, Default: tengo.ColumnDefaultNull},
+		}
+		table := &tengo.Table{
+			Name: "orders", Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true,
+			Columns:    columns,
+			PrimaryKey: &tengo.Index{Name: "PRIMARY", Columns: columns[:1], SubParts: []uint16{0}, PrimaryKey: true, Unique: true},
+		}
+		table.CreateStatement = strings.NewReplacer(replacements...).Replace(table.GeneratedCreateStatement(tengo.FlavorMySQL80))
+		table.UnsupportedDDL = true
+		return table
+	}
+	from := table(
+		"`taxed` int DEFAULT NULL", "`taxed` int GENERATED ALWAYS AS ((`total` * 1.2)) VIRTUAL",
+		"`doubled` int DEFAULT NULL", "`doubled` int GENERATED ALWAYS AS ((`total` * 2)) STORED",
+		"`code` varchar(20) DEFAULT NULL", "`code` varchar(20) GENERATED ALWAYS AS (concat(_utf8mb4'#',`id`)) VIRTUAL",
+	)
+	to := table(
+		"`taxed` int DEFAULT NULL", "`taxed` int GENERATED ALWAYS AS ((`total` * 1.21)) VIRTUAL",
+		"`doubled` int DEFAULT NULL", "`doubled` int GENERATED ALWAYS AS ((`total` * 2)) VIRTUAL",
+		"`rounded` int DEFAULT NULL", "`rounded` int GENERATED ALWAYS AS (round(`total`,-1)) STORED",
+		"`code` varchar(20) DEFAULT NULL", "`code` varchar(20) GENERATED ALWAYS AS (concat(_utf8mb4'#',`id`,_utf8mb4'!')) VIRTUAL",
+	)
+	diff := &Diff{
+		DSN1:     ParseDSN(DSN1),
+		DSN2:     ParseDSN(DSN2),
+		From:     &tengo.Schema{Name: "shop", Tables: []*tengo.Table{from}},
+		To:       &tengo.Schema{Name: "shop", Tables: []*tengo.Table{to}},
+		Flavor1:  tengo.FlavorMySQL80,
+		Flavor2:  tengo.FlavorMySQL80,
+		objects1: &Objects{},
+		objects2: &Objects{},
+	}
+
+	changes := diff.Changes()
+	ids := []string{}
+	for _, c := range changes {
+		ids = append(ids, c.ID)
+	}
+	Equal(t, []string{
+		"modify_column:orders.taxed",
+		"modify_column:orders.doubled",
+		"change_column_generation:orders.rounded",
+		"modify_column:orders.code",
+	}, ids)
+	Equal(t, "`rounded` int DEFAULT NULL", changes[2].From)
+	Equal(t, "`rounded` int GENERATED ALWAYS AS (round(`total`,-1)) STORED", changes[2].To)
+
+	sqlFmt, _ := NewFormatter("sql")
+	Equal(t, "-- Warning: Changing the stored generated column orders.doubled rebuilds the table\n"+
+		"-- Warning: Changing the stored generated column orders.rounded rebuilds the table\n"+
+		"-- Severity: risky\n"+
+		"ALTER TABLE `orders` MODIFY COLUMN `taxed` int GENERATED ALWAYS AS ((`total` * 1.21)) VIRTUAL, "+
+		"DROP COLUMN `doubled`, ADD COLUMN `doubled` int GENERATED ALWAYS AS ((`total` * 2)) VIRTUAL AFTER `taxed`, "+
+		"MODIFY COLUMN `rounded` int GENERATED ALWAYS AS (round(`total`,-1)) STORED, "+
+		"MODIFY COLUMN `code` varchar(20) GENERATED ALWAYS AS (concat(_utf8mb4'#',`id`,_utf8mb4'!')) VIRTUAL;\n", sqlFmt.Format(diff))
+
+	compactFmt, _ := NewFormatter("compact")
+	out := compactFmt.Format(diff).(string)
+	Contains(t, out, "Table orders differs: column taxed differs in generation: int GENERATED ALWAYS AS ((`total` * 1.2)) VIRTUAL in shop.127.0.0.1:33060, int GENERATED ALWAYS AS ((`total` * 1.21)) VIRTUAL in shop.127.0.0.1:33062")
+	Contains(t, out, "Table orders differs: column rounded differs in generation: int DEFAULT NULL in shop.127.0.0.1:33060, int GENERATED ALWAYS AS (round(`total`,-1)) STORED in shop.127.0.0.1:33062")
+	Contains(t, out, "Table orders differs: column code differs in generation: varchar(20) GENERATED ALWAYS AS (concat(_utf8mb4'#',`id`)) VIRTUAL in shop.127.0.0.1:33060, varchar(20) GENERATED ALWAYS AS (concat(_utf8mb4'#',`id`,_utf8mb4'!')) VIRTUAL in shop.127.0.0.1:33062")
+}
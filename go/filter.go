@@ -0,0 +1,391 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// Filter directives. They are both the names of the command line flags and
+// the directives accepted in a .mydiffignore file.
+const (
+	IncludeTables = "include-tables"
+	ExcludeTables = "exclude-tables"
+	IgnoreColumns = "ignore-columns"
+	IgnoreChanges = "ignore-changes"
+	MinSeverity   = "min-severity"
+)
+
+// DefaultIgnoreFile is the file filter rules are read from, if it exists
+// in the working directory.
+const DefaultIgnoreFile = ".mydiffignore"
+
+// changeKindGroups are the names that can be given to ignore several related
+// kinds of changes at once, besides the names of the kinds themselves.
+var changeKindGroups = map[string][]ChangeKind{
+	"tables":         {ChangeCreateTable, ChangeDropTable, ChangeRenameTable, ChangeAlterTable},
+	"columns":        {ChangeAddColumn, ChangeDropColumn, ChangeModifyColumn, ChangeRenameColumn, ChangeColumnGeneration, ChangeMoveColumn},
+	"indexes":        {ChangeAddIndex, ChangeDropIndex, ChangeModifyIndex},
+	"foreign_keys":   {ChangeAddForeignKey, ChangeDropForeignKey, ChangeModifyForeignKey},
+	"comments":       {ChangeComment},
+	"charsets":       {ChangeCharSet},
+	"create_options": {ChangeCreateOptions},
+	"engines":        {ChangeStorageEngine},
+	"routines":       {ChangeCreateRoutine, ChangeDropRoutine},
+	"databases":      {ChangeAlterDatabase},
+	"triggers":       {ChangeCreateTrigger, ChangeDropTrigger, ChangeAlterTrigger},
+	"views":          {ChangeCreateView, ChangeDropView, ChangeAlterView},
+	"events":         {ChangeCreateEvent, ChangeDropEvent, ChangeAlterEvent},
+	"partitions":     {ChangePartitioning, ChangeAddPartition, ChangeDropPartition, ChangeModifyPartition, ChangeRemovePartitioning},
+	"checks":         {ChangeAddCheck, ChangeDropCheck, ChangeModifyCheck},
+}
+
+// Filter holds the rules to leave tables, columns and kinds of changes out
+// of a diff. A nil Filter leaves nothing out.
+type Filter struct {
+	includeTables []*regexp.Regexp
+	excludeTables []*regexp.Regexp
+	// ignoreColumns are glob patterns (see path.Match) in the form table.column
+	ignoreColumns []string
+	ignoreChanges map[ChangeKind]bool
+	// minSeverity is the severity below which changes are left out
+	minSeverity Severity
+}
+
+// NewFilter returns the address of a new Filter without rules
+func NewFilter() *Filter {
+	return &Filter{ignoreChanges: map[ChangeKind]bool{}}
+}
+
+// Add adds the rules given by values to the filter. The directive determines
+// how the values are interpreted:
+//	- include-tables: regexps, only matching tables are diffed
+//	- exclude-tables: regexps, matching tables are not diffed
+//	- ignore-columns: table.column glob patterns (i.e. *.updated_at)
+//	- ignore-changes: kinds of changes (i.e. add_index) or groups of them
+//	  (tables, columns, indexes, foreign_keys, comments, charsets,
+//	  create_options, engines, routines, databases, triggers, views,
+//	  events, partitions, checks)
+//	- min-severity: the severity below which changes are left out
+//	  (cosmetic, additive, risky, breaking)
+func (f *Filter) Add(directive string, values ...string) error {
+	for _, v := range values {
+		switch directive {
+		case IncludeTables, ExcludeTables:
+			re, err := regexp.Compile(v)
+			if err != nil {
+				return fmt.Errorf("Invalid %s regexp %s: %s", directive, v, err)
+			}
+			if directive == IncludeTables {
+				f.includeTables = append(f.includeTables, re)
+			} else {
+				f.excludeTables = append(f.excludeTables, re)
+			}
+		case IgnoreColumns:
+			if _, err := path.Match(v, ""); err != nil || strings.Count(v, ".") != 1 {
+				return fmt.Errorf("Invalid %s pattern %s, it must be in the form table.column", directive, v)
+			}
+			f.ignoreColumns = append(f.ignoreColumns, v)
+		case IgnoreChanges:
+			kinds, ok := changeKindGroups[v]
+			if !ok && !isChangeKind(ChangeKind(v)) {
+				return fmt.Errorf("Unknown kind of change %s", v)
+			}
+			if !ok {
+				kinds = []ChangeKind{ChangeKind(v)}
+			}
+			for _, k := range kinds {
+				f.ignoreChanges[k] = true
+			}
+		case MinSeverity:
+			severity, err := ParseSeverity(v)
+			if err != nil {
+				return err
+			}
+			f.minSeverity = severity
+		default:
+			return fmt.Errorf("Unknown filter directive %s, only (%s,%s,%s,%s,%s) are allowed", directive, IncludeTables, ExcludeTables, IgnoreColumns, IgnoreChanges, MinSeverity)
+		}
+	}
+	return nil
+}
+
+// LoadFile adds the rules in the file at path to the filter.
+// See Load for the format of the file.
+func (f *Filter) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return f.Load(file)
+}
+
+// Load adds the rules read from r to the filter. Rules are given one per
+// line, as a directive followed by one or more values, separated by spaces
+// or tabs:
+//
+//	exclude-tables ^_.*_(gho|ghc|del)$
+//	ignore-columns *.updated_at *.created_at
+//	ignore-changes comments
+//
+// Empty lines and lines starting with # are ignored.
+func (f *Filter) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return fmt.Errorf("Invalid filter rule in line %d: expected a directive and a value", n)
+		}
+		if err := f.Add(fields[0], fields[1:]...); err != nil {
+			return fmt.Errorf("Invalid filter rule in line %d: %s", n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// IgnoreTable returns a regexp matching the tables excluded by the filter,
+// suitable for tengo.StatementModifiers, or nil if no tables are excluded.
+//
+// Included tables cannot be expressed by a single regexp matching the rest
+// of the tables, so they are only filtered by Apply.
+func (f *Filter) IgnoreTable() *regexp.Regexp {
+	if f == nil || len(f.excludeTables) == 0 {
+		return nil
+	}
+	patterns := make([]string, len(f.excludeTables))
+	for i, re := range f.excludeTables {
+		patterns[i] = fmt.Sprintf("(?:%s)", re.String())
+	}
+	return regexp.MustCompile(strings.Join(patterns, "|"))
+}
+
+// reversed returns a copy of the filter ignoring the opposite kinds of the
+// changes ignored by the receiver, for the diff in the other direction.
+func (f *Filter) reversed() *Filter {
+	if f == nil {
+		return nil
+	}
+	res := *f
+	res.ignoreChanges = map[ChangeKind]bool{}
+	for k, ignored := range f.ignoreChanges {
+		res.ignoreChanges[k.opposite()] = ignored
+	}
+	return &res
+}
+
+// scope returns a copy of the filter only leaving out tables and columns,
+// for the differences whose renames are not detected yet. The kinds and
+// severities of the changes of a rename are only known once its drop and its
+// add are paired, so leaving out either of them beforehand would turn the
+// other one into a destructive change.
+func (f *Filter) scope() *Filter {
+	if f == nil {
+		return nil
+	}
+	res := *f
+	res.ignoreChanges = map[ChangeKind]bool{}
+	res.minSeverity = 0
+	return &res
+}
+
+// Apply removes from ods the object diffs and alter clauses left out
+// by the filter. Table diffs whose alter clauses are all left out are
+// removed altogether.
+func (f *Filter) Apply(ods []tengo.ObjectDiff) []tengo.ObjectDiff {
+	if f == nil {
+		return ods
+	}
+	res := make([]tengo.ObjectDiff, 0, len(ods))
+	for _, od := range ods {
+		if td, ok := od.(*TableDiff); ok && td.DiffType() == tengo.DiffTypeAlter && td.AlterClauses() != nil {
+			if !f.includesTable(td.From.Name) {
+				continue
+			}
+			if clauses := f.clauses(td); len(clauses) > 0 {
+				td.SetAlterClauses(clauses)
+				res = append(res, td)
+			}
+			continue
+		}
+		if f.includesObject(od) {
+			res = append(res, od)
+		}
+	}
+	return res
+}
+
+func (f *Filter) includesObject(od tengo.ObjectDiff) bool {
+	switch od := od.(type) {
+	case *MigrationsDiff:
+		return true
+	case *TableDiff:
+		if (od.From != nil && !f.includesTable(od.From.Name)) || (od.To != nil && !f.includesTable(od.To.Name)) {
+			return false
+		}
+	case *RenameTableDiff:
+		if !f.includesTable(od.From.Name) || !f.includesTable(od.To.Name) {
+			return false
+		}
+	case *TriggerDiff:
+		if (od.From != nil && !f.includesTable(od.From.Table)) || (od.To != nil && !f.includesTable(od.To.Table)) {
+			return false
+		}
+	case *PartitionDiff:
+		if !f.includesTable(od.Table) {
+			return false
+		}
+	case *ViewDiff:
+		if (od.From != nil && !f.includesTable(od.From.Name)) || (od.To != nil && !f.includesTable(od.To.Name)) {
+			return false
+		}
+	}
+	cs := changes([]tengo.ObjectDiff{od})
+	for _, c := range cs {
+		if f.ignoreChanges[c.Kind] {
+			return false
+		}
+	}
+	return len(cs) == 0 || maxSeverity(cs) >= f.minSeverity
+}
+
+// includesTable returns true unless the table is excluded by the filter, or
+// others are included but it is not. A nil filter includes every table.
+func (f *Filter) includesTable(name string) bool {
+	if f == nil {
+		return true
+	}
+	for _, re := range f.excludeTables {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(f.includeTables) == 0 {
+		return true
+	}
+	for _, re := range f.includeTables {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// clauses returns the alter clauses of td that are not left out by the filter.
+func (f *Filter) clauses(td *TableDiff) []tengo.TableAlterClause {
+	var res []tengo.TableAlterClause
+	for _, c := range td.AlterClauses() {
+		change, _ := clauseChange(c, td)
+		change.Origin = c
+		if f.ignoreChanges[change.Kind] || (change.Kind != "" && change.severity() < f.minSeverity) {
+			continue
+		}
+		if f.ignoresColumnsOf(c, td.From.Name) {
+			continue
+		}
+		if f.ignoreChanges[ChangeMoveColumn] {
+			if _, ok := movesColumn(c); ok {
+				continue
+			}
+			c = withoutPosition(c)
+		}
+		if mc, ok := c.(tengo.ModifyColumn); ok && f.ignoreChanges[ChangeComment] && onlyCommentDiffers(mc.OldColumn, mc.NewColumn) {
+			continue
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+// ignoresColumnsOf returns true if c alters a column ignored by the filter,
+// or an index on any of them
+func (f *Filter) ignoresColumnsOf(c tengo.TableAlterClause, table string) bool {
+	var names []string
+	switch c := c.(type) {
+	case tengo.AddIndex:
+		names = indexColumnNames(c.Index)
+	case tengo.DropIndex:
+		names = indexColumnNames(c.Index)
+	case AlterIndex:
+		if c.From != nil {
+			names = indexColumnNames(c.From.Index)
+		}
+		if c.To != nil {
+			names = append(names, indexColumnNames(c.To.Index)...)
+		}
+	case tengo.AddColumn:
+		names = []string{c.Column.Name}
+	case tengo.DropColumn:
+		names = []string{c.Column.Name}
+	case tengo.ModifyColumn:
+		names = []string{c.OldColumn.Name}
+	case RenameColumn:
+		names = []string{c.OldColumn.Name, c.NewColumn.Name}
+	case AlterColumn:
+		return f.ignoresColumnsOf(c.Alter, table)
+	case MoveColumn:
+		return f.ignoresColumnsOf(c.Alter, table)
+	}
+	for _, name := range names {
+		for _, pattern := range f.ignoreColumns {
+			if ok, _ := path.Match(pattern, table+"."+name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// indexColumnNames returns the names of the columns of an index
+func indexColumnNames(idx *tengo.Index) []string {
+	names := make([]string, len(idx.Columns))
+	for i, c := range idx.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// onlyCommentDiffers returns true if the only difference between two columns
+// is their comment
+func onlyCommentDiffers(a, b *tengo.Column) bool {
+	if a.Comment == b.Comment {
+		return false
+	}
+	commented := *a
+	commented.Comment = b.Comment
+	return commented.Equals(b)
+}
+
+func isChangeKind(k ChangeKind) bool {
+	for _, kinds := range changeKindGroups {
+		for _, kind := range kinds {
+			if kind == k {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Load(t *testing.T) {
+	f := NewFilter()
+	err := f.Load(strings.NewReader(`
+		# gh-ost shadow tables
+		exclude-tables ^_.*_(gho|ghc|del)$
+		ignore-columns	*.updated_at   *.created_at
+		ignore-changes comments
+	`))
+	Nil(t, err)
+	Equal(t, "(?:^_.*_(gho|ghc|del)$)", f.IgnoreTable().String())
+	Equal(t, []string{"*.updated_at", "*.created_at"}, f.ignoreColumns)
+
+	Error(t, NewFilter().Load(strings.NewReader("exclude-tables")))
+	Error(t, NewFilter().Load(strings.NewReader("ignore-tables foo")))
+	Error(t, NewFilter().Add(IncludeTables, "(foo"))
+	Error(t, NewFilter().Add(IgnoreColumns, "updated_at"))
+	Error(t, NewFilter().Add(IgnoreChanges, "sequences"))
+	Nil(t, NewFilter().Add(IgnoreChanges, "add_index", "foreign_keys"))
+}
+
+func TestDiff_Filter(t *testing.T) {
+	sql1 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL COMMENT 'the title',
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+	}
+
+	sql2 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			updated_at DATETIME,
+			PRIMARY KEY (id),
+			KEY title_index (title),
+			KEY updated_at_index (updated_at)
+		)  ENGINE=INNODB COMMENT 'tasks';`,
+		`CREATE TABLE IF NOT EXISTS _tasks_gho (
+			id INT AUTO_INCREMENT,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS archived_tasks (
+			id INT AUTO_INCREMENT,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+	}
+
+	s1Name, s2Name := TestCluster.LoadSchemas(t, sql1, sql2)
+	from := NewServer1Schema(s1Name)
+	to := NewServer2Schema(s2Name)
+
+	diff := NewDiff(DSN1, DSN2, from, to, false, "")
+	Equal(t, 7, len(diff.Changes()))
+
+	diff = NewDiff(DSN1, DSN2, from, to, false, "")
+	diff.Filter = NewFilter()
+	Nil(t, diff.Filter.Add(ExcludeTables, "^_.*_gho$", "^archived_"))
+	Nil(t, diff.Filter.Add(IgnoreColumns, "*.updated_at"))
+	Nil(t, diff.Filter.Add(IgnoreChanges, "comments"))
+	changes := diff.Changes()
+	Equal(t, 1, len(changes))
+	Equal(t, ChangeAddIndex, changes[0].Kind)
+
+	diff = NewDiff(DSN1, DSN2, from, to, false, "")
+	diff.Filter = NewFilter()
+	Nil(t, diff.Filter.Add(IncludeTables, "^archived_"))
+	changes = diff.Changes()
+	Equal(t, 1, len(changes))
+	Equal(t, "archived_tasks", changes[0].Table)
+
+	diff = NewDiff(DSN1, DSN2, from, to, false, "")
+	diff.Filter = NewFilter()
+	Nil(t, diff.Filter.Add(MinSeverity, "additive"))
+	changes = diff.Changes()
+	Equal(t, 5, len(changes))
+	for _, c := range changes {
+		Equal(t, SeverityAdditive, c.Severity)
+	}
+	NotNil(t, diff.Filter.Add(MinSeverity, "urgent"))
+
+	sqlFmt, _ := NewFormatter("sql")
+	diff = NewDiff(DSN1, DSN2, from, to, false, "")
+	diff.Filter = NewFilter()
+	Nil(t, diff.Filter.Add(ExcludeTables, "^_.*_gho$"))
+	NotContains(t, sqlFmt.Format(diff), "_tasks_gho")
+}
+
+func TestDiff_FilterRenames(t *testing.T) {
+	table := func(name string, columns ...string) *tengo.Table {
+		table := &tengo.Table{Name: name, Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true}
+		for _, c := range columns {
+			table.Columns = append(table.Columns, &tengo.Column{Name: c, TypeInDB: "varchar(255)", Nullable: true, Default: tengo.ColumnDefaultNull, CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorMySQL80)
+		return table
+	}
+	diff := func(rule string, from, to *tengo.Table) *Diff {
+		filter := NewFilter()
+		Nil(t, filter.Load(strings.NewReader(rule)))
+		return &Diff{
+			DSN1:     ParseDSN(DSN1),
+			DSN2:     ParseDSN(DSN2),
+			From:     &tengo.Schema{Name: "shop", Tables: []*tengo.Table{from}},
+			To:       &tengo.Schema{Name: "shop", Tables: []*tengo.Table{to}},
+			Flavor1:  tengo.FlavorMySQL80,
+			Flavor2:  tengo.FlavorMySQL80,
+			Filter:   filter,
+			objects1: &Objects{},
+			objects2: &Objects{},
+		}
+	}
+	sqlFmt, _ := NewFormatter("sql")
+
+	// ignoring the kind of either half of a rename doesn't turn the other
+	// half into a drop
+	d := diff("ignore-changes create_table", table("owners", "email"), table("users", "email"))
+	Equal(t, []string{"rename_table:owners"}, changeIDs(d.Changes()))
+	Equal(t, "-- Severity: breaking\nRENAME TABLE `owners` TO `users`;\n", sqlFmt.Format(d))
+
+	d = diff("ignore-changes add_column", table("tasks", "id", "title"), table("tasks", "id", "name"))
+	Equal(t, []string{"rename_column:tasks.title"}, changeIDs(d.Changes()))
+	NotContains(t, sqlFmt.Format(d), "DROP COLUMN")
+
+	// nor does leaving out the changes below a severity, like the additive
+	// add of a renamed column
+	d = diff("min-severity risky", table("tasks", "id", "title"), table("tasks", "id", "name"))
+	Equal(t, []string{"rename_column:tasks.title"}, changeIDs(d.Changes()))
+	NotContains(t, sqlFmt.Format(d), "DROP COLUMN")
+
+	// renames can be ignored once detected
+	d = diff("ignore-changes rename_column", table("tasks", "id", "title"), table("tasks", "id", "name"))
+	Empty(t, d.Changes())
+}
+
+// changeIDs returns the ids of the changes
+func changeIDs(cs []Change) []string {
+	var res []string
+	for _, c := range cs {
+		res = append(res, c.id())
+	}
+	return res
+}
@@ -0,0 +1,112 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"fmt"
+
+	"github.com/skeema/tengo"
+)
+
+// AlterForeignKey is a tengo.TableAlterClause representing a foreign key
+// that differs between the tables, reported as a single change rather than
+// a tengo.DropForeignKey and a tengo.AddForeignKey.
+//
+// MySQL cannot drop and add a foreign key with the same name in a single
+// statement, so like tengo does, the foreign key is dropped by an ALTER
+// TABLE statement, and added back by a later one, whose clause has Added set.
+// Only the former is reported.
+type AlterForeignKey struct {
+	From, To *tengo.ForeignKey
+	Added    bool
+}
+
+// Clause returns the clause of an ALTER TABLE statement dropping the foreign
+// key, or adding it back if Added is set.
+func (afk AlterForeignKey) Clause(mods tengo.StatementModifiers) string {
+	if afk.Added {
+		return tengo.AddForeignKey{ForeignKey: afk.To}.Clause(mods)
+	}
+	return tengo.DropForeignKey{ForeignKey: afk.From}.Clause(mods)
+}
+
+// differences returns the names of the attributes of the foreign keys that
+// differ
+func (afk AlterForeignKey) differences() []string {
+	var res []string
+	from, to := afk.From, afk.To
+	columns := len(from.Columns) == len(to.Columns)
+	for i := 0; columns && i < len(from.Columns); i++ {
+		columns = from.Columns[i].Name == to.Columns[i].Name
+	}
+	if !columns {
+		res = append(res, "columns")
+	}
+	if from.ReferencedSchemaName != to.ReferencedSchemaName || from.ReferencedTableName != to.ReferencedTableName {
+		res = append(res, "referenced table")
+	} else if fmt.Sprint(from.ReferencedColumnNames) != fmt.Sprint(to.ReferencedColumnNames) {
+		res = append(res, "referenced columns")
+	}
+	if from.DeleteRule != to.DeleteRule {
+		res = append(res, "on delete")
+	}
+	if from.UpdateRule != to.UpdateRule {
+		res = append(res, "on update")
+	}
+	return res
+}
+
+// detectForeignKeyChanges pairs the tengo.DropForeignKey and
+// tengo.AddForeignKey clauses of the same foreign key of a table, which tengo
+// puts in different table diffs, replacing them with AlterForeignKey clauses.
+func detectForeignKeyChanges(ods []tengo.ObjectDiff) {
+	type key struct{ table, name string }
+	drops := map[key]*tengo.ForeignKey{}
+	adds := map[key]*tengo.ForeignKey{}
+	for _, od := range ods {
+		td, ok := od.(*TableDiff)
+		if !ok || td.DiffType() != tengo.DiffTypeAlter {
+			continue
+		}
+		for _, c := range td.AlterClauses() {
+			switch c := c.(type) {
+			case tengo.DropForeignKey:
+				drops[key{td.From.Name, c.ForeignKey.Name}] = c.ForeignKey
+			case tengo.AddForeignKey:
+				adds[key{td.From.Name, c.ForeignKey.Name}] = c.ForeignKey
+			}
+		}
+	}
+
+	for _, od := range ods {
+		td, ok := od.(*TableDiff)
+		if !ok || td.DiffType() != tengo.DiffTypeAlter {
+			continue
+		}
+		clauses := td.AlterClauses()
+		for i, c := range clauses {
+			switch c := c.(type) {
+			case tengo.DropForeignKey:
+				if to, ok := adds[key{td.From.Name, c.ForeignKey.Name}]; ok {
+					clauses[i] = AlterForeignKey{From: c.ForeignKey, To: to}
+				}
+			case tengo.AddForeignKey:
+				if from, ok := drops[key{td.From.Name, c.ForeignKey.Name}]; ok {
+					clauses[i] = AlterForeignKey{From: from, To: c.ForeignKey, Added: true}
+				}
+			}
+		}
+		td.SetAlterClauses(clauses)
+	}
+}
@@ -21,8 +21,17 @@ import (
 // AvailableFormatters is a map with the available
 // formatters indexed by their name.
 var AvailableFormatters map[string]Formatter = map[string]Formatter{
-	"sql":     &SQLFormatter{},
-	"compact": &CompactFormatter{},
+	"sql":       &SQLFormatter{},
+	"compact":   &CompactFormatter{},
+	"json":      &JSONFormatter{},
+	"junit":     &JUnitFormatter{},
+	"markdown":  &MarkdownFormatter{},
+	"html":      &HTMLFormatter{},
+	"template":  &TemplateFormatter{},
+	"rails":     &RailsFormatter{},
+	"liquibase": &LiquibaseFormatter{},
+	GhOst:       &OnlineSchemaChangeFormatter{Tool: GhOst, Threshold: DefaultOnlineThreshold},
+	PtOSC:       &OnlineSchemaChangeFormatter{Tool: PtOSC, Threshold: DefaultOnlineThreshold},
 }
 
 // existingFormatters returns a slice of the existing formatters
@@ -41,6 +50,14 @@ type Formatter interface {
 	Format(diff *Diff) interface{}
 }
 
+// MigrationsFormatter is implemented by the formatters reporting the
+// migrations missing in each server (see MigrationsDiff), which are only
+// diffed for them.
+type MigrationsFormatter interface {
+	Formatter
+	FormatsMigrations()
+}
+
 // NewFormatter creates a new value of a specific formatter based
 // on the given difftype.
 // Allowed difftypes are:
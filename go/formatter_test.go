@@ -16,6 +16,7 @@ package mydiff
 import (
 	"testing"
 
+	"github.com/skeema/tengo"
 	. "github.com/stretchr/testify/assert"
 )
 
@@ -29,3 +30,35 @@ func TestNewFormatter_NonExisting(t *testing.T) {
 	Error(t, err, "fasfasdf")
 	Nil(t, formatter)
 }
+
+func TestMigrationsFormatter(t *testing.T) {
+	formatsMigrations := map[string]bool{
+		"compact":   true,
+		"json":      true,
+		"junit":     true,
+		"markdown":  true,
+		"html":      true,
+		"template":  true,
+		"rails":     true,
+		"liquibase": true,
+	}
+	for name, formatter := range AvailableFormatters {
+		_, ok := formatter.(MigrationsFormatter)
+		Equal(t, formatsMigrations[name], ok, name)
+	}
+}
+
+func TestGroupChanges(t *testing.T) {
+	dropColumn := Change{Kind: ChangeDropColumn, Object: tengo.ObjectTypeTable, Table: "tasks", Name: "owner_id", Severity: SeverityBreaking}
+	createView := Change{Kind: ChangeCreateView, Object: ObjectTypeView, Table: "open_tasks", Severity: SeverityAdditive}
+	addColumn := Change{Kind: ChangeAddColumn, Object: tengo.ObjectTypeTable, Table: "tasks", Name: "due_at", Severity: SeverityAdditive}
+	createTrigger := Change{Kind: ChangeCreateTrigger, Object: ObjectTypeTrigger, Table: "tasks", Name: "tasks_bi", Severity: SeverityRisky}
+
+	groups := groupChanges([]Change{dropColumn, createView, addColumn, createTrigger})
+	Equal(t, []objectChanges{
+		{Name: "table tasks", Severity: SeverityBreaking, Changes: []Change{dropColumn, addColumn, createTrigger}},
+		{Name: "view open_tasks", Severity: SeverityAdditive, Changes: []Change{createView}},
+	}, groups.Objects)
+	Equal(t, []ChangeKind{ChangeDropColumn, ChangeCreateView, ChangeAddColumn, ChangeCreateTrigger}, groups.Kinds)
+	Equal(t, 1, groups.Counts[ChangeAddColumn])
+}
@@ -68,7 +68,14 @@ func (m *MySQLCluster) LoadSchemas(t *testing.T, sql1, sql2 []string) (schema1,
 	ts := time.Now().UnixNano()
 	schema1 = fmt.Sprintf("schema1_%d", ts)
 	schema2 = fmt.Sprintf("schema2_%d", ts)
+	m.LoadSchemasAs(t, schema1, schema2, sql1, sql2)
+	return
+}
 
+// LoadSchemasAs will load sql1 and sql2 into the two servers under the given
+// schema names.
+func (m *MySQLCluster) LoadSchemasAs(t *testing.T, schema1, schema2 string, sql1, sql2 []string) {
+	t.Helper()
 	_, err := m.s1.Exec("CREATE DATABASE " + schema1)
 	if err != nil {
 		log.Panic(err)
@@ -100,8 +107,6 @@ func (m *MySQLCluster) LoadSchemas(t *testing.T, sql1, sql2 []string) (schema1,
 			log.Panic(err)
 		}
 	}
-
-	return
 }
 
 // NewServer1Schema returns a tengo.Schema value denoted by the given name in the server1
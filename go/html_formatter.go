@@ -0,0 +1,270 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// HTMLFormatter formats a diff as a standalone HTML document, with no
+// external assets, so it can be archived and browsed.
+//
+// The document lists the objects that differ, and for each of them its
+// changes and its definitions in both schemas side by side, highlighting the
+// lines that differ. Changes can be shown or hidden by kind.
+type HTMLFormatter struct{}
+
+type htmlReport struct {
+	From, To   string
+	Summary    string
+	Kinds      []htmlKind
+	Objects    []*htmlObject
+	Migrations *MigrationsDiff
+	Warnings   []string
+}
+
+type htmlKind struct {
+	Kind  ChangeKind
+	Count int
+}
+
+type htmlObject struct {
+	ID          string
+	Name        string
+	Severity    Severity
+	Changes     []Change
+	Definitions []htmlDefinitions
+}
+
+// htmlDefinitions are the lines of the definitions of an object in each
+// schema, shown side by side.
+type htmlDefinitions struct {
+	Title    string
+	From, To []htmlLine
+}
+
+type htmlLine struct {
+	Text    string
+	Differs bool
+}
+
+// FormatsMigrations (see MigrationsFormatter)
+func (f *HTMLFormatter) FormatsMigrations() {}
+
+// Format returns a string with the diff as an HTML document
+func (f *HTMLFormatter) Format(diff *Diff) interface{} {
+	ods := diff.Compute()
+	cs := changes(ods)
+	report := htmlReport{
+		From:     fmt.Sprintf("%s.%s", diff.From.Name, diff.DSN1.Addr),
+		To:       fmt.Sprintf("%s.%s", diff.To.Name, diff.DSN2.Addr),
+		Summary:  Summary(cs),
+		Warnings: diff.Warnings(),
+	}
+
+	groups := groupChanges(cs)
+	for _, k := range groups.Kinds {
+		report.Kinds = append(report.Kinds, htmlKind{Kind: k, Count: groups.Counts[k]})
+	}
+	sort.Slice(report.Kinds, func(i, j int) bool { return report.Kinds[i].Kind < report.Kinds[j].Kind })
+	for i, o := range groups.Objects {
+		object := &htmlObject{ID: fmt.Sprintf("object-%d", i+1), Name: o.Name, Severity: o.Severity, Changes: o.Changes}
+		report.Objects = append(report.Objects, object)
+		for j, c := range o.Changes {
+			if j == 0 && (c.Object == tengo.ObjectTypeTable || c.Object == ObjectTypePartition || c.Object == ObjectTypeTrigger) {
+				object.Definitions = append(object.Definitions, f.tableDefinitions(c, diff))
+			}
+			switch c.Object {
+			case tengo.ObjectTypeTable, ObjectTypePartition:
+			case ObjectTypeTrigger:
+				object.Definitions = append(object.Definitions, f.definitions("trigger "+c.Name, c.From, c.To))
+			default:
+				object.Definitions = append(object.Definitions, f.definitions(o.Name, c.From, c.To))
+			}
+		}
+	}
+
+	for _, od := range ods {
+		if md, ok := od.(*MigrationsDiff); ok {
+			report.Migrations = md
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, report); err != nil {
+		log.Errorf("Error rendering the diff as HTML: %s", err)
+		return ""
+	}
+	return buf.String()
+}
+
+// tableDefinitions returns the definitions of the table a change belongs to,
+// as given by SHOW CREATE TABLE in each schema.
+func (f *HTMLFormatter) tableDefinitions(c Change, context *Diff) htmlDefinitions {
+	var from, to string
+	if t := context.From.Table(c.Table); t != nil {
+		from = t.CreateStatement
+	}
+	toName := c.Table
+	if c.Kind == ChangeRenameTable {
+		toName = c.RenamedTo
+	}
+	if t := context.To.Table(toName); t != nil {
+		to = t.CreateStatement
+	}
+	return f.definitions("table "+c.Table, from, to)
+}
+
+// definitions returns the lines of two definitions of an object, marking
+// those that are not part of their longest common sequence of lines.
+func (f *HTMLFormatter) definitions(title, from, to string) htmlDefinitions {
+	var fromLines, toLines []string
+	if from != "" {
+		fromLines = strings.Split(from, "\n")
+	}
+	if to != "" {
+		toLines = strings.Split(to, "\n")
+	}
+
+	// lcs[i][j] is the length of the longest common sequence of
+	// fromLines[i:] and toLines[j:]
+	lcs := make([][]int, len(fromLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(toLines)+1)
+	}
+	for i := len(fromLines) - 1; i >= 0; i-- {
+		for j := len(toLines) - 1; j >= 0; j-- {
+			if fromLines[i] == toLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	res := htmlDefinitions{Title: title}
+	for _, l := range fromLines {
+		res.From = append(res.From, htmlLine{Text: l, Differs: true})
+	}
+	for _, l := range toLines {
+		res.To = append(res.To, htmlLine{Text: l, Differs: true})
+	}
+	for i, j := 0, 0; i < len(fromLines) && j < len(toLines); {
+		switch {
+		case fromLines[i] == toLines[j]:
+			res.From[i].Differs, res.To[j].Differs = false, false
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return res
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>mydiff: {{.From}} vs {{.To}}</title>
+<style>
+body { font-family: sans-serif; margin: 0; display: flex; }
+nav { width: 18em; padding: 1em; border-right: 1px solid #ddd; height: 100vh; overflow: auto; position: sticky; top: 0; box-sizing: border-box; }
+nav ul { list-style: none; padding: 0; }
+main { flex: 1; padding: 1em 2em; min-width: 0; }
+label { display: block; }
+.severity { font-size: 0.8em; padding: 0 0.4em; border-radius: 0.3em; background: #eee; }
+.severity-breaking { background: #f8d0d0; }
+.severity-risky { background: #fbe6c2; }
+.severity-additive { background: #d5f0d5; }
+.definitions { display: flex; gap: 1em; }
+.definitions > div { flex: 1; min-width: 0; }
+pre { background: #f6f8fa; padding: 0.5em; overflow: auto; }
+pre span { display: block; }
+.from .differs { background: #ffdce0; }
+.to .differs { background: #cdffd8; }
+</style>
+</head>
+<body>
+<nav>
+<h3>Kinds of changes</h3>
+{{range .Kinds}}<label><input type="checkbox" data-kind="{{.Kind}}" checked> {{.Kind}} ({{.Count}})</label>
+{{end}}
+<h3>Objects</h3>
+<ul>
+{{range .Objects}}<li><a href="#{{.ID}}">{{.Name}}</a> <span class="severity severity-{{.Severity}}">{{.Severity}}</span></li>
+{{end}}{{if .Migrations}}<li><a href="#migrations">migrations</a></li>
+{{end}}</ul>
+</nav>
+<main>
+<h1>{{.From}} vs {{.To}}</h1>
+<p>{{.Summary}}</p>
+{{if .Warnings}}<h2>Warnings</h2>
+<ul>
+{{range .Warnings}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}{{range .Objects}}<section class="object" id="{{.ID}}">
+<h2>{{.Name}}</h2>
+<ul>
+{{range .Changes}}<li class="change" data-kind="{{.Kind}}">{{.Kind}}{{if .Name}} <code>{{.Name}}</code>{{end}}{{if .RenamedTo}} to <code>{{.RenamedTo}}</code>{{end}} <span class="severity severity-{{.Severity}}">{{.Severity}}</span></li>
+{{end}}</ul>
+{{range .Definitions}}<h3>{{.Title}}</h3>
+<div class="definitions">
+<div class="from"><h4>{{$.From}}</h4><pre>{{range .From}}<span{{if .Differs}} class="differs"{{end}}>{{.Text}}</span>{{end}}</pre></div>
+<div class="to"><h4>{{$.To}}</h4><pre>{{range .To}}<span{{if .Differs}} class="differs"{{end}}>{{.Text}}</span>{{end}}</pre></div>
+</div>
+{{end}}</section>
+{{end}}{{with .Migrations}}<section id="migrations">
+<h2>Migrations</h2>
+<p>Some migrations in <code>{{.Table}}.{{.Column}}</code> are missing.</p>
+{{if .Missing1}}<h3>Missing in {{.Context.DSN1.Addr}}</h3>
+<ul>
+{{range .Missing1}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}{{if .Missing2}}<h3>Missing in {{.Context.DSN2.Addr}}</h3>
+<ul>
+{{range .Missing2}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}</section>
+{{end}}</main>
+<script>
+document.querySelectorAll("input[data-kind]").forEach(function(box) {
+  box.addEventListener("change", function() {
+    document.querySelectorAll("li.change").forEach(function(li) {
+      if (li.dataset.kind === box.dataset.kind) {
+        li.hidden = !box.checked;
+      }
+    });
+    document.querySelectorAll("section.object").forEach(function(section) {
+      var visible = section.querySelector("li.change:not([hidden])") !== null;
+      section.hidden = !visible;
+      document.querySelector("nav a[href='#" + section.id + "']").parentNode.hidden = !visible;
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,72 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"testing"
+
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestHTMLFormatter_Format(t *testing.T) {
+	schema1 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			owner_id INT,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) NOT NULL,
+			UNIQUE KEY version_key(version)
+		)  ENGINE=INNODB;`,
+		`INSERT INTO schema_migrations values (20190815193300);`,
+	}
+
+	schema2 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) NOT NULL,
+			UNIQUE KEY version_key(version)
+		)  ENGINE=INNODB;`,
+		`INSERT INTO schema_migrations values (20190815193300);`,
+		`INSERT INTO schema_migrations values (20190816000000);`,
+	}
+
+	htmlFmt, _ := NewFormatter("html")
+	out := RunDiff(t, schema1, schema2, htmlFmt).(string)
+
+	Contains(t, out, `<input type="checkbox" data-kind="drop_column" checked> drop_column (1)`)
+	Contains(t, out, `<li><a href="#object-1">table tasks</a> <span class="severity severity-breaking">breaking</span></li>`)
+	Contains(t, out, `<li class="change" data-kind="drop_column">drop_column <code>owner_id</code>`)
+	Contains(t, out, "<span class=\"differs\">  `owner_id` int(11) DEFAULT NULL,</span>")
+	Contains(t, out, "<span>  `title` varchar(255) NOT NULL,</span>")
+	Contains(t, out, "<li>20190816000000</li>")
+	NotContains(t, out, "<link")
+}
+
+func TestHTMLFormatter_Definitions(t *testing.T) {
+	f := &HTMLFormatter{}
+	defs := f.definitions("view v", "a\nb\nc\nd", "a\nc\nx\nd")
+	Equal(t, []htmlLine{{"a", false}, {"b", true}, {"c", false}, {"d", false}}, defs.From)
+	Equal(t, []htmlLine{{"a", false}, {"c", false}, {"x", true}, {"d", false}}, defs.To)
+
+	defs = f.definitions("view v", "", "a")
+	Empty(t, defs.From)
+	Equal(t, []htmlLine{{"a", true}}, defs.To)
+}
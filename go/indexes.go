@@ -0,0 +1,335 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// indexLine matches the definition of an index in the output of SHOW CREATE
+// TABLE, capturing its kind, and the rest of the definition after its name.
+var indexLine = regexp.MustCompile("(?m)^  (PRIMARY KEY|UNIQUE KEY|FULLTEXT KEY|SPATIAL KEY|KEY) ((?:`(?:[^`]|``)+` )?)(\\(.*)$")
+
+// indexParser matches the parser of a FULLTEXT index, which MySQL follows by
+// a space
+var indexParser = regexp.MustCompile(" /\\*!50100 WITH PARSER `([^`]+)` \\*/ ?")
+
+// indexInvisible matches the visibility of an invisible index, in MySQL 8
+// and MariaDB respectively.
+var indexInvisible = regexp.MustCompile(` /\*!80000 INVISIBLE \*/| IGNORED\b`)
+
+// IndexAttributes are the attributes of an index that tengo doesn't load.
+// Tables with indexes using any of them are unsupported by tengo, so mydiff
+// lifts them from their CREATE TABLE statement, see liftFeatures.
+type IndexAttributes struct {
+	Type      string // FULLTEXT or SPATIAL, empty for regular indexes
+	Parser    string // the parser of a FULLTEXT index, if not the built-in one
+	Desc      []bool // whether each part of the index is in descending order
+	Invisible bool
+}
+
+// liftIndexAttributes returns the given CREATE TABLE statement with its
+// indexes defined as tengo would, along with the attributes they had that
+// tengo doesn't load, by index name.
+func liftIndexAttributes(createStatement string) (string, map[string]IndexAttributes) {
+	attributes := map[string]IndexAttributes{}
+	stmt := indexLine.ReplaceAllStringFunc(createStatement, func(line string) string {
+		m := indexLine.FindStringSubmatch(line)
+		kind, name, rest := m[1], m[2], m[3]
+		end := closingParen(rest)
+		if end < 0 {
+			return line
+		}
+		parts, options := splitTopLevel(rest[1:end]), rest[end+1:]
+
+		var attrs IndexAttributes
+		switch kind {
+		case "FULLTEXT KEY", "SPATIAL KEY":
+			attrs.Type = strings.TrimSuffix(kind, " KEY")
+			kind = "KEY"
+		}
+		for i, part := range parts {
+			if strings.HasSuffix(part, " DESC") {
+				if attrs.Desc == nil {
+					attrs.Desc = make([]bool, len(parts))
+				}
+				attrs.Desc[i] = true
+				parts[i] = strings.TrimSuffix(part, " DESC")
+			}
+		}
+		if p := indexParser.FindStringSubmatch(options); p != nil {
+			attrs.Parser = p[1]
+			options = indexParser.ReplaceAllString(options, "")
+		}
+		if indexInvisible.MatchString(options) {
+			attrs.Invisible = true
+			options = indexInvisible.ReplaceAllString(options, "")
+		}
+		if reflect.DeepEqual(attrs, IndexAttributes{}) {
+			return line
+		}
+		indexName := "PRIMARY"
+		if name != "" {
+			indexName = strings.Replace(strings.Trim(name, "` "), "``", "`", -1)
+		}
+		attributes[indexName] = attrs
+		return fmt.Sprintf("  %s %s(%s)%s", kind, name, strings.Join(parts, ","), options)
+	})
+	return stmt, attributes
+}
+
+// closingParen returns the position of the parenthesis closing the one s
+// starts with, or -1 if there's none.
+func closingParen(s string) int {
+	var quote rune
+	depth := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Index is a tengo.Index along with the attributes that tengo doesn't load
+type Index struct {
+	*tengo.Index
+	IndexAttributes
+}
+
+// Kind returns the kind of the index as in its definition: PRIMARY KEY,
+// UNIQUE KEY, FULLTEXT KEY, SPATIAL KEY or KEY.
+func (idx *Index) Kind() string {
+	switch {
+	case idx.PrimaryKey:
+		return "PRIMARY KEY"
+	case idx.Unique:
+		return "UNIQUE KEY"
+	case idx.Type != "":
+		return idx.Type + " KEY"
+	}
+	return "KEY"
+}
+
+// parts returns the definitions of the parts of the index: the columns,
+// quoted by the given function, with their prefix length and order, if any.
+func (idx *Index) parts(quote func(string) string) []string {
+	parts := make([]string, len(idx.Columns))
+	for i, c := range idx.Columns {
+		parts[i] = quote(c.Name)
+		if idx.SubParts[i] > 0 {
+			parts[i] += fmt.Sprintf("(%d)", idx.SubParts[i])
+		}
+		if i < len(idx.Desc) && idx.Desc[i] {
+			parts[i] += " DESC"
+		}
+	}
+	return parts
+}
+
+// Definition returns the definition of the index as in a CREATE TABLE
+// statement, like tengo.Index.Definition does.
+func (idx *Index) Definition(flavor tengo.Flavor) string {
+	def := idx.Kind()
+	if !idx.PrimaryKey {
+		def += " " + tengo.EscapeIdentifier(idx.Name)
+	}
+	def += fmt.Sprintf(" (%s)", strings.Join(idx.parts(tengo.EscapeIdentifier), ","))
+	if idx.Comment != "" {
+		def += fmt.Sprintf(" COMMENT '%s'", tengo.EscapeValueForCreateTable(idx.Comment))
+	}
+	if idx.Parser != "" {
+		def += fmt.Sprintf(" /*!50100 WITH PARSER %s */", tengo.EscapeIdentifier(idx.Parser))
+	}
+	if idx.Invisible {
+		def += visibility(flavor, false)
+	}
+	return def
+}
+
+// visibility returns the visibility clause of an index for the given flavor
+func visibility(flavor tengo.Flavor, visible bool) string {
+	switch {
+	case flavor.Vendor == tengo.VendorMariaDB && visible:
+		return " NOT IGNORED"
+	case flavor.Vendor == tengo.VendorMariaDB:
+		return " IGNORED"
+	case visible:
+		return " VISIBLE"
+	}
+	return " /*!80000 INVISIBLE */"
+}
+
+// differences returns the names of the attributes of the indexes that differ
+func (idx *Index) differences(other *Index) []string {
+	var res []string
+	if idx.Kind() != other.Kind() {
+		res = append(res, "type")
+	}
+	columns := len(idx.Columns) == len(other.Columns)
+	for i := 0; columns && i < len(idx.Columns); i++ {
+		columns = idx.Columns[i].Name == other.Columns[i].Name
+	}
+	if !columns {
+		res = append(res, "columns")
+	} else {
+		if !reflect.DeepEqual(idx.SubParts, other.SubParts) {
+			res = append(res, "prefix length")
+		}
+		if !reflect.DeepEqual(idx.order(), other.order()) {
+			res = append(res, "order")
+		}
+	}
+	if idx.Parser != other.Parser {
+		res = append(res, "parser")
+	}
+	if idx.Comment != other.Comment {
+		res = append(res, "comment")
+	}
+	if idx.Invisible != other.Invisible {
+		res = append(res, "visibility")
+	}
+	return res
+}
+
+// order returns whether each part of the index is in descending order
+func (idx *Index) order() []bool {
+	order := make([]bool, len(idx.Columns))
+	copy(order, idx.Desc)
+	return order
+}
+
+// AlterIndex is a tengo.TableAlterClause representing an index that differs
+// between the tables, accounting for the attributes of indexes that tengo
+// doesn't load. The index is added if From is nil, dropped if To is nil,
+// and otherwise modified: reported as a single change rather than a
+// tengo.DropIndex and a tengo.AddIndex.
+type AlterIndex struct {
+	From, To *Index
+}
+
+// Clause returns the clauses of an ALTER TABLE statement that drop the index,
+// add it, or both. Indexes only differing in visibility are altered in place,
+// and the ones not differing at all, which are dropped and added again just
+// to be reordered, are only altered with StrictIndexOrder.
+func (ai AlterIndex) Clause(mods tengo.StatementModifiers) string {
+	if ai.From == nil {
+		return fmt.Sprintf("ADD %s", ai.To.Definition(mods.Flavor))
+	}
+	drop := tengo.DropIndex{Index: ai.From.Index}.Clause(mods)
+	if ai.To == nil {
+		return drop
+	}
+	differences := ai.From.differences(ai.To)
+	switch {
+	case len(differences) == 0 && !mods.StrictIndexOrder:
+		return ""
+	case len(differences) == 1 && differences[0] == "visibility":
+		return fmt.Sprintf("ALTER INDEX %s%s", tengo.EscapeIdentifier(ai.To.Name), visibility(mods.Flavor, !ai.To.Invisible))
+	}
+	return fmt.Sprintf("%s, ADD %s", drop, ai.To.Definition(mods.Flavor))
+}
+
+// alterIndexes replaces the tengo.DropIndex and tengo.AddIndex clauses of the
+// same index with a single AlterIndex clause, and the ones of indexes with
+// attributes tengo doesn't load, given by the lifted table if any, so their
+// statements are correct. AlterIndex clauses are also added for indexes
+// only differing in these attributes.
+func alterIndexes(td *TableDiff, lt *liftedTable) {
+	if td.AlterClauses() == nil {
+		return
+	}
+	var fromAttributes, toAttributes map[string]IndexAttributes
+	if lt != nil {
+		fromAttributes, toAttributes = lt.fromFeatures.indexes, lt.toFeatures.indexes
+	}
+	clauses := td.AlterClauses()
+	drops := map[string]*tengo.Index{}
+	adds := map[string]*tengo.Index{}
+	for _, c := range clauses {
+		switch c := c.(type) {
+		case tengo.DropIndex:
+			drops[c.Index.Name] = c.Index
+		case tengo.AddIndex:
+			adds[c.Index.Name] = c.Index
+		}
+	}
+
+	res := make([]tengo.TableAlterClause, 0, len(clauses))
+	for _, c := range clauses {
+		switch c := c.(type) {
+		case tengo.DropIndex:
+			if _, ok := adds[c.Index.Name]; ok {
+				continue
+			}
+			if attrs, ok := fromAttributes[c.Index.Name]; ok {
+				res = append(res, AlterIndex{From: &Index{c.Index, attrs}})
+				continue
+			}
+		case tengo.AddIndex:
+			from, ok := drops[c.Index.Name]
+			if ok {
+				res = append(res, AlterIndex{From: &Index{from, fromAttributes[from.Name]}, To: &Index{c.Index, toAttributes[c.Index.Name]}})
+				continue
+			}
+			if attrs, ok := toAttributes[c.Index.Name]; ok {
+				res = append(res, AlterIndex{To: &Index{c.Index, attrs}})
+				continue
+			}
+		}
+		res = append(res, c)
+	}
+
+	fromIndexes := map[string]*tengo.Index{}
+	for _, idx := range tableIndexes(td.From) {
+		fromIndexes[idx.Name] = idx
+	}
+	for _, idx := range tableIndexes(td.To) {
+		from, ok := fromIndexes[idx.Name]
+		if !ok || drops[idx.Name] != nil || adds[idx.Name] != nil {
+			continue
+		}
+		fromIndex, toIndex := &Index{from, fromAttributes[from.Name]}, &Index{idx, toAttributes[idx.Name]}
+		if len(fromIndex.differences(toIndex)) > 0 {
+			res = append(res, AlterIndex{From: fromIndex, To: toIndex})
+		}
+	}
+	td.SetAlterClauses(res)
+}
+
+// tableIndexes returns the primary key and the secondary indexes of a table
+func tableIndexes(t *tengo.Table) []*tengo.Index {
+	var res []*tengo.Index
+	if t.PrimaryKey != nil {
+		res = append(res, t.PrimaryKey)
+	}
+	return append(res, t.SecondaryIndexes...)
+}
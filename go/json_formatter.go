@@ -0,0 +1,110 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// JSONFormatter formats a diff as a JSON document, so it can be
+// consumed by other tools.
+type JSONFormatter struct{}
+
+type jsonSchema struct {
+	Server string `json:"server"`
+	Schema string `json:"schema"`
+}
+
+type jsonMigrations struct {
+	Table    string   `json:"table"`
+	Column   string   `json:"column"`
+	Missing1 []string `json:"missing_in_server1"`
+	Missing2 []string `json:"missing_in_server2"`
+}
+
+type jsonBaselineEntry struct {
+	ID      string `json:"id"`
+	Owner   string `json:"owner"`
+	Reason  string `json:"reason"`
+	Expires string `json:"expires,omitempty"`
+}
+
+type jsonBaseline struct {
+	Accepted []jsonBaselineEntry `json:"accepted"`
+	Expired  []jsonBaselineEntry `json:"expired"`
+	Stale    []jsonBaselineEntry `json:"stale"`
+}
+
+type jsonDiff struct {
+	Server1    jsonSchema      `json:"server1"`
+	Server2    jsonSchema      `json:"server2"`
+	Changes    []Change        `json:"changes"`
+	Migrations *jsonMigrations `json:"migrations,omitempty"`
+	Baseline   *jsonBaseline   `json:"baseline,omitempty"`
+	Warnings   []string        `json:"warnings,omitempty"`
+}
+
+// FormatsMigrations (see MigrationsFormatter)
+func (f *JSONFormatter) FormatsMigrations() {}
+
+// Format returns a string with the diff as an indented JSON document
+func (f *JSONFormatter) Format(diff *Diff) interface{} {
+	ods := diff.Compute()
+	doc := jsonDiff{
+		Server1: jsonSchema{Server: diff.DSN1.Addr, Schema: diff.From.Name},
+		Server2: jsonSchema{Server: diff.DSN2.Addr, Schema: diff.To.Name},
+		Changes: changes(ods),
+	}
+	doc.Warnings = diff.Warnings()
+	for _, od := range ods {
+		if md, ok := od.(*MigrationsDiff); ok {
+			doc.Migrations = &jsonMigrations{
+				Table:    md.Table,
+				Column:   md.Column,
+				Missing1: md.Missing1,
+				Missing2: md.Missing2,
+			}
+		}
+	}
+
+	if len(diff.Baseline) > 0 {
+		report := diff.BaselineReport()
+		doc.Baseline = &jsonBaseline{
+			Accepted: jsonBaselineEntries(report.Accepted),
+			Expired:  jsonBaselineEntries(report.Expired),
+			Stale:    jsonBaselineEntries(report.Stale),
+		}
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Errorf("Error encoding the diff as JSON: %s", err)
+		return ""
+	}
+	return string(out) + "\n"
+}
+
+func jsonBaselineEntries(entries []BaselineEntry) []jsonBaselineEntry {
+	res := []jsonBaselineEntry{}
+	for _, e := range entries {
+		entry := jsonBaselineEntry{ID: e.ID, Owner: e.Owner, Reason: e.Reason}
+		if !e.Expires.IsZero() {
+			entry.Expires = e.Expires.Format(baselineDateFormat)
+		}
+		res = append(res, entry)
+	}
+	return res
+}
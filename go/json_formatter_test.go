@@ -0,0 +1,78 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestJSONFormatter_Format(t *testing.T) {
+	schema1 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) NOT NULL,
+			UNIQUE KEY version_key(version)
+		)  ENGINE=INNODB;`,
+		`INSERT INTO schema_migrations values (20190815193300);`,
+	}
+
+	schema2 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			name VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) NOT NULL,
+			UNIQUE KEY version_key(version)
+		)  ENGINE=INNODB;`,
+		`INSERT INTO schema_migrations values (20190815193300);`,
+		`INSERT INTO schema_migrations values (20190816000000);`,
+	}
+
+	jsonFmt, _ := NewFormatter("json")
+	out := RunDiff(t, schema1, schema2, jsonFmt)
+
+	var doc struct {
+		Server1 struct{ Server, Schema string }
+		Changes []struct {
+			Kind      string
+			Severity  string
+			Table     string
+			Name      string
+			RenamedTo string `json:"renamed_to"`
+			Probable  bool
+		}
+		Migrations struct {
+			Missing1 []string `json:"missing_in_server1"`
+		}
+	}
+	Nil(t, json.Unmarshal([]byte(out.(string)), &doc))
+	Equal(t, "127.0.0.1:33060", doc.Server1.Server)
+	Equal(t, 1, len(doc.Changes))
+	Equal(t, "rename_column", doc.Changes[0].Kind)
+	Equal(t, "breaking", doc.Changes[0].Severity)
+	Equal(t, "tasks", doc.Changes[0].Table)
+	Equal(t, "title", doc.Changes[0].Name)
+	Equal(t, "name", doc.Changes[0].RenamedTo)
+	True(t, doc.Changes[0].Probable)
+	Equal(t, []string{"20190816000000"}, doc.Migrations.Missing1)
+}
@@ -0,0 +1,162 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// JUnitFormatter formats a diff as a JUnit XML report, so CI systems can
+// show the differences along with the results of other tests.
+//
+// The schema and each of its tables not left out by the filter of the diff
+// are a test case, failing with a failure per difference. Other objects, like
+// views or routines, are only test cases if they differ, and so are the
+// migrations.
+type JUnitFormatter struct{}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+	SystemOut string          `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	ClassName string         `xml:"classname,attr"`
+	Name      string         `xml:"name,attr"`
+	Failures  []junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+// FormatsMigrations (see MigrationsFormatter)
+func (f *JUnitFormatter) FormatsMigrations() {}
+
+// Format returns a string with the diff as a JUnit XML document
+func (f *JUnitFormatter) Format(diff *Diff) interface{} {
+	ods := diff.Compute()
+	cases := map[string]*junitTestCase{}
+	testCase := func(name string) *junitTestCase {
+		if _, ok := cases[name]; !ok {
+			cases[name] = &junitTestCase{ClassName: fmt.Sprintf("mydiff.%s", diff.From.Name), Name: name}
+		}
+		return cases[name]
+	}
+
+	testCase(Change{Object: tengo.ObjectTypeDatabase, Table: diff.From.Name}.object())
+	renamed := map[string]bool{}
+	for _, o := range groupChanges(changes(ods)).Objects {
+		tc := testCase(o.Name)
+		for _, c := range o.Changes {
+			tc.Failures = append(tc.Failures, f.failure(c, diff))
+			if c.RenamedTo != "" && c.Name == "" {
+				renamed[c.RenamedTo] = true
+			}
+		}
+	}
+	for _, t := range diff.From.Tables {
+		if diff.Filter.includesTable(t.Name) {
+			testCase(Change{Object: tengo.ObjectTypeTable, Table: t.Name}.object())
+		}
+	}
+	for _, t := range diff.To.Tables {
+		if !renamed[t.Name] && diff.Filter.includesTable(t.Name) {
+			testCase(Change{Object: tengo.ObjectTypeTable, Table: t.Name}.object())
+		}
+	}
+	for _, od := range ods {
+		if md, ok := od.(*MigrationsDiff); ok {
+			tc := testCase(fmt.Sprintf("migrations %s.%s", md.Table, md.Column))
+			tc.Failures = append(tc.Failures, f.migrationsFailure(md))
+		}
+	}
+
+	suite := junitTestSuite{Name: fmt.Sprintf("%s.%s vs %s.%s", diff.From.Name, diff.DSN1.Addr, diff.To.Name, diff.DSN2.Addr)}
+	names := make([]string, 0, len(cases))
+	for name := range cases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		suite.TestCases = append(suite.TestCases, *cases[name])
+		if len(cases[name].Failures) > 0 {
+			suite.Failures++
+		}
+	}
+	suite.Tests = len(suite.TestCases)
+	if warnings := diff.Warnings(); len(warnings) > 0 {
+		suite.SystemOut = "Warnings:\n" + strings.Join(warnings, "\n")
+	}
+
+	doc := junitTestSuites{Name: "mydiff", Tests: suite.Tests, Failures: suite.Failures, Suites: []junitTestSuite{suite}}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Errorf("Error encoding the diff as JUnit XML: %s", err)
+		return ""
+	}
+	return xml.Header + string(out) + "\n"
+}
+
+// failure returns the failure of a change, with its definitions in each
+// schema as the text of the failure.
+func (f *JUnitFormatter) failure(c Change, context *Diff) junitFailure {
+	message := fmt.Sprintf("%s (%s)", c.description(), c.Severity)
+
+	var text bytes.Buffer
+	if c.From != "" {
+		text.WriteString(fmt.Sprintf("In %s.%s:\n%s\n", context.From.Name, context.DSN1.Addr, c.From))
+	}
+	if c.To != "" {
+		text.WriteString(fmt.Sprintf("In %s.%s:\n%s\n", context.To.Name, context.DSN2.Addr, c.To))
+	}
+	return junitFailure{Message: message, Type: string(c.Kind), Text: text.String()}
+}
+
+// migrationsFailure returns the failure listing the migrations missing in
+// each server.
+func (f *JUnitFormatter) migrationsFailure(md *MigrationsDiff) junitFailure {
+	var text bytes.Buffer
+	if len(md.Missing1) > 0 {
+		text.WriteString(fmt.Sprintf("Missing in %s:\n%s\n", md.Context.DSN1.Addr, strings.Join(md.Missing1, "\n")))
+	}
+	if len(md.Missing2) > 0 {
+		text.WriteString(fmt.Sprintf("Missing in %s:\n%s\n", md.Context.DSN2.Addr, strings.Join(md.Missing2, "\n")))
+	}
+	return junitFailure{
+		Message: fmt.Sprintf("%d migrations missing", len(md.Missing1)+len(md.Missing2)),
+		Type:    "migrations",
+		Text:    text.String(),
+	}
+}
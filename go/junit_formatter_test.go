@@ -0,0 +1,133 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestJUnitFormatter_Format(t *testing.T) {
+	schema1 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			owner_id INT,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS owners (
+			id INT AUTO_INCREMENT,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) NOT NULL,
+			UNIQUE KEY version_key(version)
+		)  ENGINE=INNODB;`,
+		`INSERT INTO schema_migrations values (20190815193300);`,
+	}
+
+	schema2 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS owners (
+			id INT AUTO_INCREMENT,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) NOT NULL,
+			UNIQUE KEY version_key(version)
+		)  ENGINE=INNODB;`,
+		`INSERT INTO schema_migrations values (20190815193300);`,
+		`INSERT INTO schema_migrations values (20190816000000);`,
+	}
+
+	junitFmt, _ := NewFormatter("junit")
+	out := RunDiff(t, schema1, schema2, junitFmt)
+
+	var doc struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Suites   []struct {
+			TestCases []struct {
+				Name     string `xml:"name,attr"`
+				Failures []struct {
+					Message string `xml:"message,attr"`
+					Type    string `xml:"type,attr"`
+					Text    string `xml:",chardata"`
+				} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	Nil(t, xml.Unmarshal([]byte(out.(string)), &doc))
+	Equal(t, 5, doc.Tests)
+	Equal(t, 2, doc.Failures)
+
+	cases := doc.Suites[0].TestCases
+	names := []string{}
+	for _, tc := range cases {
+		names = append(names, tc.Name)
+	}
+	Equal(t, []string{"migrations schema_migrations.version", "table owners", "table schema_migrations", "table tasks"}, names[1:])
+	Regexp(t, "^database schema1_\\d+$", names[0])
+
+	Equal(t, 1, len(cases[1].Failures))
+	Equal(t, "1 migrations missing", cases[1].Failures[0].Message)
+	Empty(t, cases[2].Failures)
+	Equal(t, 1, len(cases[4].Failures))
+	Equal(t, "drop_column", cases[4].Failures[0].Type)
+	Equal(t, "drop column owner_id (breaking)", cases[4].Failures[0].Message)
+	Regexp(t, "In schema1_\\d+.127.0.0.1:33060:\n`owner_id` int\\(11\\) DEFAULT NULL\n", cases[4].Failures[0].Text)
+}
+
+func TestJUnitFormatter_Filter(t *testing.T) {
+	table := func(name string, columns ...string) *tengo.Table {
+		table := &tengo.Table{Name: name, Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true}
+		for _, col := range columns {
+			table.Columns = append(table.Columns, &tengo.Column{Name: col, TypeInDB: "int", Nullable: true, Default: tengo.ColumnDefaultNull})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorMySQL80)
+		return table
+	}
+	diff := &Diff{
+		DSN1:     ParseDSN(DSN1),
+		DSN2:     ParseDSN(DSN2),
+		From:     &tengo.Schema{Name: "shop", Tables: []*tengo.Table{table("tasks", "id"), table("_tasks_gho", "id")}},
+		To:       &tengo.Schema{Name: "shop", Tables: []*tengo.Table{table("tasks", "id", "due_at"), table("owners", "id")}},
+		Filter:   NewFilter(),
+		objects1: &Objects{},
+		objects2: &Objects{},
+	}
+	Nil(t, diff.Filter.Add(ExcludeTables, "^_.*_gho$", "^owners$"))
+
+	var doc struct {
+		Suites []struct {
+			TestCases []struct {
+				Name string `xml:"name,attr"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	junitFmt, _ := NewFormatter("junit")
+	Nil(t, xml.Unmarshal([]byte(junitFmt.Format(diff).(string)), &doc))
+	names := []string{}
+	for _, tc := range doc.Suites[0].TestCases {
+		names = append(names, tc.Name)
+	}
+	Equal(t, []string{"database shop", "table tasks"}, names)
+}
@@ -0,0 +1,649 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// Author of the changeSets and schema of the changelog emitted by a
+// LiquibaseFormatter
+const (
+	liquibaseAuthor         = "mydiff"
+	liquibaseNamespace      = "http://www.liquibase.org/xml/ns/dbchangelog"
+	liquibaseSchemaLocation = "http://www.liquibase.org/xml/ns/dbchangelog/dbchangelog-3.8.xsd"
+)
+
+// LiquibaseFormatter formats a diff as a Liquibase XML changelog, which
+// applied to the first schema of the diff turns it into the second one.
+//
+// Every change is a changeSet whose id is the ID of the change (see Change),
+// made of the structured change types of Liquibase: createTable, addColumn,
+// modifyDataType, createIndex, addForeignKeyConstraint... Differences those
+// cannot express, like triggers, views or generated columns, are applied as
+// raw SQL. ChangeSets have a rollback block whenever the change can be undone.
+type LiquibaseFormatter struct{}
+
+// liquibaseChangeSet is a changeSet of the changelog, whose rollback is nil
+// if the change cannot be undone.
+//
+// Stage orders the changeSets altering a table, as railsOperation.Stage does
+// with the commands of a Rails migration.
+type liquibaseChangeSet struct {
+	ID, Comment       string
+	Changes, Rollback []*liquibaseElement
+	Stage             int
+}
+
+// liquibaseElement is an element of the changelog. Attrs are the names and
+// values of its attributes, in order. Text is the content of the elements
+// without children.
+type liquibaseElement struct {
+	Name     string
+	Attrs    []string
+	Children []*liquibaseElement
+	Text     string
+}
+
+// FormatsMigrations (see MigrationsFormatter)
+func (f *LiquibaseFormatter) FormatsMigrations() {}
+
+// Format returns a string with the diff as a Liquibase XML changelog
+func (f *LiquibaseFormatter) Format(diff *Diff) interface{} {
+	var buf bytes.Buffer
+	mods := tengo.StatementModifiers{IgnoreTable: diff.Filter.IgnoreTable(), StrictForeignKeyNaming: diff.StrictForeignKeyNaming, Flavor: diff.Flavor1}
+	buf.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	buf.WriteString(xmlComment(fmt.Sprintf("Differences between `%s` in %s and `%s` in %s", diff.From.Name, diff.DSN1.Addr, diff.To.Name, diff.DSN2.Addr)))
+	for _, w := range diff.Warnings() {
+		buf.WriteString(xmlComment("Warning: " + w))
+	}
+
+	var changeSets []liquibaseChangeSet
+	for _, od := range diff.Compute() {
+		if md, ok := od.(*MigrationsDiff); ok {
+			if len(md.Missing1) > 0 {
+				buf.WriteString(xmlComment(fmt.Sprintf("Migrations missing in %s: %s", md.Context.DSN1.Addr, strings.Join(md.Missing1, ", "))))
+			}
+			if len(md.Missing2) > 0 {
+				buf.WriteString(xmlComment(fmt.Sprintf("Migrations missing in %s: %s", md.Context.DSN2.Addr, strings.Join(md.Missing2, ", "))))
+			}
+			continue
+		}
+		changeSets = append(changeSets, f.changeSets(od, diff.To, mods)...)
+	}
+
+	changelog := liquibaseTag("databaseChangeLog",
+		"xmlns", liquibaseNamespace,
+		"xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance",
+		"xsi:schemaLocation", liquibaseNamespace+" "+liquibaseSchemaLocation)
+	// The ids of changeSets must be unique, and a change may take several
+	// of them, i.e. when a table is renamed and altered.
+	ids := map[string]int{}
+	for _, cs := range changeSets {
+		ids[cs.ID]++
+		if ids[cs.ID] > 1 {
+			cs.ID = fmt.Sprintf("%s#%d", cs.ID, ids[cs.ID])
+		}
+		changelog.Children = append(changelog.Children, cs.element())
+	}
+	changelog.write(&buf, "")
+	return buf.String()
+}
+
+// changeSets returns the changeSets reproducing an object diff. Other
+// objects than tables are created, altered or dropped with raw SQL.
+func (f *LiquibaseFormatter) changeSets(od tengo.ObjectDiff, schema *tengo.Schema, mods tengo.StatementModifiers) []liquibaseChangeSet {
+	switch od := od.(type) {
+	case *TableDiff:
+		return f.tableChangeSets(od, schema, mods)
+	case *RenameTableDiff:
+		rename := liquibaseChangeSet{
+			Changes:  []*liquibaseElement{liquibaseTag("renameTable", "oldTableName", od.From.Name, "newTableName", od.To.Name)},
+			Rollback: []*liquibaseElement{liquibaseTag("renameTable", "oldTableName", od.To.Name, "newTableName", od.From.Name)},
+		}
+		return []liquibaseChangeSet{rename.of(changes([]tengo.ObjectDiff{od})...)}
+	}
+
+	up := statements(od, mods)
+	cs := changes([]tengo.ObjectDiff{od})
+	if len(up) == 0 || len(cs) == 0 {
+		return nil
+	}
+	res := liquibaseChangeSet{Changes: liquibaseSQL(up...)}.of(cs...)
+	if reversed := reversedDiff(od); reversed != nil {
+		if down := statements(reversed, mods); len(down) > 0 {
+			res.Rollback = liquibaseSQL(down...)
+		}
+	}
+	return []liquibaseChangeSet{res}
+}
+
+// tableChangeSets returns the changeSets creating, dropping or altering a
+// table, given the schema tables are created in.
+func (f *LiquibaseFormatter) tableChangeSets(td *TableDiff, schema *tengo.Schema, mods tengo.StatementModifiers) []liquibaseChangeSet {
+	name := td.ObjectKey().Name
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(name) {
+		return nil
+	}
+	switch td.DiffType() {
+	case tengo.DiffTypeCreate:
+		stmt, _ := td.Statement(mods)
+		res := liquibaseChangeSet{
+			Changes:  liquibaseSQL(stmt),
+			Rollback: []*liquibaseElement{liquibaseTag("dropTable", "tableName", name)},
+		}
+		if create, ok := liquibaseCreateTable(td.To, schema); ok {
+			res.Changes = create
+		}
+		return []liquibaseChangeSet{res.of(changes([]tengo.ObjectDiff{td})...)}
+	case tengo.DiffTypeDrop:
+		res := liquibaseChangeSet{
+			Changes:  []*liquibaseElement{liquibaseTag("dropTable", "tableName", name)},
+			Rollback: liquibaseSQL(td.From.CreateStatement),
+		}
+		return []liquibaseChangeSet{res.of(changes([]tengo.ObjectDiff{td})...)}
+	}
+
+	if td.AlterClauses() == nil {
+		// Without clauses, the statement altering the table cannot be undone
+		stmt, _ := td.Statement(mods)
+		if stmt == "" {
+			return nil
+		}
+		res := liquibaseChangeSet{Changes: liquibaseSQL(stmt)}
+		return []liquibaseChangeSet{res.of(changes([]tengo.ObjectDiff{td})...)}
+	}
+	var res []liquibaseChangeSet
+	for _, c := range td.AlterClauses() {
+		change, _ := clauseChange(c, td)
+		if change.Kind == "" {
+			continue
+		}
+		change.Object, change.Table, change.Origin = tengo.ObjectTypeTable, td.From.Name, c
+		change.ID, change.Severity = change.id(), change.severity()
+		for _, cs := range f.clauseChangeSets(c, td, mods) {
+			// The ID of the changeSets of a change taking several of them is
+			// suffixed, i.e. with :drop and :add
+			suffix := cs.ID
+			cs = cs.of(change)
+			cs.ID += suffix
+			res = append(res, cs)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Stage < res[j].Stage })
+	return res
+}
+
+// clauseChangeSets returns the changeSets applying an alter clause of a table
+// diff. Clauses that the change types of Liquibase cannot express are applied
+// as an ALTER TABLE statement, rolled back by the one applying the opposite
+// clause.
+func (f *LiquibaseFormatter) clauseChangeSets(c tengo.TableAlterClause, td *TableDiff, mods tengo.StatementModifiers) []liquibaseChangeSet {
+	table := td.From.Name
+	dropColumn := func(col *tengo.Column) []*liquibaseElement {
+		return []*liquibaseElement{liquibaseTag("dropColumn", "tableName", table, "columnName", col.Name)}
+	}
+
+	switch c := c.(type) {
+	case tengo.AddColumn:
+		return []liquibaseChangeSet{{Changes: f.addColumn(c, td, mods), Rollback: dropColumn(c.Column)}}
+	case tengo.DropColumn:
+		first, after := columnPredecessor(td.From, c.Column.Name)
+		add := tengo.AddColumn{Table: td.From, Column: c.Column, PositionFirst: first, PositionAfter: after}
+		return []liquibaseChangeSet{{Changes: dropColumn(c.Column), Rollback: f.addColumn(add, td, mods)}}
+	case tengo.ModifyColumn:
+		return []liquibaseChangeSet{f.modifyColumn(AlterColumn{Alter: c}, td, mods)}
+	case MoveColumn:
+		if ac, ok := c.Alter.(AlterColumn); ok {
+			return []liquibaseChangeSet{f.modifyColumn(ac, td, mods)}
+		}
+		return []liquibaseChangeSet{f.modifyColumn(AlterColumn{Alter: c.Alter}, td, mods)}
+	case AlterColumn:
+		if add, ok := c.Alter.(tengo.AddColumn); ok {
+			if c.ToGeneration != nil || c.ToInvisible {
+				return []liquibaseChangeSet{{Changes: liquibaseAlter(td, c, mods), Rollback: dropColumn(add.Column)}}
+			}
+			return []liquibaseChangeSet{{Changes: f.addColumn(add, td, mods), Rollback: dropColumn(add.Column)}}
+		}
+		return []liquibaseChangeSet{f.modifyColumn(c, td, mods)}
+	case RenameColumn:
+		first, after := columnPredecessor(td.From, c.OldColumn.Name)
+		undo := RenameColumn{Table: td.From, OldColumn: c.NewColumn, NewColumn: c.OldColumn}
+		if c.PositionFirst || c.PositionAfter != nil {
+			undo.PositionFirst, undo.PositionAfter = first, after
+		}
+		renamed := *c.OldColumn
+		renamed.Name = c.NewColumn.Name
+		// renameColumn redefines the column with its type only, so it's used
+		// for the columns with no other attributes
+		if !renamed.Equals(c.NewColumn) || undo.PositionFirst || undo.PositionAfter != nil || !liquibasePlainColumn(c.OldColumn, td.From) {
+			return []liquibaseChangeSet{{Changes: liquibaseAlter(td, c, mods), Rollback: liquibaseAlter(td, undo, mods)}}
+		}
+		return []liquibaseChangeSet{{
+			Changes:  []*liquibaseElement{liquibaseTag("renameColumn", "tableName", table, "oldColumnName", c.OldColumn.Name, "newColumnName", c.NewColumn.Name, "columnDataType", c.NewColumn.TypeInDB)},
+			Rollback: []*liquibaseElement{liquibaseTag("renameColumn", "tableName", table, "oldColumnName", c.NewColumn.Name, "newColumnName", c.OldColumn.Name, "columnDataType", c.OldColumn.TypeInDB)},
+		}}
+	case tengo.AddIndex:
+		if c.Clause(mods) == "" {
+			return nil
+		}
+		return []liquibaseChangeSet{f.addIndex(&Index{Index: c.Index}, td, mods)}
+	case tengo.DropIndex:
+		if c.Clause(mods) == "" {
+			return nil
+		}
+		return []liquibaseChangeSet{f.dropIndex(&Index{Index: c.Index}, td, mods)}
+	case AlterIndex:
+		switch {
+		case c.From == nil:
+			return []liquibaseChangeSet{f.addIndex(c.To, td, mods)}
+		case c.To == nil:
+			return []liquibaseChangeSet{f.dropIndex(c.From, td, mods)}
+		case c.Clause(mods) == "":
+			return nil
+		case strings.HasPrefix(c.Clause(mods), "ALTER INDEX"):
+			return []liquibaseChangeSet{{Changes: liquibaseAlter(td, c, mods), Rollback: liquibaseAlter(td, AlterIndex{From: c.To, To: c.From}, mods)}}
+		}
+		drop, add := f.dropIndex(c.From, td, mods), f.addIndex(c.To, td, mods)
+		drop.ID, add.ID = ":drop", ":add"
+		return []liquibaseChangeSet{drop, add}
+	case tengo.AddForeignKey:
+		if c.Clause(mods) == "" {
+			return nil
+		}
+		return []liquibaseChangeSet{f.addForeignKey(c.ForeignKey, td)}
+	case tengo.DropForeignKey:
+		if c.Clause(mods) == "" {
+			return nil
+		}
+		return []liquibaseChangeSet{f.dropForeignKey(c.ForeignKey, td)}
+	case AlterForeignKey:
+		if c.Added {
+			add := f.addForeignKey(c.To, td)
+			add.ID = ":add"
+			return []liquibaseChangeSet{add}
+		}
+		drop := f.dropForeignKey(c.From, td)
+		drop.ID = ":drop"
+		return []liquibaseChangeSet{drop}
+	case AlterCheck:
+		switch {
+		case c.From == nil:
+			return []liquibaseChangeSet{f.addCheck(c.To, td, mods)}
+		case c.To == nil:
+			return []liquibaseChangeSet{f.dropCheck(c.From, td, mods)}
+		case c.Clause(mods) == "":
+			return nil
+		case strings.HasPrefix(c.Clause(mods), "ALTER CHECK"):
+			return []liquibaseChangeSet{{Changes: liquibaseAlter(td, c, mods), Rollback: liquibaseAlter(td, AlterCheck{From: c.To, To: c.From}, mods)}}
+		}
+		drop, add := f.dropCheck(c.From, td, mods), f.addCheck(c.To, td, mods)
+		drop.ID, add.ID = ":drop", ":add"
+		return []liquibaseChangeSet{drop, add}
+	case tengo.ChangeCharSet:
+		return []liquibaseChangeSet{{Changes: liquibaseAlter(td, c, mods), Rollback: liquibaseAlter(td, tengo.ChangeCharSet{CharSet: td.From.CharSet, Collation: td.From.Collation}, mods)}}
+	case tengo.ChangeCreateOptions:
+		return []liquibaseChangeSet{{Changes: liquibaseAlter(td, c, mods), Rollback: liquibaseAlter(td, tengo.ChangeCreateOptions{OldCreateOptions: c.NewCreateOptions, NewCreateOptions: c.OldCreateOptions}, mods)}}
+	case tengo.ChangeComment:
+		// setTableRemarks needs remarks, so comments are removed with SQL
+		remarks := func(comment string) []*liquibaseElement {
+			if comment == "" {
+				return liquibaseAlter(td, tengo.ChangeComment{}, mods)
+			}
+			return []*liquibaseElement{liquibaseTag("setTableRemarks", "tableName", table, "remarks", comment)}
+		}
+		return []liquibaseChangeSet{{Changes: remarks(c.NewComment), Rollback: remarks(td.From.Comment)}}
+	case tengo.ChangeStorageEngine:
+		return []liquibaseChangeSet{{Changes: liquibaseAlter(td, c, mods), Rollback: liquibaseAlter(td, tengo.ChangeStorageEngine{NewStorageEngine: td.From.Engine}, mods)}}
+	case tengo.ChangeAutoIncrement:
+		return nil
+	}
+	return []liquibaseChangeSet{{Changes: liquibaseAlter(td, c, mods)}}
+}
+
+// addColumn returns the change adding a column, or the SQL applying the
+// clause that adds it if Liquibase cannot define the column or place it
+// first.
+func (f *LiquibaseFormatter) addColumn(add tengo.AddColumn, td *TableDiff, mods tengo.StatementModifiers) []*liquibaseElement {
+	col, ok := liquibaseColumn(add.Column, add.Table)
+	if !ok || add.PositionFirst {
+		return liquibaseAlter(td, add, mods)
+	}
+	if add.PositionAfter != nil {
+		col.Attrs = append(col.Attrs, "afterColumn", add.PositionAfter.Name)
+	}
+	return []*liquibaseElement{liquibaseTag("addColumn", "tableName", td.From.Name).with(col)}
+}
+
+// modifyColumn returns the changeSet modifying a column, which is rolled back
+// by modifying it back, in its former position if it's moved. Only changes
+// of type or default of columns are made with structured changes, as
+// modifyDataType redefines the column with its type only.
+func (f *LiquibaseFormatter) modifyColumn(ac AlterColumn, td *TableDiff, mods tengo.StatementModifiers) liquibaseChangeSet {
+	undo := ac.undo(td)
+	mc := ac.Alter.(tengo.ModifyColumn)
+	if ac.FromGeneration == nil && ac.ToGeneration == nil && !ac.FromInvisible && !ac.ToInvisible && !mc.PositionFirst && mc.PositionAfter == nil {
+		up, ok := liquibaseModifyColumn(td.From.Name, mc.OldColumn, mc.NewColumn, td.From, mc.Table)
+		down, undoOK := liquibaseModifyColumn(td.From.Name, mc.NewColumn, mc.OldColumn, mc.Table, td.From)
+		if ok && undoOK {
+			return liquibaseChangeSet{Changes: []*liquibaseElement{up}, Rollback: []*liquibaseElement{down}}
+		}
+	}
+	return liquibaseChangeSet{Changes: liquibaseAlter(td, ac, mods), Rollback: liquibaseAlter(td, undo, mods)}
+}
+
+// addIndex returns the changeSet creating an index, or applying the clause
+// that adds it if Liquibase cannot define it.
+func (f *LiquibaseFormatter) addIndex(idx *Index, td *TableDiff, mods tengo.StatementModifiers) liquibaseChangeSet {
+	create, ok := liquibaseCreateIndex(idx, td.From.Name)
+	if !ok {
+		return liquibaseChangeSet{
+			Changes:  liquibaseSQL(fmt.Sprintf("%s ADD %s", td.From.AlterStatement(), idx.Definition(mods.Flavor))),
+			Rollback: liquibaseAlter(td, tengo.DropIndex{Index: idx.Index}, mods),
+			Stage:    1,
+		}
+	}
+	return liquibaseChangeSet{
+		Changes:  []*liquibaseElement{create},
+		Rollback: []*liquibaseElement{liquibaseTag("dropIndex", "tableName", td.From.Name, "indexName", idx.Name)},
+		Stage:    1,
+	}
+}
+
+// dropIndex returns the changeSet dropping an index, rolled back by creating
+// it again.
+func (f *LiquibaseFormatter) dropIndex(idx *Index, td *TableDiff, mods tengo.StatementModifiers) liquibaseChangeSet {
+	cs := f.addIndex(idx, td, mods)
+	cs.Changes, cs.Rollback, cs.Stage = cs.Rollback, cs.Changes, -cs.Stage
+	return cs
+}
+
+// addForeignKey returns the changeSet adding a foreign key
+func (f *LiquibaseFormatter) addForeignKey(fk *tengo.ForeignKey, td *TableDiff) liquibaseChangeSet {
+	return liquibaseChangeSet{
+		Changes:  []*liquibaseElement{liquibaseAddForeignKey(fk, td.From.Name)},
+		Rollback: []*liquibaseElement{liquibaseTag("dropForeignKeyConstraint", "baseTableName", td.From.Name, "constraintName", fk.Name)},
+		Stage:    2,
+	}
+}
+
+// dropForeignKey returns the changeSet dropping a foreign key, rolled back by
+// adding it again.
+func (f *LiquibaseFormatter) dropForeignKey(fk *tengo.ForeignKey, td *TableDiff) liquibaseChangeSet {
+	cs := f.addForeignKey(fk, td)
+	cs.Changes, cs.Rollback, cs.Stage = cs.Rollback, cs.Changes, -cs.Stage
+	return cs
+}
+
+// addCheck returns the changeSet adding a CHECK constraint, which Liquibase
+// has no change type for.
+func (f *LiquibaseFormatter) addCheck(check *Check, td *TableDiff, mods tengo.StatementModifiers) liquibaseChangeSet {
+	return liquibaseChangeSet{
+		Changes:  liquibaseAlter(td, AlterCheck{To: check}, mods),
+		Rollback: liquibaseAlter(td, AlterCheck{From: check}, mods),
+		Stage:    1,
+	}
+}
+
+// dropCheck returns the changeSet dropping a CHECK constraint, rolled back by
+// adding it again.
+func (f *LiquibaseFormatter) dropCheck(check *Check, td *TableDiff, mods tengo.StatementModifiers) liquibaseChangeSet {
+	cs := f.addCheck(check, td, mods)
+	cs.Changes, cs.Rollback, cs.Stage = cs.Rollback, cs.Changes, -cs.Stage
+	return cs
+}
+
+// of returns the changeSet identified and described by the given changes,
+// the first of them giving its ID, i.e. add_column:tasks.due_at, and all of
+// them its comment, i.e. table tasks: add column due_at (additive).
+func (cs liquibaseChangeSet) of(changes ...Change) liquibaseChangeSet {
+	var descriptions []string
+	for _, c := range changes {
+		descriptions = append(descriptions, fmt.Sprintf("%s: %s (%s)", c.object(), c.description(), c.Severity))
+	}
+	if len(changes) > 0 {
+		cs.ID = changes[0].ID
+	}
+	cs.Comment = strings.Join(descriptions, "; ")
+	return cs
+}
+
+// element returns the changeSet element of the changelog
+func (cs liquibaseChangeSet) element() *liquibaseElement {
+	res := liquibaseTag("changeSet", "id", cs.ID, "author", liquibaseAuthor)
+	if cs.Comment != "" {
+		res.Children = append(res.Children, &liquibaseElement{Name: "comment", Text: cs.Comment})
+	}
+	res.Children = append(res.Children, cs.Changes...)
+	if cs.Rollback != nil {
+		res.Children = append(res.Children, liquibaseTag("rollback").with(cs.Rollback...))
+	}
+	return res
+}
+
+// liquibaseCreateTable returns the changes creating a table along with its
+// indexes and foreign keys, or false if Liquibase cannot define it. That's
+// the case of tables using features tengo doesn't support, or options other
+// than the default ones of the schema they are created in.
+func liquibaseCreateTable(t *tengo.Table, schema *tengo.Schema) ([]*liquibaseElement, bool) {
+	if t.UnsupportedDDL || t.Engine != "InnoDB" || t.CreateOptions != "" || t.CharSet != schema.CharSet || t.Collation != schema.Collation {
+		return nil, false
+	}
+	// The primary key is defined in its columns, so they must be in order
+	primaryKey := map[string]bool{}
+	if t.PrimaryKey != nil {
+		i := 0
+		for _, col := range t.Columns {
+			if i < len(t.PrimaryKey.Columns) && col.Name == t.PrimaryKey.Columns[i].Name {
+				if t.PrimaryKey.SubParts[i] > 0 {
+					return nil, false
+				}
+				primaryKey[col.Name] = true
+				i++
+			}
+		}
+		if i < len(t.PrimaryKey.Columns) {
+			return nil, false
+		}
+	}
+
+	create := liquibaseTag("createTable", "tableName", t.Name, "remarks", t.Comment)
+	for _, c := range t.Columns {
+		col, ok := liquibaseColumn(c, t)
+		if !ok {
+			return nil, false
+		}
+		if primaryKey[c.Name] {
+			col.Children = []*liquibaseElement{liquibaseTag("constraints", "primaryKey", "true", "nullable", "false")}
+		}
+		create.Children = append(create.Children, col)
+	}
+	res := []*liquibaseElement{create}
+	for _, idx := range t.SecondaryIndexes {
+		index, ok := liquibaseCreateIndex(&Index{Index: idx}, t.Name)
+		if !ok {
+			return nil, false
+		}
+		res = append(res, index)
+	}
+	for _, fk := range t.ForeignKeys {
+		res = append(res, liquibaseAddForeignKey(fk, t.Name))
+	}
+	return res, true
+}
+
+// liquibaseColumn returns the column element defining a column of a table, or
+// false if Liquibase cannot define it, as it happens with the columns updated
+// on update, or with a character set other than the one of the table.
+func liquibaseColumn(col *tengo.Column, table *tengo.Table) (*liquibaseElement, bool) {
+	if col.OnUpdate != "" || (col.CharSet != "" && (col.CharSet != table.CharSet || col.Collation != table.Collation)) {
+		return nil, false
+	}
+	res := liquibaseTag("column", "name", col.Name, "type", col.TypeInDB)
+	switch {
+	case col.AutoIncrement:
+		res.Attrs = append(res.Attrs, "autoIncrement", "true")
+	case col.Default.Quoted:
+		res.Attrs = append(res.Attrs, "defaultValue", col.Default.Value)
+	case !col.Default.Null && col.Default.Value != "":
+		res.Attrs = append(res.Attrs, "defaultValueComputed", col.Default.Value)
+	}
+	if col.Comment != "" {
+		res.Attrs = append(res.Attrs, "remarks", col.Comment)
+	}
+	if !col.Nullable {
+		res.Children = []*liquibaseElement{liquibaseTag("constraints", "nullable", "false")}
+	}
+	return res, true
+}
+
+// liquibasePlainColumn returns true if a column of a table has no other
+// attributes than its type, so it's defined by the type alone.
+func liquibasePlainColumn(col *tengo.Column, table *tengo.Table) bool {
+	return col.Nullable && col.Default == tengo.ColumnDefaultNull && !col.AutoIncrement && col.OnUpdate == "" && col.Comment == "" &&
+		(col.CharSet == "" || (col.CharSet == table.CharSet && col.Collation == table.Collation))
+}
+
+// liquibaseModifyColumn returns the change modifying a column of a table
+// from one definition into another one, or false if it takes more than
+// changing its type or default value.
+func liquibaseModifyColumn(table string, from, to *tengo.Column, fromTable, toTable *tengo.Table) (*liquibaseElement, bool) {
+	retyped := *from
+	retyped.TypeInDB, retyped.CharSet, retyped.Collation, retyped.CollationIsDefault = to.TypeInDB, to.CharSet, to.Collation, to.CollationIsDefault
+	if retyped.Equals(to) && liquibasePlainColumn(from, fromTable) && liquibasePlainColumn(to, toTable) {
+		return liquibaseTag("modifyDataType", "tableName", table, "columnName", to.Name, "newDataType", to.TypeInDB), true
+	}
+
+	redefaulted := *from
+	redefaulted.Default = to.Default
+	switch {
+	case !redefaulted.Equals(to):
+		return nil, false
+	case to.Default.Quoted:
+		return liquibaseTag("addDefaultValue", "tableName", table, "columnName", to.Name, "columnDataType", to.TypeInDB, "defaultValue", to.Default.Value), true
+	case to.Default == tengo.ColumnDefault{}:
+		return liquibaseTag("dropDefaultValue", "tableName", table, "columnName", to.Name, "columnDataType", to.TypeInDB), true
+	}
+	return nil, false
+}
+
+// liquibaseCreateIndex returns the change creating an index on a table, or
+// false if Liquibase cannot define it, as it happens with primary keys,
+// prefixes of columns, descending parts and full-text or spatial indexes.
+func liquibaseCreateIndex(idx *Index, table string) (*liquibaseElement, bool) {
+	if idx.PrimaryKey || idx.Type != "" || idx.Invisible || idx.Comment != "" {
+		return nil, false
+	}
+	res := liquibaseTag("createIndex", "tableName", table, "indexName", idx.Name)
+	if idx.Unique {
+		res.Attrs = append(res.Attrs, "unique", "true")
+	}
+	for i, c := range idx.Columns {
+		if idx.SubParts[i] > 0 || (i < len(idx.Desc) && idx.Desc[i]) {
+			return nil, false
+		}
+		res.Children = append(res.Children, liquibaseTag("column", "name", c.Name))
+	}
+	return res, true
+}
+
+// liquibaseAddForeignKey returns the change adding a foreign key to a table
+func liquibaseAddForeignKey(fk *tengo.ForeignKey, table string) *liquibaseElement {
+	var columns []string
+	for _, c := range fk.Columns {
+		columns = append(columns, c.Name)
+	}
+	return liquibaseTag("addForeignKeyConstraint",
+		"baseTableName", table,
+		"baseColumnNames", strings.Join(columns, ","),
+		"constraintName", fk.Name,
+		"referencedTableSchemaName", fk.ReferencedSchemaName,
+		"referencedTableName", fk.ReferencedTableName,
+		"referencedColumnNames", strings.Join(fk.ReferencedColumnNames, ","),
+		"onDelete", fk.DeleteRule,
+		"onUpdate", fk.UpdateRule)
+}
+
+// liquibaseAlter returns the change running the ALTER TABLE statement that
+// applies a clause to the table of a table diff.
+func liquibaseAlter(td *TableDiff, c tengo.TableAlterClause, mods tengo.StatementModifiers) []*liquibaseElement {
+	return liquibaseSQL(fmt.Sprintf("%s %s", td.From.AlterStatement(), c.Clause(mods)))
+}
+
+// liquibaseSQL returns the changes running the given SQL statements, which
+// are not split, as the bodies of triggers and routines have semicolons.
+func liquibaseSQL(stmts ...string) []*liquibaseElement {
+	var res []*liquibaseElement
+	for _, stmt := range stmts {
+		sql := liquibaseTag("sql", "splitStatements", "false")
+		sql.Text = stmt
+		res = append(res, sql)
+	}
+	return res
+}
+
+// liquibaseTag returns an element with the given name and pairs of names and
+// values of attributes. Attributes with an empty value are left out.
+func liquibaseTag(name string, attrs ...string) *liquibaseElement {
+	res := &liquibaseElement{Name: name}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			res.Attrs = append(res.Attrs, attrs[i], attrs[i+1])
+		}
+	}
+	return res
+}
+
+// with returns the element with the given children appended
+func (e *liquibaseElement) with(children ...*liquibaseElement) *liquibaseElement {
+	e.Children = append(e.Children, children...)
+	return e
+}
+
+// write writes the element to buf, indented by the given prefix. Text with
+// markup characters is written as a CDATA section, so SQL remains readable.
+func (e *liquibaseElement) write(buf *bytes.Buffer, indent string) {
+	buf.WriteString(indent + "<" + e.Name)
+	for i := 0; i+1 < len(e.Attrs); i += 2 {
+		buf.WriteString(fmt.Sprintf(" %s=\"", e.Attrs[i]))
+		xml.EscapeText(buf, []byte(e.Attrs[i+1]))
+		buf.WriteString("\"")
+	}
+	switch {
+	case e.Text != "" && strings.ContainsAny(e.Text, "<>&"):
+		buf.WriteString("><![CDATA[" + strings.Replace(e.Text, "]]>", "]]]]><![CDATA[>", -1) + "]]></" + e.Name + ">\n")
+	case e.Text != "":
+		buf.WriteString(">" + e.Text + "</" + e.Name + ">\n")
+	case len(e.Children) == 0:
+		buf.WriteString("/>\n")
+	default:
+		buf.WriteString(">\n")
+		for _, child := range e.Children {
+			child.write(buf, indent+"    ")
+		}
+		buf.WriteString(indent + "</" + e.Name + ">\n")
+	}
+}
+
+// xmlComment returns an XML comment line with the given text, whose double
+// hyphens, not allowed in comments, are split.
+func xmlComment(text string) string {
+	return fmt.Sprintf("<!-- %s -->\n", strings.Replace(text, "--", "- -", -1))
+}
@@ -0,0 +1,140 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestLiquibaseFormatter_Format(t *testing.T) {
+	id := &tengo.Column{Name: "id", TypeInDB: "int", AutoIncrement: true}
+	title := &tengo.Column{Name: "title", TypeInDB: "varchar(255)", Nullable: true, Default: tengo.ColumnDefaultNull, CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true}
+	longTitle := &tengo.Column{Name: "title", TypeInDB: "varchar(500)", Default: tengo.ColumnDefaultValue("untitled"), CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true}
+	ownerID := &tengo.Column{Name: "owner_id", TypeInDB: "int", Nullable: true, Default: tengo.ColumnDefaultNull}
+	bigOwnerID := &tengo.Column{Name: "owner_id", TypeInDB: "bigint", Nullable: true, Default: tengo.ColumnDefaultNull}
+	dueAt := &tengo.Column{Name: "due_at", TypeInDB: "datetime(6)", Nullable: true, Default: tengo.ColumnDefaultNull}
+	table := func(columns ...*tengo.Column) *tengo.Table {
+		table := &tengo.Table{Name: "tasks", Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true, Columns: columns}
+		table.PrimaryKey = &tengo.Index{Name: "PRIMARY", Columns: []*tengo.Column{id}, SubParts: []uint16{0}, PrimaryKey: true, Unique: true}
+		for _, c := range columns {
+			if c.Name == "owner_id" {
+				table.SecondaryIndexes = []*tengo.Index{{Name: "owner_index", Columns: []*tengo.Column{c}, SubParts: []uint16{0}}}
+			}
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorMySQL80)
+		return table
+	}
+	diff := func(from, to []*tengo.Table) *Diff {
+		return &Diff{
+			DSN1:     ParseDSN(DSN1),
+			DSN2:     ParseDSN(DSN2),
+			From:     &tengo.Schema{Name: "shop", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", Tables: from},
+			To:       &tengo.Schema{Name: "shop", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", Tables: to},
+			Flavor1:  tengo.FlavorMySQL80,
+			Flavor2:  tengo.FlavorMySQL80,
+			objects1: &Objects{},
+			objects2: &Objects{},
+		}
+	}
+	header := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" +
+		"<!-- Differences between `shop` in 127.0.0.1:33060 and `shop` in 127.0.0.1:33062 -->\n" +
+		"<databaseChangeLog xmlns=\"http://www.liquibase.org/xml/ns/dbchangelog\" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\" xsi:schemaLocation=\"http://www.liquibase.org/xml/ns/dbchangelog http://www.liquibase.org/xml/ns/dbchangelog/dbchangelog-3.8.xsd\">\n"
+
+	t.Run("Create table", func(t *testing.T) {
+		Equal(t, header+
+			"    <changeSet id=\"create_table:tasks\" author=\"mydiff\">\n"+
+			"        <comment>table tasks: create table (additive)</comment>\n"+
+			"        <createTable tableName=\"tasks\">\n"+
+			"            <column name=\"id\" type=\"int\" autoIncrement=\"true\">\n"+
+			"                <constraints primaryKey=\"true\" nullable=\"false\"/>\n"+
+			"            </column>\n"+
+			"            <column name=\"title\" type=\"varchar(255)\"/>\n"+
+			"            <column name=\"owner_id\" type=\"int\"/>\n"+
+			"        </createTable>\n"+
+			"        <createIndex tableName=\"tasks\" indexName=\"owner_index\">\n"+
+			"            <column name=\"owner_id\"/>\n"+
+			"        </createIndex>\n"+
+			"        <rollback>\n"+
+			"            <dropTable tableName=\"tasks\"/>\n"+
+			"        </rollback>\n"+
+			"    </changeSet>\n"+
+			"</databaseChangeLog>\n", (&LiquibaseFormatter{}).Format(diff(nil, []*tengo.Table{table(id, title, ownerID)})))
+	})
+
+	t.Run("Alter table", func(t *testing.T) {
+		Equal(t, header+
+			"    <changeSet id=\"drop_index:tasks.owner_index\" author=\"mydiff\">\n"+
+			"        <comment>table tasks: drop index owner_index (risky)</comment>\n"+
+			"        <dropIndex tableName=\"tasks\" indexName=\"owner_index\"/>\n"+
+			"        <rollback>\n"+
+			"            <createIndex tableName=\"tasks\" indexName=\"owner_index\">\n"+
+			"                <column name=\"owner_id\"/>\n"+
+			"            </createIndex>\n"+
+			"        </rollback>\n"+
+			"    </changeSet>\n"+
+			"    <changeSet id=\"drop_column:tasks.owner_id\" author=\"mydiff\">\n"+
+			"        <comment>table tasks: drop column owner_id (breaking)</comment>\n"+
+			"        <dropColumn tableName=\"tasks\" columnName=\"owner_id\"/>\n"+
+			"        <rollback>\n"+
+			"            <addColumn tableName=\"tasks\">\n"+
+			"                <column name=\"owner_id\" type=\"int\" afterColumn=\"title\"/>\n"+
+			"            </addColumn>\n"+
+			"        </rollback>\n"+
+			"    </changeSet>\n"+
+			"    <changeSet id=\"add_column:tasks.due_at\" author=\"mydiff\">\n"+
+			"        <comment>table tasks: add column due_at (additive)</comment>\n"+
+			"        <addColumn tableName=\"tasks\">\n"+
+			"            <column name=\"due_at\" type=\"datetime(6)\"/>\n"+
+			"        </addColumn>\n"+
+			"        <rollback>\n"+
+			"            <dropColumn tableName=\"tasks\" columnName=\"due_at\"/>\n"+
+			"        </rollback>\n"+
+			"    </changeSet>\n"+
+			"</databaseChangeLog>\n", (&LiquibaseFormatter{}).Format(diff([]*tengo.Table{table(id, title, ownerID)}, []*tengo.Table{table(id, title, dueAt)})))
+	})
+
+	t.Run("Raw SQL", func(t *testing.T) {
+		Equal(t, header+
+			"    <changeSet id=\"modify_column:tasks.title\" author=\"mydiff\">\n"+
+			"        <comment>table tasks: modify column title (risky)</comment>\n"+
+			"        <sql splitStatements=\"false\">ALTER TABLE `tasks` MODIFY COLUMN `title` varchar(500) NOT NULL DEFAULT 'untitled'</sql>\n"+
+			"        <rollback>\n"+
+			"            <sql splitStatements=\"false\">ALTER TABLE `tasks` MODIFY COLUMN `title` varchar(255) DEFAULT NULL</sql>\n"+
+			"        </rollback>\n"+
+			"    </changeSet>\n"+
+			"    <changeSet id=\"modify_column:tasks.owner_id\" author=\"mydiff\">\n"+
+			"        <comment>table tasks: modify column owner_id (additive)</comment>\n"+
+			"        <modifyDataType tableName=\"tasks\" columnName=\"owner_id\" newDataType=\"bigint\"/>\n"+
+			"        <rollback>\n"+
+			"            <modifyDataType tableName=\"tasks\" columnName=\"owner_id\" newDataType=\"int\"/>\n"+
+			"        </rollback>\n"+
+			"    </changeSet>\n"+
+			"</databaseChangeLog>\n", (&LiquibaseFormatter{}).Format(diff([]*tengo.Table{table(id, title, ownerID)}, []*tengo.Table{table(id, longTitle, bigOwnerID)})))
+	})
+}
+
+func TestLiquibaseElement_Write(t *testing.T) {
+	sql := liquibaseSQL("CREATE TRIGGER `t` BEFORE INSERT ON `tasks` FOR EACH ROW SET NEW.done = NEW.due_at < NOW()")[0]
+	comment := &liquibaseElement{Name: "comment", Text: "it's a \"trigger\""}
+	var buf bytes.Buffer
+	liquibaseTag("changeSet", "id", "a<b", "author", "").with(comment, sql).write(&buf, "")
+	Equal(t, "<changeSet id=\"a&lt;b\">\n"+
+		"    <comment>it's a \"trigger\"</comment>\n"+
+		"    <sql splitStatements=\"false\"><![CDATA[CREATE TRIGGER `t` BEFORE INSERT ON `tasks` FOR EACH ROW SET NEW.done = NEW.due_at < NOW()]]></sql>\n"+
+		"</changeSet>\n", buf.String())
+}
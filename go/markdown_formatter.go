@@ -0,0 +1,142 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// MarkdownFormatter formats a diff as a GitHub flavored markdown document,
+// suitable for pull request comments.
+//
+// The document summarizes the number of changes of each kind, and lists them
+// in a collapsible section per object with their definitions in each schema,
+// followed by the missing migrations and the SQL statements reconciling the
+// schemas.
+type MarkdownFormatter struct{}
+
+// FormatsMigrations (see MigrationsFormatter)
+func (f *MarkdownFormatter) FormatsMigrations() {}
+
+// Format returns a string with the diff as a markdown document
+func (f *MarkdownFormatter) Format(diff *Diff) interface{} {
+	var buf bytes.Buffer
+	ods := diff.Compute()
+	cs := changes(ods)
+
+	buf.WriteString(fmt.Sprintf("## Differences between `%s` in %s and `%s` in %s\n\n", diff.From.Name, diff.DSN1.Addr, diff.To.Name, diff.DSN2.Addr))
+	buf.WriteString(fmt.Sprintf("%s\n\n", Summary(cs)))
+	if len(cs) > 0 {
+		groups := groupChanges(cs)
+		buf.WriteString(f.countsTable(groups))
+		buf.WriteString(f.objectSections(groups, diff))
+	}
+
+	for _, od := range ods {
+		if md, ok := od.(*MigrationsDiff); ok {
+			buf.WriteString(f.migrations(md))
+		}
+	}
+
+	if warnings := diff.Warnings(); len(warnings) > 0 {
+		buf.WriteString("### Warnings\n\n")
+		for _, w := range warnings {
+			buf.WriteString(fmt.Sprintf("- %s\n", w))
+		}
+		buf.WriteString("\n")
+	}
+
+	if sql := sqlStatements(diff, delimitedStatement); sql != "" {
+		buf.WriteString("### SQL to reconcile the schemas\n\n")
+		buf.WriteString(fmt.Sprintf("```sql\n%s```\n", sql))
+	}
+	return buf.String()
+}
+
+// countsTable returns a table with the number of changes of each kind
+func (f *MarkdownFormatter) countsTable(groups changeGroups) string {
+	kinds := make([]string, len(groups.Kinds))
+	for i, k := range groups.Kinds {
+		kinds[i] = string(k)
+	}
+	sort.Strings(kinds)
+
+	var buf bytes.Buffer
+	buf.WriteString("| Change | Count |\n")
+	buf.WriteString("| --- | ---: |\n")
+	for _, k := range kinds {
+		buf.WriteString(fmt.Sprintf("| `%s` | %d |\n", k, groups.Counts[ChangeKind(k)]))
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+// objectSections returns a collapsible section for each object changed,
+// listing its changes in the order they were computed.
+func (f *MarkdownFormatter) objectSections(groups changeGroups, context *Diff) string {
+	var buf bytes.Buffer
+	for _, object := range groups.Objects {
+		buf.WriteString(fmt.Sprintf("<details>\n<summary>%s (%d, %s)</summary>\n\n", object.Name, len(object.Changes), object.Severity))
+		for _, c := range object.Changes {
+			buf.WriteString(f.change(c, context))
+		}
+		buf.WriteString("</details>\n\n")
+	}
+	return buf.String()
+}
+
+// change describes a change, followed by its definitions in each schema
+func (f *MarkdownFormatter) change(c Change, context *Diff) string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("**%s**", c.Kind))
+	if c.Name != "" {
+		buf.WriteString(fmt.Sprintf(" `%s`", c.Name))
+	}
+	if c.RenamedTo != "" {
+		buf.WriteString(fmt.Sprintf(" to `%s`", c.RenamedTo))
+		if c.Probable {
+			buf.WriteString(" (probably)")
+		}
+	}
+	buf.WriteString(fmt.Sprintf(", %s\n\n", c.Severity))
+	if c.From != "" {
+		buf.WriteString(fmt.Sprintf("Before, in `%s`:\n\n```sql\n%s\n```\n\n", context.From.Name, c.From))
+	}
+	if c.To != "" {
+		buf.WriteString(fmt.Sprintf("After, in `%s`:\n\n```sql\n%s\n```\n\n", context.To.Name, c.To))
+	}
+	return buf.String()
+}
+
+// migrations lists the migrations missing in each server
+func (f *MarkdownFormatter) migrations(md *MigrationsDiff) string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("### Migrations\n\nSome migrations in `%s.%s` are missing.\n\n", md.Table, md.Column))
+	for _, missing := range []struct {
+		addr     string
+		versions []string
+	}{{md.Context.DSN1.Addr, md.Missing1}, {md.Context.DSN2.Addr, md.Missing2}} {
+		if len(missing.versions) == 0 {
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("Missing in %s:\n\n", missing.addr))
+		for _, v := range missing.versions {
+			buf.WriteString(fmt.Sprintf("- `%s`\n", v))
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
@@ -0,0 +1,90 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownFormatter_Format(t *testing.T) {
+	schema1 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			owner_id INT,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) NOT NULL,
+			UNIQUE KEY version_key(version)
+		)  ENGINE=INNODB;`,
+		`INSERT INTO schema_migrations values (20190815193300);`,
+	}
+
+	schema2 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) NOT NULL,
+			UNIQUE KEY version_key(version)
+		)  ENGINE=INNODB;`,
+		`INSERT INTO schema_migrations values (20190815193300);`,
+		`INSERT INTO schema_migrations values (20190816000000);`,
+	}
+
+	markdownFmt, _ := NewFormatter("markdown")
+	out := RunDiff(t, schema1, schema2, markdownFmt).(string)
+
+	Contains(t, out, "Differences found (1): 1 breaking\n")
+	Contains(t, out, "| `drop_column` | 1 |\n")
+	Contains(t, out, "<details>\n<summary>table tasks (1, breaking)</summary>\n\n**drop_column** `owner_id`, breaking\n\n")
+	Regexp(t, "Before, in `schema1_\\d+`:\n\n```sql\n`owner_id` int\\(11\\) DEFAULT NULL\n```\n", out)
+	Contains(t, out, "Missing in 127.0.0.1:33060:\n\n- `20190816000000`\n")
+	Contains(t, out, "```sql\n-- Severity: breaking\nALTER TABLE `tasks` DROP COLUMN `owner_id`;\n```\n")
+}
+
+func TestMarkdownFormatter_Warnings(t *testing.T) {
+	table := func(name string, columns ...string) *tengo.Table {
+		table := &tengo.Table{Name: name, Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true}
+		for _, c := range columns {
+			table.Columns = append(table.Columns, &tengo.Column{Name: c, TypeInDB: "int", Nullable: true, Default: tengo.ColumnDefaultNull})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorMySQL80)
+		return table
+	}
+	diff := &Diff{
+		DSN1:            ParseDSN(DSN1),
+		DSN2:            ParseDSN(DSN2),
+		From:            &tengo.Schema{Name: "shop", Tables: []*tengo.Table{table("Tasks", "id")}},
+		To:              &tengo.Schema{Name: "shop", Tables: []*tengo.Table{table("tasks", "id", "position")}},
+		Flavor1:         tengo.FlavorMySQL80,
+		Flavor2:         tengo.FlavorMySQL80,
+		LowerCaseNames2: 1,
+		objects1:        &Objects{},
+		objects2:        &Objects{},
+	}
+
+	// warnings are listed apart, rather than in the SQL block
+	markdownFmt, _ := NewFormatter("markdown")
+	out := markdownFmt.Format(diff).(string)
+	Contains(t, out, "### Warnings\n\n- Table Tasks in shop.127.0.0.1:33060 would be named tasks in 127.0.0.1:33062, which has lower_case_table_names=1\n")
+	Contains(t, out, "```sql\n-- Severity: additive\nALTER TABLE `Tasks` ADD COLUMN `position` int DEFAULT NULL;\n```\n")
+	NotContains(t, out, "-- Warning")
+}
@@ -0,0 +1,95 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// migrationVersionFormat is the format of the versions of migration files, a
+// timestamp like the ones given by golang-migrate create.
+const migrationVersionFormat = "20060102150405"
+
+// MigrationFile is a file of a migration reconciling the schemas of a diff
+type MigrationFile struct {
+	Name    string
+	Content string
+}
+
+// migrationTools name the files of a migration applying up, and undoing it
+// with down, for each of the migration tools supported.
+var migrationTools = map[string]func(version, name, up, down string) []MigrationFile{
+	// golang-migrate applies the up file of each version, and the down file
+	// to roll it back.
+	"golang-migrate": func(version, name, up, down string) []MigrationFile {
+		return []MigrationFile{
+			{Name: fmt.Sprintf("%s_%s.up.sql", version, name), Content: up},
+			{Name: fmt.Sprintf("%s_%s.down.sql", version, name), Content: down},
+		}
+	},
+	// flyway applies versioned migrations, prefixed with V, and undoes them
+	// with the undo migration of the same version, prefixed with U. Undo
+	// migrations are only run by Flyway Teams, other editions ignore them.
+	"flyway": func(version, name, up, down string) []MigrationFile {
+		return []MigrationFile{
+			{Name: fmt.Sprintf("V%s__%s.sql", version, name), Content: up},
+			{Name: fmt.Sprintf("U%s__%s.sql", version, name), Content: down},
+		}
+	},
+}
+
+// MigrationTools returns the names of the migration tools whose files can be
+// written by MigrationFiles.
+func MigrationTools() []string {
+	var res []string
+	for tool := range migrationTools {
+		res = append(res, tool)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// migrationName matches the characters that cannot be part of the name of a
+// migration file.
+var migrationName = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// MigrationFiles returns the files of a migration for the given tool, with
+// the given name and the version of the given time. The migration runs the
+// statements of the diff (see SQLFormatter) and undoes them with the
+// statements of the diff in the other direction (see Diff.Reverse).
+//
+// Unlike the ones of SQLFormatter, the statements defining the bodies of
+// triggers, routines and events are not wrapped in DELIMITER commands, which
+// only the mysql client understands, and are terminated by a semicolon like
+// the rest.
+//
+// No files are returned if the schemas don't differ.
+func MigrationFiles(diff *Diff, tool, name string, at time.Time) ([]MigrationFile, error) {
+	files, ok := migrationTools[tool]
+	if !ok {
+		return nil, fmt.Errorf("Unknown migration tool %s, only (%s) are allowed", tool, strings.Join(MigrationTools(), ","))
+	}
+	if len(diff.Changes()) == 0 {
+		return nil, nil
+	}
+	up := sqlWarnings(diff) + sqlStatements(diff, terminated)
+	reversed := diff.Reverse()
+	down := sqlWarnings(reversed) + sqlStatements(reversed, terminated)
+	name = strings.Trim(migrationName.ReplaceAllString(name, "_"), "_")
+	return files(at.UTC().Format(migrationVersionFormat), name, up, down), nil
+}
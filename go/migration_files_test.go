@@ -0,0 +1,94 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestMigrationFiles(t *testing.T) {
+	table := func(columns ...string) *tengo.Table {
+		table := &tengo.Table{Name: "tasks", Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true}
+		for _, name := range columns {
+			typ := "int"
+			if name == "due_at" {
+				typ = "datetime"
+			}
+			table.Columns = append(table.Columns, &tengo.Column{Name: name, TypeInDB: typ, Nullable: true, Default: tengo.ColumnDefaultNull})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorMySQL80)
+		return table
+	}
+	diff := func(from, to *tengo.Table) *Diff {
+		return &Diff{
+			DSN1:     ParseDSN(DSN1),
+			DSN2:     ParseDSN(DSN2),
+			From:     &tengo.Schema{Name: "shop", Tables: []*tengo.Table{from}},
+			To:       &tengo.Schema{Name: "shop", Tables: []*tengo.Table{to}},
+			Flavor1:  tengo.FlavorMySQL80,
+			Flavor2:  tengo.FlavorMySQL80,
+			objects1: &Objects{},
+			objects2: &Objects{},
+		}
+	}
+	at := time.Date(2019, 10, 18, 15, 4, 5, 0, time.UTC)
+
+	d := diff(table("id", "owner_id"), table("id", "due_at"))
+	files, err := MigrationFiles(d, "golang-migrate", "Drop owner, add due date", at)
+	Nil(t, err)
+	Equal(t, []MigrationFile{
+		{
+			Name:    "20191018150405_Drop_owner_add_due_date.up.sql",
+			Content: "-- Severity: breaking\nALTER TABLE `tasks` DROP COLUMN `owner_id`, ADD COLUMN `due_at` datetime DEFAULT NULL;\n",
+		},
+		{
+			Name:    "20191018150405_Drop_owner_add_due_date.down.sql",
+			Content: "-- Severity: breaking\nALTER TABLE `tasks` DROP COLUMN `due_at`, ADD COLUMN `owner_id` int DEFAULT NULL;\n",
+		},
+	}, files)
+
+	files, err = MigrationFiles(d, "flyway", "reconcile_schemas", at)
+	Nil(t, err)
+	Equal(t, "V20191018150405__reconcile_schemas.sql", files[0].Name)
+	Equal(t, "U20191018150405__reconcile_schemas.sql", files[1].Name)
+
+	// the opposite of the changes ignored are ignored when undoing them
+	d = diff(table("id", "owner_id"), table("id", "due_at"))
+	d.Filter = NewFilter()
+	Nil(t, d.Filter.Add(IgnoreChanges, "add_column"))
+	files, err = MigrationFiles(d, "golang-migrate", "reconcile_schemas", at)
+	Nil(t, err)
+	Equal(t, "-- Severity: breaking\nALTER TABLE `tasks` DROP COLUMN `owner_id`;\n", files[0].Content)
+	Equal(t, "-- Severity: additive\nALTER TABLE `tasks` ADD COLUMN `owner_id` int DEFAULT NULL;\n", files[1].Content)
+
+	// the bodies of triggers are not wrapped in DELIMITER commands
+	d = diff(table("id"), table("id"))
+	d.objects2 = &Objects{Triggers: []*Trigger{{Name: "audit", Table: "tasks", Event: "INSERT", Timing: "AFTER", Statement: "BEGIN SET @n = 1; SET @m = 2; END", Definer: "root@localhost"}}}
+	files, err = MigrationFiles(d, "golang-migrate", "reconcile_schemas", at)
+	Nil(t, err)
+	Contains(t, files[0].Content, " FOR EACH ROW BEGIN SET @n = 1; SET @m = 2; END;\n")
+	NotContains(t, files[0].Content, "DELIMITER")
+	Contains(t, files[1].Content, "DROP TRIGGER IF EXISTS `audit`;\n")
+
+	files, err = MigrationFiles(diff(table("id"), table("id")), "golang-migrate", "reconcile_schemas", at)
+	Nil(t, err)
+	Empty(t, files)
+
+	_, err = MigrationFiles(d, "liquibase", "reconcile_schemas", at)
+	NotNil(t, err)
+}
@@ -42,13 +42,7 @@ type MigrationsDiff struct {
 // This is useful while detecting inconsistencies
 // in the DBs of web application development frameworks such as rails
 func NewMigrationsDiff(d *Diff) (m *MigrationsDiff, err error) {
-	var table, col string
-	parts := strings.Split(d.MigrationsCol, ".")
-	if len(parts) == 2 {
-		table = parts[0]
-		col = parts[1]
-	}
-
+	table, col := splitMigrationsCol(d.MigrationsCol)
 	m = &MigrationsDiff{
 		Context:  d,
 		Table:    table,
@@ -59,7 +53,7 @@ func NewMigrationsDiff(d *Diff) (m *MigrationsDiff, err error) {
 
 	dsn1 := *d.DSN1
 	dsn1.DBName = d.From.Name
-	migrations1, err := m.existingMigrations(dsn1, col, table)
+	migrations1, err := existingMigrations(dsn1, col, table)
 	if err != nil {
 		log.Warningf("Cannot retrieve migrations from %s.%s in %s/%s. Error: %s", table, col, dsn1.Addr, dsn1.DBName, err)
 		return
@@ -67,7 +61,7 @@ func NewMigrationsDiff(d *Diff) (m *MigrationsDiff, err error) {
 
 	dsn2 := *d.DSN2
 	dsn2.DBName = d.To.Name
-	migrations2, err := m.existingMigrations(dsn2, col, table)
+	migrations2, err := existingMigrations(dsn2, col, table)
 	if err != nil {
 		log.Warningf("Cannot retrieve migrations from %s.%s in %s/%s. Error: %s", table, col, dsn1.Addr, dsn1.DBName, err)
 		return
@@ -101,15 +95,19 @@ func (m *MigrationsDiff) IsEmpty() bool {
 	return len(m.Missing1) == 0 && len(m.Missing2) == 0
 }
 
-func (m *MigrationsDiff) existingMigrations(DSN ParsedDSN, col string, table string) ([]string, error) {
+// existingMigrations returns the values of col in table, for the schema
+// the given DSN points to, ordered ascendingly.
+func existingMigrations(DSN ParsedDSN, col string, table string) ([]string, error) {
 	db, err := sql.Open("mysql", DSN.FormatDSN())
 	if err != nil {
 		return nil, err
 	}
+	defer db.Close()
 	rows, err := db.Query(fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", col, table, col))
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	migrations := []string{}
 	for rows.Next() {
@@ -122,3 +120,15 @@ func (m *MigrationsDiff) existingMigrations(DSN ParsedDSN, col string, table str
 	}
 	return migrations, nil
 }
+
+// splitMigrationsCol splits a migrations column given in the form
+// table.column into its parts. Both parts are empty if the column is
+// not qualified by its table.
+func splitMigrationsCol(migrationsCol string) (table, col string) {
+	parts := strings.Split(migrationsCol, ".")
+	if len(parts) == 2 {
+		table = parts[0]
+		col = parts[1]
+	}
+	return
+}
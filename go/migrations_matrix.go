@@ -0,0 +1,249 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// MigrationState denotes the state of a migration in a given server
+type MigrationState int
+
+// Possible states of a migration in a server. A migration is dirty when
+// the migrations table has a boolean dirty column (as golang-migrate does)
+// and the migration is flagged in it.
+const (
+	MigrationMissing MigrationState = iota
+	MigrationApplied
+	MigrationDirty
+)
+
+func (s MigrationState) String() string {
+	switch s {
+	case MigrationApplied:
+		return "applied"
+	case MigrationDirty:
+		return "dirty"
+	default:
+		return "missing"
+	}
+}
+
+// MigrationsMatrix represents the migrations recorded in the same schema
+// of N servers. Unlike MigrationsDiff, which only tells which migrations
+// are missing in either side of a two-sided diff, the matrix knows the
+// state of every migration in every server, so it can express that a
+// migration was applied in some servers but not in the rest.
+type MigrationsMatrix struct {
+	Schema        string
+	Table, Column string
+	Servers       []*ParsedDSN
+	// Versions is the union of the migrations found in all the servers,
+	// sorted ascendingly.
+	Versions []string
+	// States holds, for each version, its state in each of the servers,
+	// in the same order as Servers.
+	States map[string][]MigrationState
+}
+
+// NewMigrationsMatrix reads the migrations recorded in migrationsCol
+// (in the form table.column) of the given schema in each of the servers
+// denoted by DSNs, and builds a matrix with them.
+//
+// An error is returned if the migrations of any server cannot be read,
+// as a matrix with a missing column would report every migration as
+// missing in that server.
+func NewMigrationsMatrix(DSNs []string, schema string, migrationsCol string) (*MigrationsMatrix, error) {
+	table, col := splitMigrationsCol(migrationsCol)
+	m := &MigrationsMatrix{
+		Schema:  schema,
+		Table:   table,
+		Column:  col,
+		Servers: make([]*ParsedDSN, len(DSNs)),
+		States:  map[string][]MigrationState{},
+	}
+
+	for i, DSN := range DSNs {
+		parsed := ParseDSN(DSN)
+		m.Servers[i] = parsed
+
+		config := *parsed.Config
+		config.DBName = schema
+		dsn := ParsedDSN{&config}
+
+		applied, err := existingMigrations(dsn, col, table)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot retrieve migrations from %s.%s in %s/%s. Error: %s", table, col, dsn.Addr, dsn.DBName, err)
+		}
+		dirty, err := dirtyMigrations(dsn, col, table)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot retrieve dirty migrations from %s.%s in %s/%s. Error: %s", table, col, dsn.Addr, dsn.DBName, err)
+		}
+
+		for _, v := range applied {
+			m.state(v)[i] = MigrationApplied
+		}
+		for _, v := range dirty {
+			m.state(v)[i] = MigrationDirty
+		}
+	}
+
+	sort.Slice(m.Versions, func(i, j int) bool {
+		return versionLess(m.Versions[i], m.Versions[j])
+	})
+	return m, nil
+}
+
+// state returns the states of version v in each of the servers, registering
+// the version in the matrix if it wasn't before.
+func (m *MigrationsMatrix) state(v string) []MigrationState {
+	if _, ok := m.States[v]; !ok {
+		m.Versions = append(m.Versions, v)
+		m.States[v] = make([]MigrationState, len(m.Servers))
+	}
+	return m.States[v]
+}
+
+// AppliedEverywhere returns the versions that were cleanly applied in all the
+// servers of the matrix.
+func (m *MigrationsMatrix) AppliedEverywhere() []string {
+	versions := []string{}
+	for _, v := range m.Versions {
+		if m.appliedEverywhere(v) {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// Divergent returns the versions that are missing or dirty in any of the
+// servers of the matrix.
+func (m *MigrationsMatrix) Divergent() []string {
+	versions := []string{}
+	for _, v := range m.Versions {
+		if !m.appliedEverywhere(v) {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+func (m *MigrationsMatrix) appliedEverywhere(v string) bool {
+	for _, s := range m.States[v] {
+		if s != MigrationApplied {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the matrix in a human-readable way: one row per version
+// and one column per server. The versions applied everywhere are collapsed
+// into a single summary line.
+func (m *MigrationsMatrix) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("Migrations matrix for %s.%s in %s (%d servers):\n", m.Table, m.Column, m.Schema, len(m.Servers)))
+	for i, s := range m.Servers {
+		buf.WriteString(fmt.Sprintf("\t[%d] %s\n", i+1, s.Addr))
+	}
+
+	everywhere := m.AppliedEverywhere()
+	switch len(everywhere) {
+	case 0:
+	case 1:
+		buf.WriteString(fmt.Sprintf("\t- 1 migration applied everywhere (%s)\n", everywhere[0]))
+	default:
+		buf.WriteString(fmt.Sprintf("\t- %d migrations applied everywhere (%s to %s)\n", len(everywhere), everywhere[0], everywhere[len(everywhere)-1]))
+	}
+
+	divergent := m.Divergent()
+	if len(divergent) == 0 {
+		buf.WriteString("\t- No divergent migrations found\n")
+		return buf.String()
+	}
+
+	buf.WriteString(fmt.Sprintf("\t- %d divergent migrations:\n", len(divergent)))
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "\t\tversion")
+	for i := range m.Servers {
+		fmt.Fprintf(w, "\t[%d]", i+1)
+	}
+	fmt.Fprint(w, "\n")
+	for _, v := range divergent {
+		fmt.Fprintf(w, "\t\t%s", v)
+		for _, s := range m.States[v] {
+			fmt.Fprintf(w, "\t%s", s)
+		}
+		fmt.Fprint(w, "\n")
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// dirtyMigrations returns the values of col in table that are flagged as
+// dirty. Only tables with a dirty column, like the ones created by
+// golang-migrate, can have dirty migrations; for the rest an empty slice
+// is returned.
+func dirtyMigrations(DSN ParsedDSN, col string, table string) ([]string, error) {
+	db, err := sql.Open("mysql", DSN.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var hasDirty int
+	err = db.QueryRow(`
+		SELECT COUNT(*)
+		FROM   information_schema.columns
+		WHERE  table_schema = DATABASE() AND table_name = ? AND column_name = 'dirty'`, table).Scan(&hasDirty)
+	if err != nil {
+		return nil, err
+	}
+	if hasDirty == 0 {
+		return []string{}, nil
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT %s FROM %s WHERE dirty ORDER BY %s", col, table, col))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	migrations := []string{}
+	for rows.Next() {
+		var migration string
+		if err = rows.Scan(&migration); err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// versionLess compares two migration versions numerically when both of them
+// are numbers, as golang-migrate versions (1, 2, 10...) would be wrongly
+// sorted as strings, and lexicographically otherwise.
+func versionLess(a, b string) bool {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	if errA == nil && errB == nil {
+		return na < nb
+	}
+	return a < b
+}
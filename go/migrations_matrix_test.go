@@ -0,0 +1,72 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestMigrationsMatrix(t *testing.T) {
+	sql1 := []string{
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL,
+			dirty BOOLEAN NOT NULL,
+			PRIMARY KEY (version)
+		)  ENGINE=INNODB;`,
+		`INSERT INTO schema_migrations values (1, false);`,
+		`INSERT INTO schema_migrations values (2, false);`,
+		`INSERT INTO schema_migrations values (10, true);`,
+	}
+
+	sql2 := []string{
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL,
+			dirty BOOLEAN NOT NULL,
+			PRIMARY KEY (version)
+		)  ENGINE=INNODB;`,
+		`INSERT INTO schema_migrations values (1, false);`,
+		`INSERT INTO schema_migrations values (2, false);`,
+		`INSERT INTO schema_migrations values (3, false);`,
+	}
+
+	schema := fmt.Sprintf("matrix_%d", time.Now().UnixNano())
+	TestCluster.LoadSchemasAs(t, schema, schema, sql1, sql2)
+
+	matrix, err := NewMigrationsMatrix([]string{DSN1, DSN2}, schema, "schema_migrations.version")
+	Nil(t, err)
+	Equal(t, []string{"1", "2", "3", "10"}, matrix.Versions)
+	Equal(t, []string{"1", "2"}, matrix.AppliedEverywhere())
+	Equal(t, []string{"3", "10"}, matrix.Divergent())
+	Equal(t, []MigrationState{MigrationMissing, MigrationApplied}, matrix.States["3"])
+	Equal(t, []MigrationState{MigrationDirty, MigrationMissing}, matrix.States["10"])
+
+	out := matrix.String()
+	Regexp(t, "\\(2 servers\\)", out)
+	Regexp(t, "\t\\[1\\] 127.0.0.1:33060", out)
+	Regexp(t, "\t- 2 migrations applied everywhere \\(1 to 2\\)", out)
+	Regexp(t, "3\\s+missing\\s+applied", out)
+	Regexp(t, "10\\s+dirty\\s+missing", out)
+}
+
+func TestMigrationsMatrix_MissingTable(t *testing.T) {
+	schema := fmt.Sprintf("matrix_%d", time.Now().UnixNano())
+	TestCluster.LoadSchemasAs(t, schema, schema, []string{}, []string{})
+
+	_, err := NewMigrationsMatrix([]string{DSN1, DSN2}, schema, "schema_migrations.version")
+	Error(t, err)
+}
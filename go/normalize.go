@@ -0,0 +1,158 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// intDisplayWidth matches the display width of integer types, which MySQL
+// 8.0.19 and later don't show, and the width of the year type, which MySQL
+// 8.0 doesn't show either.
+var intDisplayWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint|year)\(\d+\)`)
+
+// normalizer aligns the definitions in the From schema of a diff to the
+// definitions in the To schema, whenever they are equivalent and only differ
+// because of the flavors of the servers they come from. For instance, int(11)
+// in MySQL 5.7 and int in MySQL 8.0, or utf8 and utf8mb3.
+//
+// Definitions are aligned to the To schema, so the statements generated to
+// amend the differences that remain are still correct for the To server.
+type normalizer struct {
+	from, to tengo.Flavor
+}
+
+// schema returns a copy of the from schema whose definitions are aligned
+// to the ones in the to schema.
+func (n normalizer) schema(from, to *tengo.Schema) *tengo.Schema {
+	res := *from
+	fromDefault := isDefaultCollation(n.from, from.CharSet, from.Collation)
+	toDefault := isDefaultCollation(n.to, to.CharSet, to.Collation)
+	if equivalentCharSets(from.CharSet, from.Collation, fromDefault, to.CharSet, to.Collation, toDefault) {
+		res.CharSet, res.Collation = to.CharSet, to.Collation
+	}
+
+	toTables := to.TablesByName()
+	res.Tables = make([]*tengo.Table, len(from.Tables))
+	for i, t := range from.Tables {
+		res.Tables[i] = t
+		if other, ok := toTables[t.Name]; ok && !t.UnsupportedDDL && !other.UnsupportedDDL {
+			res.Tables[i] = n.table(t, other)
+		}
+	}
+	return &res
+}
+
+// table returns table from, or a copy of it aligned to table to if any of
+// their definitions are equivalent but different.
+func (n normalizer) table(from, to *tengo.Table) *tengo.Table {
+	res := *from
+	if equivalentCharSets(from.CharSet, from.Collation, from.CollationIsDefault, to.CharSet, to.Collation, to.CollationIsDefault) {
+		res.CharSet, res.Collation, res.CollationIsDefault = to.CharSet, to.Collation, to.CollationIsDefault
+	}
+
+	toColumns := map[string]*tengo.Column{}
+	for _, c := range to.Columns {
+		toColumns[c.Name] = c
+	}
+	aligned := res.CharSet != from.CharSet || res.Collation != from.Collation
+	res.Columns = make([]*tengo.Column, len(from.Columns))
+	for i, c := range from.Columns {
+		res.Columns[i] = c
+		if other, ok := toColumns[c.Name]; ok {
+			if col := n.column(c, other); col != c {
+				res.Columns[i] = col
+				aligned = true
+			}
+		}
+	}
+	if !aligned {
+		return from
+	}
+
+	// The CREATE TABLE statements of both tables still differ, which tengo
+	// takes as an unsupported difference when no other one is found.
+	res.CreateStatement = ""
+	return &res
+}
+
+// column returns column from, or a copy of it aligned to column to if any
+// of their definitions are equivalent but different.
+func (n normalizer) column(from, to *tengo.Column) *tengo.Column {
+	res := *from
+	if from.TypeInDB != to.TypeInDB && withoutDisplayWidth(from.TypeInDB) == withoutDisplayWidth(to.TypeInDB) {
+		res.TypeInDB = to.TypeInDB
+	}
+	if equivalentCharSets(from.CharSet, from.Collation, from.CollationIsDefault, to.CharSet, to.Collation, to.CollationIsDefault) {
+		res.CharSet, res.Collation, res.CollationIsDefault = to.CharSet, to.Collation, to.CollationIsDefault
+	}
+	if isNullDefault(from.Default) && isNullDefault(to.Default) {
+		res.Default = to.Default
+	}
+	if res == *from {
+		return from
+	}
+	return &res
+}
+
+// withoutDisplayWidth returns the given column type without its display
+// width, unless the width is meaningful because the column is ZEROFILL.
+func withoutDisplayWidth(typ string) string {
+	if strings.Contains(typ, "zerofill") {
+		return typ
+	}
+	return intDisplayWidth.ReplaceAllString(typ, "$1")
+}
+
+// equivalentCharSets returns true if two character sets and collations are
+// the same, taking utf8mb3 as utf8, or if both collations are the default
+// ones for the character set in their respective servers.
+func equivalentCharSets(charSet1, collation1 string, default1 bool, charSet2, collation2 string, default2 bool) bool {
+	if canonicalCharSet(charSet1) != canonicalCharSet(charSet2) {
+		return false
+	}
+	return canonicalCharSet(collation1) == canonicalCharSet(collation2) || (default1 && default2)
+}
+
+// canonicalCharSet replaces utf8mb3, the name MySQL 8.0 gives to utf8, in the
+// name of a character set or collation.
+func canonicalCharSet(name string) string {
+	if name == "utf8mb3" || strings.HasPrefix(name, "utf8mb3_") {
+		return "utf8" + strings.TrimPrefix(name, "utf8mb3")
+	}
+	return name
+}
+
+// isDefaultCollation returns true if collation is the default one for the
+// character set in the given flavor. Only the character sets whose default
+// collation differs between flavors are considered.
+func isDefaultCollation(flavor tengo.Flavor, charSet, collation string) bool {
+	switch canonicalCharSet(charSet) {
+	case "utf8mb4":
+		return collation == flavor.DefaultUtf8mb4Collation()
+	case "utf8":
+		return canonicalCharSet(collation) == "utf8_general_ci"
+	}
+	return false
+}
+
+// isNullDefault returns true if the column default is NULL, either because
+// it's implicit, or because it was explicitly set as an expression, which is
+// the way MariaDB 10.2 and later show it.
+func isNullDefault(d tengo.ColumnDefault) bool {
+	return d.Null || (!d.Quoted && strings.EqualFold(d.Value, "NULL"))
+}
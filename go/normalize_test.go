@@ -0,0 +1,105 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+// mysql57And80Schemas returns the same schema as loaded from MySQL 5.7 and
+// MySQL 8.0, plus a column whose type really differs.
+func mysql57And80Schemas() (*tengo.Schema, *tengo.Schema) {
+	table57 := &tengo.Table{
+		Name: "tasks", Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_general_ci", CollationIsDefault: true,
+		Columns: []*tengo.Column{
+			{Name: "id", TypeInDB: "int(11)", Default: tengo.ColumnDefaultNull},
+			{Name: "title", TypeInDB: "varchar(255)", CharSet: "utf8mb4", Collation: "utf8mb4_general_ci", CollationIsDefault: true, Nullable: true, Default: tengo.ColumnDefaultNull},
+			{Name: "slug", TypeInDB: "varchar(255)", CharSet: "utf8", Collation: "utf8_bin", Nullable: true, Default: tengo.ColumnDefaultNull},
+			{Name: "position", TypeInDB: "int(11)", Default: tengo.ColumnDefaultNull},
+		},
+		CreateStatement: "CREATE TABLE `tasks` (...) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+	}
+	table80 := &tengo.Table{
+		Name: "tasks", Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true,
+		Columns: []*tengo.Column{
+			{Name: "id", TypeInDB: "int", Default: tengo.ColumnDefaultNull},
+			{Name: "title", TypeInDB: "varchar(255)", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true, Nullable: true, Default: tengo.ColumnDefaultExpression("NULL")},
+			{Name: "slug", TypeInDB: "varchar(255)", CharSet: "utf8mb3", Collation: "utf8mb3_bin", Nullable: true, Default: tengo.ColumnDefaultNull},
+			{Name: "position", TypeInDB: "bigint", Default: tengo.ColumnDefaultNull},
+		},
+		CreateStatement: "CREATE TABLE `tasks` (...) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci",
+	}
+	schema57 := &tengo.Schema{Name: "tasks", CharSet: "utf8mb4", Collation: "utf8mb4_general_ci", Tables: []*tengo.Table{table57}}
+	schema80 := &tengo.Schema{Name: "tasks", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", Tables: []*tengo.Table{table80}}
+	return schema57, schema80
+}
+
+func TestDiff_Normalization(t *testing.T) {
+	from, to := mysql57And80Schemas()
+	diff := &Diff{
+		DSN1:     ParseDSN(DSN1),
+		DSN2:     ParseDSN(DSN2),
+		From:     from,
+		To:       to,
+		Flavor1:  tengo.FlavorMySQL57,
+		Flavor2:  tengo.FlavorMySQL80,
+		objects1: &Objects{},
+		objects2: &Objects{},
+	}
+
+	changes := diff.Changes()
+	Equal(t, 1, len(changes))
+	Equal(t, "modify_column:tasks.position", changes[0].ID)
+	Equal(t, "`position` int(11) NOT NULL", changes[0].From)
+	Equal(t, "`position` bigint NOT NULL", changes[0].To)
+
+	sqlFmt, _ := NewFormatter("sql")
+	Equal(t, "-- Severity: additive\nALTER TABLE `tasks` MODIFY COLUMN `position` bigint NOT NULL;\n", sqlFmt.Format(diff))
+
+	diff = &Diff{
+		DSN1:     ParseDSN(DSN1),
+		DSN2:     ParseDSN(DSN2),
+		From:     from,
+		To:       to,
+		Flavor1:  tengo.FlavorMySQL57,
+		Flavor2:  tengo.FlavorMySQL80,
+		Strict:   true,
+		objects1: &Objects{},
+		objects2: &Objects{},
+	}
+	ids := []string{}
+	for _, c := range diff.Changes() {
+		ids = append(ids, c.ID)
+	}
+	Equal(t, []string{
+		"alter_database:tasks",
+		"change_charset:tasks",
+		"modify_column:tasks.id",
+		"modify_column:tasks.title",
+		"modify_column:tasks.slug",
+		"modify_column:tasks.position",
+	}, ids)
+}
+
+func TestWithoutDisplayWidth(t *testing.T) {
+	Equal(t, "int", withoutDisplayWidth("int(11)"))
+	Equal(t, "bigint unsigned", withoutDisplayWidth("bigint(20) unsigned"))
+	Equal(t, "year", withoutDisplayWidth("year(4)"))
+	Equal(t, "int(5) unsigned zerofill", withoutDisplayWidth("int(5) unsigned zerofill"))
+	Equal(t, "varchar(11)", withoutDisplayWidth("varchar(11)"))
+	Equal(t, "decimal(10,2)", withoutDisplayWidth("decimal(10,2)"))
+}
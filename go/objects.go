@@ -0,0 +1,88 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// Objects holds the objects of a schema that tengo.Schema doesn't load,
+// and mydiff introspects by itself.
+type Objects struct {
+	Triggers []*Trigger
+	Views    []*View
+	Events   []*Event
+}
+
+// LoadObjects introspects the objects of the given schema that tengo.Schema
+// doesn't load, in the server denoted by DSN.
+func LoadObjects(DSN *ParsedDSN, schema string) (*Objects, error) {
+	db, err := sql.Open("mysql", DSN.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	triggers, err := loadTriggers(db, schema)
+	if err != nil {
+		return nil, err
+	}
+	views, err := loadViews(db, schema)
+	if err != nil {
+		return nil, err
+	}
+	events, err := loadEvents(db, schema)
+	if err != nil {
+		return nil, err
+	}
+	return &Objects{Triggers: triggers, Views: views, Events: events}, nil
+}
+
+// objects returns the objects of the From and To schemas that tengo doesn't
+// load. They are introspected the first time they are needed.
+func (d *Diff) objects() (from, to *Objects, err error) {
+	if d.objects1 == nil {
+		if d.objects1, err = loadObjects(d.DSN1, d.From.Name); err != nil {
+			return nil, nil, err
+		}
+	}
+	if d.objects2 == nil {
+		if d.objects2, err = loadObjects(d.DSN2, d.To.Name); err != nil {
+			return nil, nil, err
+		}
+	}
+	return d.objects1, d.objects2, nil
+}
+
+func loadObjects(DSN *ParsedDSN, schema string) (*Objects, error) {
+	objects, err := LoadObjects(DSN, schema)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot introspect the triggers, views and events of %s in %s. Error: %s", schema, DSN.Addr, err)
+	}
+	return objects, nil
+}
+
+// definerClause returns the DEFINER clause of a CREATE statement for the
+// given definer, as reported by information_schema (user@host)
+func definerClause(definer string) string {
+	i := strings.LastIndex(definer, "@")
+	if i < 0 {
+		return fmt.Sprintf("DEFINER=%s", tengo.EscapeIdentifier(definer))
+	}
+	return fmt.Sprintf("DEFINER=%s@%s", tengo.EscapeIdentifier(definer[:i]), tengo.EscapeIdentifier(definer[i+1:]))
+}
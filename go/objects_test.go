@@ -0,0 +1,46 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestLoadObjects(t *testing.T) {
+	sql := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			done TINYINT NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE ALGORITHM=MERGE VIEW done_tasks AS SELECT id FROM tasks WHERE done = 1;`,
+	}
+
+	s1Name, _ := TestCluster.LoadSchemas(t, sql, sql)
+	objects, err := LoadObjects(ParseDSN(DSN1), s1Name)
+	Nil(t, err)
+	Equal(t, 1, len(objects.Views))
+	Equal(t, "done_tasks", objects.Views[0].Name)
+	Equal(t, "MERGE", objects.Views[0].Algorithm)
+}
+
+func TestDiff_Err(t *testing.T) {
+	schema := &tengo.Schema{Name: "shop"}
+	diff := &Diff{DSN1: ParseDSN("root@tcp(127.0.0.1:1)/"), DSN2: ParseDSN(DSN2), From: schema, To: schema}
+	Error(t, diff.Err())
+	Empty(t, diff.Changes())
+}
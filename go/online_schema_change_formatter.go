@@ -0,0 +1,325 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// Online schema change tools supported by OnlineSchemaChangeFormatter
+const (
+	GhOst = "gh-ost"
+	PtOSC = "pt-osc"
+)
+
+// DefaultOnlineThreshold is the size of the tables, 1GiB, from which an
+// OnlineSchemaChangeFormatter alters them with an online schema change tool.
+const DefaultOnlineThreshold int64 = 1 << 30
+
+// OnlineSchemaChangeFormatter formats a diff as a shell script that turns the
+// schema in the first server of the diff into the second one.
+//
+// The tables of Threshold bytes or more in the first server, as told by
+// TableSize, are altered with an invocation of the online schema change
+// tool, gh-ost or pt-online-schema-change, given the clauses of the ALTER
+// TABLE statement (see tengo.TableDiff.Clauses), or the ones changing their
+// partitioning. The other statements are run by the mysql client. Tables
+// whose size cannot be told are taken as large, so they never get a blocking
+// ALTER TABLE.
+//
+// The formatters in AvailableFormatters have no TableSize, so every table is
+// taken as large. Build one with the TableSize of the first server instead,
+// i.e. tengo.Instance.TableSize.
+type OnlineSchemaChangeFormatter struct {
+	Tool      string
+	Threshold int64
+	// TableSize returns the size of a table in the first server of the diff
+	TableSize func(schema, table string) (int64, error)
+}
+
+// onlineCommands are the commands of each online schema change tool altering
+// a table with the given clauses, in the server of a DSN. renames tells if
+// any of the clauses renames a column, which the tools refuse to do unless
+// told to.
+var onlineCommands = map[string]func(DSN *ParsedDSN, schema, table, clauses string, renames bool) []string{
+	GhOst: func(DSN *ParsedDSN, schema, table, clauses string, renames bool) []string {
+		args := []string{"gh-ost"}
+		if host, port, socket := dsnConnection(DSN); socket == "" {
+			args = append(args, "--host="+shellQuote(host), "--port="+port)
+		}
+		args = append(args, "--user="+shellQuote(DSN.User))
+		if DSN.Passwd != "" {
+			args = append(args, "--ask-pass")
+		}
+		args = append(args, "--database="+shellQuote(schema), "--table="+shellQuote(table), "--alter="+shellQuote(clauses))
+		if renames {
+			args = append(args, "--approve-renamed-columns")
+		}
+		return append(args, "--execute")
+	},
+	PtOSC: func(DSN *ParsedDSN, schema, table, clauses string, renames bool) []string {
+		args := []string{"pt-online-schema-change", "--alter " + shellQuote(clauses)}
+		if DSN.Passwd != "" {
+			args = append(args, "--ask-pass")
+		}
+		if renames {
+			args = append(args, "--no-check-alter")
+		}
+		dsn := []string{"D=" + schema, "t=" + table}
+		if host, port, socket := dsnConnection(DSN); socket != "" {
+			dsn = append(dsn, "S="+socket)
+		} else {
+			dsn = append(dsn, "h="+host, "P="+port)
+		}
+		dsn = append(dsn, "u="+DSN.User)
+		return append(args, "--execute", shellQuote(strings.Join(dsn, ",")))
+	},
+}
+
+// Format returns a string with the diff as a shell script
+func (f *OnlineSchemaChangeFormatter) Format(diff *Diff) interface{} {
+	var buf bytes.Buffer
+	command, ok := onlineCommands[f.Tool]
+	if !ok {
+		log.Errorf("Unknown online schema change tool %s", f.Tool)
+		return ""
+	}
+	if f.TableSize == nil {
+		log.Warningf("The sizes of the tables in %s are unknown, taking all of them as large", diff.DSN1.Addr)
+	}
+	mods := tengo.StatementModifiers{IgnoreTable: diff.Filter.IgnoreTable(), StrictForeignKeyNaming: diff.StrictForeignKeyNaming, Flavor: diff.Flavor1}
+
+	buf.WriteString("#!/bin/sh\n")
+	buf.WriteString(fmt.Sprintf("# Differences between `%s` in %s and `%s` in %s\n", diff.From.Name, diff.DSN1.Addr, diff.To.Name, diff.DSN2.Addr))
+	buf.WriteString(fmt.Sprintf("# Tables of %s or more are altered with %s, and the other statements are run by the mysql client\n", byteSize(f.Threshold), f.Tool))
+	for _, w := range diff.Warnings() {
+		buf.WriteString(fmt.Sprintf("# Warning: %s\n", w))
+	}
+	buf.WriteString("set -e\n")
+
+	ods := diff.Compute()
+	// Renamed tables are altered after being renamed, but they have their
+	// former name when their size is computed.
+	formerNames := map[string]string{}
+	for _, od := range ods {
+		if rtd, ok := od.(*RenameTableDiff); ok {
+			formerNames[rtd.To.Name] = rtd.From.Name
+		}
+	}
+	size := func(table string) (int64, bool) {
+		if f.TableSize == nil {
+			return 0, false
+		}
+		if former, ok := formerNames[table]; ok {
+			table = former
+		}
+		size, err := f.TableSize(diff.From.Name, table)
+		if err != nil {
+			log.Warningf("Cannot compute the size of table %s in %s, taking it as large. Error: %s", table, diff.DSN1.Addr, err)
+			return 0, false
+		}
+		return size, true
+	}
+
+	// The statements run by the mysql client are gathered until a table is
+	// altered with the online schema change tool, so they are run in order.
+	var pending bytes.Buffer
+	flush := func() {
+		if pending.Len() == 0 {
+			return
+		}
+		buf.WriteString(fmt.Sprintf("\n%s <<'SQL'\n%sSQL\n", strings.Join(mysqlCommand(diff.DSN1, diff.From.Name), " "), pending.String()))
+		pending.Reset()
+	}
+	for _, od := range ods {
+		if _, ok := od.(*MigrationsDiff); ok {
+			continue
+		}
+		switch od := od.(type) {
+		case *TableDiff:
+			if od.DiffType() != tengo.DiffTypeAlter {
+				break
+			}
+			clauses, _ := od.Clauses(mods)
+			if clauses == "" {
+				continue
+			}
+			n, known := size(od.From.Name)
+			if known && n < f.Threshold {
+				break
+			}
+			flush()
+			buf.WriteString(fmt.Sprintf("\n# table %s (%s): %s\n", od.From.Name, describedSize(n, known), describedChanges(od)))
+			for _, w := range f.toolWarnings(od, diff.From) {
+				buf.WriteString(fmt.Sprintf("# Warning: %s\n", w))
+			}
+			buf.WriteString(strings.Join(command(diff.DSN1, diff.From.Name, od.From.Name, clauses, renamesColumns(od)), " \\\n  ") + "\n")
+			continue
+		case *PartitionDiff:
+			if stmt, _ := od.Statement(mods); stmt == "" {
+				continue
+			}
+			n, known := size(od.Table)
+			if known && n < f.Threshold {
+				break
+			}
+			flush()
+			buf.WriteString(fmt.Sprintf("\n# table %s (%s): %s\n", od.Table, describedSize(n, known), describedChanges(od)))
+			buf.WriteString(strings.Join(command(diff.DSN1, diff.From.Name, od.Table, od.clause(), false), " \\\n  ") + "\n")
+			continue
+		}
+		if stmts := statements(od, mods); len(stmts) > 0 {
+			pending.WriteString(severityComment(objectSeverity(od)))
+			for _, stmt := range stmts {
+				pending.WriteString(delimitedStatement(od, stmt))
+			}
+		}
+	}
+	flush()
+	return buf.String()
+}
+
+// toolWarnings returns the warnings about altering a table of the given
+// schema with the online schema change tool, as both of them have
+// restrictions on foreign keys.
+func (f *OnlineSchemaChangeFormatter) toolWarnings(td *TableDiff, schema *tengo.Schema) []string {
+	var referenced bool
+	for _, t := range schema.Tables {
+		for _, fk := range t.ForeignKeys {
+			if fk.ReferencedSchemaName == "" && fk.ReferencedTableName == td.From.Name {
+				referenced = true
+			}
+		}
+	}
+	var res []string
+	switch {
+	case f.Tool == GhOst && (referenced || len(td.From.ForeignKeys) > 0 || len(td.To.ForeignKeys) > 0):
+		res = append(res, fmt.Sprintf("gh-ost does not support foreign keys, and table %s has or is referenced by some of them", td.From.Name))
+	case f.Tool == PtOSC && referenced:
+		res = append(res, fmt.Sprintf("pt-online-schema-change needs --alter-foreign-keys-method, as table %s is referenced by foreign keys", td.From.Name))
+	}
+	return res
+}
+
+// renamesColumns returns true if any of the clauses of a table diff renames
+// a column.
+func renamesColumns(td *TableDiff) bool {
+	for _, c := range td.AlterClauses() {
+		if _, ok := c.(RenameColumn); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// describedChanges describes the changes of an object diff, i.e. add column
+// due_at (additive), drop index owner_index (risky)
+func describedChanges(od tengo.ObjectDiff) string {
+	var res []string
+	for _, c := range changes([]tengo.ObjectDiff{od}) {
+		res = append(res, fmt.Sprintf("%s (%s)", c.description(), c.Severity))
+	}
+	return strings.Join(res, ", ")
+}
+
+// describedSize describes the size of a table, which may be unknown
+func describedSize(size int64, known bool) string {
+	if !known {
+		return "unknown size"
+	}
+	return byteSize(size)
+}
+
+// mysqlCommand returns the command of the mysql client connecting to the
+// server of a DSN, using the given schema.
+func mysqlCommand(DSN *ParsedDSN, schema string) []string {
+	args := []string{"mysql"}
+	if host, port, socket := dsnConnection(DSN); socket != "" {
+		args = append(args, "--socket="+shellQuote(socket))
+	} else {
+		args = append(args, "--host="+shellQuote(host), "--port="+port)
+	}
+	args = append(args, "--user="+shellQuote(DSN.User))
+	if DSN.Passwd != "" {
+		args = append(args, "--password")
+	}
+	return append(args, shellQuote(schema))
+}
+
+// dsnConnection returns the host and port of the server of a DSN, or its
+// socket if it's connected to through a unix socket. Passwords are never
+// given to the commands, which ask for them.
+func dsnConnection(DSN *ParsedDSN) (string, string, string) {
+	if DSN.Net == "unix" {
+		return "", "", DSN.Addr
+	}
+	host, port, err := net.SplitHostPort(DSN.Addr)
+	if err != nil {
+		return DSN.Addr, "3306", ""
+	}
+	return host, port, ""
+}
+
+// shellSafe matches the arguments that need no quotes in a shell
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./:=@,+-]+$`)
+
+// shellQuote returns a shell word for s, single quoted unless it's safe
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// byteUnits are the units of sizes, each 1024 times the previous one
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB"}
+
+// byteSize returns a size in the largest unit it has one of, i.e. 1.5 GiB
+func byteSize(size int64) string {
+	value, unit := float64(size), 0
+	for value >= 1024 && unit < len(byteUnits)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%s %s", strings.TrimSuffix(strconv.FormatFloat(value, 'f', 1, 64), ".0"), byteUnits[unit])
+}
+
+// byteSizeFormat matches sizes given as a number of bytes, or of some unit,
+// i.e. 512M, 512MB or 512MiB
+var byteSizeFormat = regexp.MustCompile(`(?i)^\s*(\d+)\s*([KMGT]?)(I?B)?\s*$`)
+
+// ParseByteSize parses a size in bytes, which may be given in kibibytes,
+// mebibytes, gibibytes or tebibytes with a suffix, i.e. 512M or 1GiB.
+func ParseByteSize(s string) (int64, error) {
+	m := byteSizeFormat.FindStringSubmatch(s)
+	if m == nil || (m[2] == "" && len(m[3]) > 1) {
+		return 0, fmt.Errorf("Invalid size %s, expected a number of bytes optionally followed by K, M, G or T", s)
+	}
+	res, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid size %s: %s", s, err)
+	}
+	if m[2] != "" {
+		res <<= 10 * uint(strings.Index("KMGT", strings.ToUpper(m[2]))+1)
+	}
+	return res, nil
+}
@@ -0,0 +1,156 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestOnlineSchemaChangeFormatter_Format(t *testing.T) {
+	id := &tengo.Column{Name: "id", TypeInDB: "int", AutoIncrement: true}
+	title := &tengo.Column{Name: "title", TypeInDB: "varchar(255)", Nullable: true, Default: tengo.ColumnDefaultNull, CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true}
+	dueAt := &tengo.Column{Name: "due_at", TypeInDB: "datetime(6)", Nullable: true, Default: tengo.ColumnDefaultNull}
+	table := func(name string, columns ...*tengo.Column) *tengo.Table {
+		table := &tengo.Table{Name: name, Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true, Columns: columns}
+		table.PrimaryKey = &tengo.Index{Name: "PRIMARY", Columns: []*tengo.Column{id}, SubParts: []uint16{0}, PrimaryKey: true, Unique: true}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorMySQL80)
+		return table
+	}
+	diff := &Diff{
+		DSN1:     ParseDSN(DSN1),
+		DSN2:     ParseDSN(DSN2),
+		From:     &tengo.Schema{Name: "shop", Tables: []*tengo.Table{table("lists", id, title), table("tasks", id, title)}},
+		To:       &tengo.Schema{Name: "shop", Tables: []*tengo.Table{table("lists", id, title, dueAt), table("tasks", id, title, dueAt)}},
+		Flavor1:  tengo.FlavorMySQL80,
+		Flavor2:  tengo.FlavorMySQL80,
+		objects1: &Objects{},
+		objects2: &Objects{},
+	}
+	sizes := map[string]int64{"lists": 16 << 10, "tasks": 3 << 29}
+	tableSize := func(schema, table string) (int64, error) {
+		if size, ok := sizes[table]; ok && schema == "shop" {
+			return size, nil
+		}
+		return 0, sql.ErrNoRows
+	}
+	header := "#!/bin/sh\n" +
+		"# Differences between `shop` in 127.0.0.1:33060 and `shop` in 127.0.0.1:33062\n"
+	mysql := "\n" +
+		"mysql --host=127.0.0.1 --port=33060 --user=root shop <<'SQL'\n" +
+		"-- Severity: additive\n" +
+		"ALTER TABLE `lists` ADD COLUMN `due_at` datetime(6) DEFAULT NULL;\n" +
+		"SQL\n"
+
+	// Tables are diffed in no particular order, so each command is looked for
+	t.Run("gh-ost", func(t *testing.T) {
+		f := &OnlineSchemaChangeFormatter{Tool: GhOst, Threshold: DefaultOnlineThreshold, TableSize: tableSize}
+		script := f.Format(diff).(string)
+		True(t, strings.HasPrefix(script, header+
+			"# Tables of 1 GiB or more are altered with gh-ost, and the other statements are run by the mysql client\n"+
+			"set -e\n"), script)
+		Contains(t, script, mysql)
+		Contains(t, script, "\n"+
+			"# table tasks (1.5 GiB): add column due_at (additive)\n"+
+			"gh-ost \\\n"+
+			"  --host=127.0.0.1 \\\n"+
+			"  --port=33060 \\\n"+
+			"  --user=root \\\n"+
+			"  --database=shop \\\n"+
+			"  --table=tasks \\\n"+
+			"  --alter='ADD COLUMN `due_at` datetime(6) DEFAULT NULL' \\\n"+
+			"  --execute\n")
+	})
+
+	t.Run("pt-osc", func(t *testing.T) {
+		f := &OnlineSchemaChangeFormatter{Tool: PtOSC, Threshold: 1 << 20, TableSize: tableSize}
+		script := f.Format(diff).(string)
+		True(t, strings.HasPrefix(script, header+
+			"# Tables of 1 MiB or more are altered with pt-osc, and the other statements are run by the mysql client\n"+
+			"set -e\n"), script)
+		Contains(t, script, mysql)
+		Contains(t, script, "\n"+
+			"# table tasks (1.5 GiB): add column due_at (additive)\n"+
+			"pt-online-schema-change \\\n"+
+			"  --alter 'ADD COLUMN `due_at` datetime(6) DEFAULT NULL' \\\n"+
+			"  --execute \\\n"+
+			"  D=shop,t=tasks,h=127.0.0.1,P=33060,u=root\n")
+	})
+
+	t.Run("Unknown size", func(t *testing.T) {
+		f := &OnlineSchemaChangeFormatter{Tool: GhOst, Threshold: DefaultOnlineThreshold, TableSize: func(string, string) (int64, error) { return 0, sql.ErrConnDone }}
+		Contains(t, f.Format(diff), "# table lists (unknown size): add column due_at (additive)\ngh-ost \\\n")
+
+		f = &OnlineSchemaChangeFormatter{Tool: GhOst, Threshold: DefaultOnlineThreshold}
+		Contains(t, f.Format(diff), "# table lists (unknown size): add column due_at (additive)\ngh-ost \\\n")
+	})
+
+	t.Run("Partitioning", func(t *testing.T) {
+		partitioned := func(partitions int) *tengo.Table {
+			table := table("events", id)
+			table.CreateStatement += fmt.Sprintf("\n/*!50100 PARTITION BY HASH (`id`)\nPARTITIONS %d */", partitions)
+			table.UnsupportedDDL = true
+			return table
+		}
+		diff := &Diff{
+			DSN1:     ParseDSN(DSN1),
+			DSN2:     ParseDSN(DSN2),
+			From:     &tengo.Schema{Name: "shop", Tables: []*tengo.Table{partitioned(4)}},
+			To:       &tengo.Schema{Name: "shop", Tables: []*tengo.Table{partitioned(8)}},
+			Flavor1:  tengo.FlavorMySQL80,
+			Flavor2:  tengo.FlavorMySQL80,
+			objects1: &Objects{},
+			objects2: &Objects{},
+		}
+		sizes["events"] = 2 << 30
+		f := &OnlineSchemaChangeFormatter{Tool: PtOSC, Threshold: DefaultOnlineThreshold, TableSize: tableSize}
+		script := f.Format(diff).(string)
+		Contains(t, script, "\n"+
+			"pt-online-schema-change \\\n"+
+			"  --alter 'ADD PARTITION PARTITIONS 4' \\\n"+
+			"  --execute \\\n"+
+			"  D=shop,t=events,h=127.0.0.1,P=33060,u=root\n")
+		NotContains(t, script, "<<'SQL'")
+
+		sizes["events"] = 16 << 10
+		Contains(t, f.Format(diff), "ALTER TABLE `events` ADD PARTITION PARTITIONS 4;\n")
+	})
+}
+
+func TestParseByteSize(t *testing.T) {
+	for s, expected := range map[string]int64{
+		"1024":  1024,
+		"512B":  512,
+		"16k":   16 << 10,
+		"512M":  512 << 20,
+		"512MB": 512 << 20,
+		"1GiB":  1 << 30,
+		" 2 T ": 2 << 40,
+	} {
+		size, err := ParseByteSize(s)
+		Nil(t, err, s)
+		Equal(t, expected, size, s)
+	}
+	for _, s := range []string{"", "1.5G", "1iB", "-1", "1P"} {
+		_, err := ParseByteSize(s)
+		Error(t, err, s)
+	}
+	Equal(t, "1.5 GiB", byteSize(3<<29))
+	Equal(t, "100 B", byteSize(100))
+}
@@ -0,0 +1,331 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// ObjectTypePartition is the object type of partitions, which tengo doesn't
+// define
+const ObjectTypePartition tengo.ObjectType = "partition"
+
+// partitionClause matches the partitioning clause at the end of the output
+// of SHOW CREATE TABLE, which MySQL wraps in a version comment.
+var partitionClause = regexp.MustCompile(`(?s)\n(/\*!\d+ )?\s*(PARTITION BY .*?)( \*/)?$`)
+
+// partitionCount matches the number of partitions of tables partitioned by
+// HASH or KEY, whose partitions are not listed.
+var partitionCount = regexp.MustCompile(`(?m)^PARTITIONS (\d+)$`)
+
+// Partitioning is the partitioning scheme of a table, which tengo doesn't
+// support.
+type Partitioning struct {
+	// Method is the PARTITION BY clause, including subpartitioning, without
+	// the number of partitions nor the list of them.
+	Method     string
+	Count      int
+	Partitions []*Partition
+}
+
+// Partition is a partition in the list of partitions of a table
+type Partition struct {
+	Name string
+	// Definition is the definition of the partition in the list, like
+	// PARTITION p2019 VALUES LESS THAN (2020) ENGINE = InnoDB
+	Definition string
+}
+
+// String returns the partitioning clause of a CREATE or ALTER TABLE statement
+func (p *Partitioning) String() string {
+	clause := p.Method
+	if p.Count > 0 {
+		clause += fmt.Sprintf(" PARTITIONS %d", p.Count)
+	}
+	if len(p.Partitions) > 0 {
+		clause += fmt.Sprintf(" (%s)", partitionDefinitions(p.Partitions))
+	}
+	return clause
+}
+
+// parsePartitioning returns the partitioning of a table given its CREATE
+// TABLE statement, and the statement without it. The partitioning is nil if
+// the table is not partitioned.
+func parsePartitioning(createStatement string) (*Partitioning, string) {
+	m := partitionClause.FindStringSubmatchIndex(createStatement)
+	if m == nil {
+		return nil, createStatement
+	}
+	clause := createStatement[m[4]:m[5]]
+	p := &Partitioning{}
+	header, list := clause, ""
+	if i := strings.Index(clause, "\n("); i >= 0 {
+		header, list = clause[:i], clause[i+1:]
+	}
+	if c := partitionCount.FindStringSubmatch(header); c != nil {
+		p.Count, _ = strconv.Atoi(c[1])
+		header = partitionCount.ReplaceAllString(header, "")
+	}
+	p.Method = strings.Join(strings.Fields(header), " ")
+	if list != "" {
+		for _, def := range splitTopLevel(list[1 : len(list)-1]) {
+			def = strings.Join(strings.Fields(def), " ")
+			name := strings.Trim(strings.Fields(def)[1], "`")
+			p.Partitions = append(p.Partitions, &Partition{Name: name, Definition: def})
+		}
+	}
+	return p, createStatement[:m[0]]
+}
+
+// splitTopLevel splits s by the commas that are not enclosed in parentheses
+// or quotes.
+func splitTopLevel(s string) []string {
+	var res []string
+	var quote rune
+	depth, start := 0, 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			res = append(res, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(res, s[start:])
+}
+
+// comparePartitioning returns the diffs of the partitioning of a table. Each
+// diff corresponds to a single ALTER TABLE statement, as partitioning
+// operations cannot be combined with other alterations.
+//
+// Partitions by RANGE can only be added at the end of the list, so the ones
+// added elsewhere are split from the partition following them.
+func comparePartitioning(table string, from, to *Partitioning) []tengo.ObjectDiff {
+	switch {
+	case from == nil && to == nil:
+		return nil
+	case from == nil || to == nil || from.Method != to.Method:
+		return []tengo.ObjectDiff{&PartitionDiff{Table: table, From: from, To: to}}
+	case from.Count != to.Count:
+		return []tengo.ObjectDiff{&PartitionDiff{Table: table, From: from, To: to}}
+	case !from.ranged():
+		return compareListedPartitions(table, from, to)
+	}
+
+	// Partitions that are identical in both lists are kept, and the
+	// partitions between them are dropped, added or reorganized.
+	toByName := map[string]*Partition{}
+	for _, p := range to.Partitions {
+		toByName[p.Name] = p
+	}
+	kept := map[string]bool{}
+	for _, p := range from.Partitions {
+		if other, ok := toByName[p.Name]; ok && other.Definition == p.Definition {
+			kept[p.Name] = true
+		}
+	}
+
+	var res []tengo.ObjectDiff
+	var dropped, added []*Partition
+	i := 0
+	for _, p := range to.Partitions {
+		if !kept[p.Name] {
+			added = append(added, p)
+			continue
+		}
+		for ; i < len(from.Partitions) && from.Partitions[i].Name != p.Name; i++ {
+			dropped = append(dropped, from.Partitions[i])
+		}
+		i++
+		if len(dropped) == 0 && len(added) > 0 {
+			// Partitions can only be added at the end of the list, so
+			// new partitions before p are split from it.
+			dropped, added = []*Partition{p}, append(added, p)
+		}
+		if len(dropped) > 0 || len(added) > 0 {
+			res = append(res, &PartitionDiff{Table: table, From: from, To: to, Dropped: dropped, Added: added})
+		}
+		dropped, added = nil, nil
+	}
+	if i < len(from.Partitions) {
+		dropped = append(dropped, from.Partitions[i:]...)
+	}
+	if len(dropped) > 0 || len(added) > 0 {
+		res = append(res, &PartitionDiff{Table: table, From: from, To: to, Dropped: dropped, Added: added})
+	}
+	return res
+}
+
+// compareListedPartitions returns the diffs of the partitions of a table
+// partitioned by a method other than RANGE, like LIST, whose partitions are
+// not ordered. The partitions only in From are dropped, the ones differing
+// in both lists are reorganized, and the ones only in To are added, in
+// that order, so the values of the dropped partitions can be reused.
+func compareListedPartitions(table string, from, to *Partitioning) []tengo.ObjectDiff {
+	fromByName := map[string]*Partition{}
+	for _, p := range from.Partitions {
+		fromByName[p.Name] = p
+	}
+	toByName := map[string]*Partition{}
+	for _, p := range to.Partitions {
+		toByName[p.Name] = p
+	}
+	var dropped, modified, reorganized, added []*Partition
+	for _, p := range from.Partitions {
+		if other, ok := toByName[p.Name]; !ok {
+			dropped = append(dropped, p)
+		} else if other.Definition != p.Definition {
+			modified, reorganized = append(modified, p), append(reorganized, other)
+		}
+	}
+	for _, p := range to.Partitions {
+		if _, ok := fromByName[p.Name]; !ok {
+			added = append(added, p)
+		}
+	}
+
+	var res []tengo.ObjectDiff
+	if len(dropped) > 0 {
+		res = append(res, &PartitionDiff{Table: table, From: from, To: to, Dropped: dropped})
+	}
+	if len(modified) > 0 {
+		res = append(res, &PartitionDiff{Table: table, From: from, To: to, Dropped: modified, Added: reorganized})
+	}
+	if len(added) > 0 {
+		res = append(res, &PartitionDiff{Table: table, From: from, To: to, Added: added})
+	}
+	return res
+}
+
+// ranged returns true if the table is partitioned by RANGE or RANGE COLUMNS,
+// whose partitions are ordered by the values they hold.
+func (p *Partitioning) ranged() bool {
+	return strings.HasPrefix(p.Method, "PARTITION BY RANGE")
+}
+
+// PartitionDiff is an implementation of tengo.ObjectDiff representing a
+// change to the partitioning of a table, made by a single ALTER TABLE
+// statement:
+//   - From is nil: the table is partitioned
+//   - To is nil: the table is no longer partitioned
+//   - The partitioning method differs: the table is partitioned again
+//   - The number of partitions differs: partitions are added or coalesced
+//   - Otherwise: the Dropped partitions of From are replaced by the Added
+//     partitions of To
+type PartitionDiff struct {
+	Table          string
+	From, To       *Partitioning
+	Dropped, Added []*Partition
+}
+
+// DiffType (see tengo.ObjectDiff)
+func (pd *PartitionDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey (see tengo.ObjectDiff)
+func (pd *PartitionDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: pd.Table}
+}
+
+// Statement (see tengo.ObjectDiff)
+func (pd *PartitionDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(pd.Table) {
+		return "", nil
+	}
+	return fmt.Sprintf("ALTER TABLE %s %s", tengo.EscapeIdentifier(pd.Table), pd.clause()), nil
+}
+
+func (pd *PartitionDiff) clause() string {
+	switch {
+	case pd.To == nil:
+		return "REMOVE PARTITIONING"
+	case pd.From == nil || pd.From.Method != pd.To.Method:
+		return pd.To.String()
+	case pd.From.Count < pd.To.Count:
+		return fmt.Sprintf("ADD PARTITION PARTITIONS %d", pd.To.Count-pd.From.Count)
+	case pd.From.Count > pd.To.Count:
+		return fmt.Sprintf("COALESCE PARTITION %d", pd.From.Count-pd.To.Count)
+	case len(pd.Dropped) == 0:
+		return fmt.Sprintf("ADD PARTITION (%s)", partitionDefinitions(pd.Added))
+	case len(pd.Added) == 0:
+		return fmt.Sprintf("DROP PARTITION %s", partitionNames(pd.Dropped))
+	}
+	return fmt.Sprintf("REORGANIZE PARTITION %s INTO (%s)", partitionNames(pd.Dropped), partitionDefinitions(pd.Added))
+}
+
+func partitionDefinitions(partitions []*Partition) string {
+	defs := make([]string, len(partitions))
+	for i, p := range partitions {
+		defs[i] = p.Definition
+	}
+	return strings.Join(defs, ", ")
+}
+
+func partitionNames(partitions []*Partition) string {
+	names := make([]string, len(partitions))
+	for i, p := range partitions {
+		names[i] = tengo.EscapeIdentifier(p.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// partitionChanges returns the changes corresponding to a partition diff:
+// one per partition added, dropped or modified, or a single one if the
+// partitioning of the table changes as a whole.
+func partitionChanges(pd *PartitionDiff) []Change {
+	switch {
+	case pd.To == nil:
+		return []Change{{Kind: ChangeRemovePartitioning, Object: tengo.ObjectTypeTable, Table: pd.Table, From: pd.From.String(), Origin: pd}}
+	case pd.From == nil:
+		return []Change{{Kind: ChangePartitioning, Object: tengo.ObjectTypeTable, Table: pd.Table, To: pd.To.String(), Origin: pd}}
+	case pd.From.Method != pd.To.Method || pd.From.Count != pd.To.Count:
+		return []Change{{Kind: ChangePartitioning, Object: tengo.ObjectTypeTable, Table: pd.Table, From: pd.From.String(), To: pd.To.String(), Origin: pd}}
+	}
+
+	var res []Change
+	added := map[string]*Partition{}
+	for _, p := range pd.Added {
+		added[p.Name] = p
+	}
+	dropped := map[string]bool{}
+	for _, p := range pd.Dropped {
+		dropped[p.Name] = true
+		if other, ok := added[p.Name]; !ok {
+			res = append(res, Change{Kind: ChangeDropPartition, Object: ObjectTypePartition, Table: pd.Table, Name: p.Name, From: p.Definition, Origin: pd})
+		} else if other.Definition != p.Definition {
+			res = append(res, Change{Kind: ChangeModifyPartition, Object: ObjectTypePartition, Table: pd.Table, Name: p.Name, From: p.Definition, To: other.Definition, Origin: pd})
+		}
+	}
+	for _, p := range pd.Added {
+		if !dropped[p.Name] {
+			res = append(res, Change{Kind: ChangeAddPartition, Object: ObjectTypePartition, Table: pd.Table, Name: p.Name, To: p.Definition, Origin: pd})
+		}
+	}
+	return res
+}
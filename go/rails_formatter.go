@@ -0,0 +1,694 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// Name and version of the ActiveRecord migration emitted by a RailsFormatter
+const (
+	railsMigrationClass   = "ReconcileSchemas"
+	railsMigrationVersion = "6.1"
+)
+
+// RailsFormatter formats a diff as an ActiveRecord migration, which applied
+// to the first schema of the diff turns it into the second one.
+//
+// The differences are reproduced with the migrations DSL: add_column,
+// change_column, add_index, add_foreign_key... and the ones that it cannot
+// express, like triggers, views or invisible columns, are executed as raw
+// SQL. The migration defines a change method if Rails can reverse all its
+// commands, and up and down methods otherwise, down raising
+// ActiveRecord::IrreversibleMigration if some difference cannot be undone.
+type RailsFormatter struct{}
+
+// railsOperation is a command of a migration, along with the command undoing
+// it, which is empty if there's none. Reversible is true for the commands
+// Rails can undo by itself in a change method.
+//
+// Stage orders the commands altering a table: unlike the clauses of an ALTER
+// TABLE statement, commands run one at a time, so indexes, foreign keys and
+// constraints are dropped before altering the columns they use, with a
+// negative stage, and added after, with a positive one.
+//
+// Severity is only set in the first command of each object diff, which is
+// preceded by a comment with the severity of the diff, as statements are in
+// SQL output.
+type railsOperation struct {
+	Up, Down   string
+	Reversible bool
+	Stage      int
+	Severity   Severity
+}
+
+// FormatsMigrations (see MigrationsFormatter)
+func (f *RailsFormatter) FormatsMigrations() {}
+
+// Format returns a string with the diff as a Ruby ActiveRecord migration
+func (f *RailsFormatter) Format(diff *Diff) interface{} {
+	var buf bytes.Buffer
+	mods := tengo.StatementModifiers{IgnoreTable: diff.Filter.IgnoreTable(), StrictForeignKeyNaming: diff.StrictForeignKeyNaming, Flavor: diff.Flavor1}
+	buf.WriteString(fmt.Sprintf("# Differences between `%s` in %s and `%s` in %s\n", diff.From.Name, diff.DSN1.Addr, diff.To.Name, diff.DSN2.Addr))
+	for _, w := range diff.Warnings() {
+		buf.WriteString(fmt.Sprintf("# Warning: %s\n", w))
+	}
+
+	var ops []railsOperation
+	for _, od := range diff.Compute() {
+		if md, ok := od.(*MigrationsDiff); ok {
+			if len(md.Missing1) > 0 {
+				buf.WriteString(fmt.Sprintf("# Migrations missing in %s: %s\n", md.Context.DSN1.Addr, strings.Join(md.Missing1, ", ")))
+			}
+			if len(md.Missing2) > 0 {
+				buf.WriteString(fmt.Sprintf("# Migrations missing in %s: %s\n", md.Context.DSN2.Addr, strings.Join(md.Missing2, ", ")))
+			}
+			continue
+		}
+		odOps := f.operations(od, mods)
+		if len(odOps) > 0 {
+			odOps[0].Severity = objectSeverity(od)
+		}
+		ops = append(ops, odOps...)
+	}
+
+	reversible, undoable := true, true
+	for _, op := range ops {
+		reversible = reversible && op.Reversible
+		undoable = undoable && op.Down != ""
+	}
+
+	buf.WriteString(fmt.Sprintf("class %s < ActiveRecord::Migration[%s]\n", railsMigrationClass, railsMigrationVersion))
+	if reversible {
+		buf.WriteString("  def change\n")
+		for _, op := range ops {
+			buf.WriteString(railsSeverityComment(op.Severity))
+			buf.WriteString(railsIndent(op.Up))
+		}
+		buf.WriteString("  end\n")
+	} else {
+		buf.WriteString("  def up\n")
+		for _, op := range ops {
+			buf.WriteString(railsSeverityComment(op.Severity))
+			buf.WriteString(railsIndent(op.Up))
+		}
+		buf.WriteString("  end\n\n  def down\n")
+		if !undoable {
+			buf.WriteString(railsIndent("raise ActiveRecord::IrreversibleMigration"))
+		} else {
+			for i := len(ops) - 1; i >= 0; i-- {
+				buf.WriteString(railsIndent(ops[i].Down))
+			}
+		}
+		buf.WriteString("  end\n")
+	}
+	buf.WriteString("end\n")
+	return buf.String()
+}
+
+// operations returns the commands of the migration reproducing an object diff
+func (f *RailsFormatter) operations(od tengo.ObjectDiff, mods tengo.StatementModifiers) []railsOperation {
+	switch od := od.(type) {
+	case *TableDiff:
+		return f.tableOperations(od, mods)
+	case *RenameTableDiff:
+		return []railsOperation{{
+			Up:         fmt.Sprintf("rename_table %s, %s", rubySymbol(od.From.Name), rubySymbol(od.To.Name)),
+			Down:       fmt.Sprintf("rename_table %s, %s", rubySymbol(od.To.Name), rubySymbol(od.From.Name)),
+			Reversible: true,
+		}}
+	}
+
+	up := statements(od, mods)
+	if len(up) == 0 {
+		return nil
+	}
+	op := railsOperation{Up: railsExecute(up...)}
+	if reversed := reversedDiff(od); reversed != nil {
+		if down := statements(reversed, mods); len(down) > 0 {
+			op.Down = railsExecute(down...)
+		}
+	}
+	return []railsOperation{op}
+}
+
+// tableOperations returns the commands creating, dropping or altering a table
+func (f *RailsFormatter) tableOperations(td *TableDiff, mods tengo.StatementModifiers) []railsOperation {
+	name := td.ObjectKey().Name
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(name) {
+		return nil
+	}
+	switch td.DiffType() {
+	case tengo.DiffTypeCreate:
+		stmt, _ := td.Statement(mods)
+		return []railsOperation{{Up: railsExecute(stmt), Down: fmt.Sprintf("drop_table %s", rubySymbol(name))}}
+	case tengo.DiffTypeDrop:
+		return []railsOperation{{Up: fmt.Sprintf("drop_table %s", rubySymbol(name)), Down: railsExecute(td.From.CreateStatement)}}
+	}
+
+	if td.AlterClauses() == nil {
+		// Without clauses, the statement altering the table cannot be undone
+		stmt, _ := td.Statement(mods)
+		if stmt == "" {
+			return nil
+		}
+		return []railsOperation{{Up: railsExecute(stmt)}}
+	}
+	var res []railsOperation
+	for _, c := range td.AlterClauses() {
+		res = append(res, f.clauseOperations(c, td, mods)...)
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Stage < res[j].Stage })
+	return res
+}
+
+// clauseOperations returns the commands applying an alter clause of a table
+// diff. Clauses that cannot be expressed with the migrations DSL are executed
+// as an ALTER TABLE statement, undone by the one applying the opposite clause.
+func (f *RailsFormatter) clauseOperations(c tengo.TableAlterClause, td *TableDiff, mods tengo.StatementModifiers) []railsOperation {
+	table := rubySymbol(td.From.Name)
+	alter := func(up, down tengo.TableAlterClause) railsOperation {
+		op := railsOperation{Up: railsExecute(fmt.Sprintf("%s %s", td.From.AlterStatement(), up.Clause(mods)))}
+		if down != nil {
+			op.Down = railsExecute(fmt.Sprintf("%s %s", td.From.AlterStatement(), down.Clause(mods)))
+		}
+		return op
+	}
+
+	switch c := c.(type) {
+	case tengo.AddColumn:
+		def, ok := railsColumn(c.Column, c.Table, nil, false)
+		if !ok {
+			return []railsOperation{alter(c, tengo.DropColumn{Column: c.Column})}
+		}
+		def += railsPosition(c.PositionFirst, c.PositionAfter)
+		return []railsOperation{{
+			Up:         fmt.Sprintf("add_column %s, %s, %s", table, rubySymbol(c.Column.Name), def),
+			Down:       fmt.Sprintf("remove_column %s, %s", table, rubySymbol(c.Column.Name)),
+			Reversible: true,
+		}}
+	case tengo.DropColumn:
+		first, after := columnPredecessor(td.From, c.Column.Name)
+		def, ok := railsColumn(c.Column, td.From, nil, false)
+		if !ok {
+			return []railsOperation{alter(c, tengo.AddColumn{Table: td.From, Column: c.Column, PositionFirst: first, PositionAfter: after})}
+		}
+		// remove_column is given the position of the column too, so it's
+		// added back where it was when the migration is rolled back
+		def += railsPosition(first, after)
+		return []railsOperation{{
+			Up:         fmt.Sprintf("remove_column %s, %s, %s", table, rubySymbol(c.Column.Name), def),
+			Down:       fmt.Sprintf("add_column %s, %s, %s", table, rubySymbol(c.Column.Name), def),
+			Reversible: true,
+		}}
+	case tengo.ModifyColumn:
+		return []railsOperation{f.modifyColumn(AlterColumn{Alter: c}, td, mods)}
+	case MoveColumn:
+		if ac, ok := c.Alter.(AlterColumn); ok {
+			return []railsOperation{f.modifyColumn(ac, td, mods)}
+		}
+		return []railsOperation{f.modifyColumn(AlterColumn{Alter: c.Alter}, td, mods)}
+	case AlterColumn:
+		if add, ok := c.Alter.(tengo.AddColumn); ok {
+			def, ok := railsColumn(add.Column, add.Table, c.ToGeneration, false)
+			if !ok || c.ToInvisible {
+				return []railsOperation{alter(c, tengo.DropColumn{Column: add.Column})}
+			}
+			def += railsPosition(add.PositionFirst, add.PositionAfter)
+			return []railsOperation{{
+				Up:         fmt.Sprintf("add_column %s, %s, %s", table, rubySymbol(add.Column.Name), def),
+				Down:       fmt.Sprintf("remove_column %s, %s", table, rubySymbol(add.Column.Name)),
+				Reversible: true,
+			}}
+		}
+		return []railsOperation{f.modifyColumn(c, td, mods)}
+	case RenameColumn:
+		rename := railsOperation{
+			Up:         fmt.Sprintf("rename_column %s, %s, %s", table, rubySymbol(c.OldColumn.Name), rubySymbol(c.NewColumn.Name)),
+			Down:       fmt.Sprintf("rename_column %s, %s, %s", table, rubySymbol(c.NewColumn.Name), rubySymbol(c.OldColumn.Name)),
+			Reversible: true,
+		}
+		renamed := *c.OldColumn
+		renamed.Name = c.NewColumn.Name
+		if renamed.Equals(c.NewColumn) {
+			return []railsOperation{rename}
+		}
+		// The column is modified once renamed
+		first, after := columnPredecessor(td.From, c.OldColumn.Name)
+		modify := tengo.ModifyColumn{Table: c.Table, OldColumn: &renamed, NewColumn: c.NewColumn, PositionFirst: c.PositionFirst, PositionAfter: c.PositionAfter}
+		undo := tengo.ModifyColumn{Table: td.From, OldColumn: c.NewColumn, NewColumn: &renamed, PositionFirst: first, PositionAfter: after}
+		return []railsOperation{rename, f.modifyColumnWith(modify, undo, nil, nil, td, mods)}
+	case tengo.AddIndex:
+		if c.Clause(mods) == "" {
+			return nil
+		}
+		return []railsOperation{f.addIndex(&Index{Index: c.Index}, td, mods)}
+	case tengo.DropIndex:
+		if c.Clause(mods) == "" {
+			return nil
+		}
+		return []railsOperation{f.dropIndex(&Index{Index: c.Index}, td, mods)}
+	case AlterIndex:
+		switch {
+		case c.From == nil:
+			return []railsOperation{f.addIndex(c.To, td, mods)}
+		case c.To == nil:
+			return []railsOperation{f.dropIndex(c.From, td, mods)}
+		case c.Clause(mods) == "":
+			return nil
+		case strings.HasPrefix(c.Clause(mods), "ALTER INDEX"):
+			return []railsOperation{alter(c, AlterIndex{From: c.To, To: c.From})}
+		}
+		return []railsOperation{f.dropIndex(c.From, td, mods), f.addIndex(c.To, td, mods)}
+	case tengo.AddForeignKey:
+		if c.Clause(mods) == "" {
+			return nil
+		}
+		return []railsOperation{f.addForeignKey(c.ForeignKey, td, mods)}
+	case tengo.DropForeignKey:
+		if c.Clause(mods) == "" {
+			return nil
+		}
+		return []railsOperation{f.dropForeignKey(c.ForeignKey, td, mods)}
+	case AlterForeignKey:
+		if c.Added {
+			return []railsOperation{f.addForeignKey(c.To, td, mods)}
+		}
+		return []railsOperation{f.dropForeignKey(c.From, td, mods)}
+	case AlterCheck:
+		switch {
+		case c.From == nil:
+			return []railsOperation{f.addCheck(c.To, td, mods)}
+		case c.To == nil:
+			return []railsOperation{f.dropCheck(c.From, td, mods)}
+		case c.Clause(mods) == "":
+			return nil
+		case strings.HasPrefix(c.Clause(mods), "ALTER CHECK"):
+			return []railsOperation{alter(c, AlterCheck{From: c.To, To: c.From})}
+		}
+		return []railsOperation{f.dropCheck(c.From, td, mods), f.addCheck(c.To, td, mods)}
+	case tengo.ChangeCharSet:
+		return []railsOperation{alter(c, tengo.ChangeCharSet{CharSet: td.From.CharSet, Collation: td.From.Collation})}
+	case tengo.ChangeCreateOptions:
+		return []railsOperation{alter(c, tengo.ChangeCreateOptions{OldCreateOptions: c.NewCreateOptions, NewCreateOptions: c.OldCreateOptions})}
+	case tengo.ChangeComment:
+		return []railsOperation{{
+			Up:         fmt.Sprintf("change_table_comment %s, from: %s, to: %s", table, rubyString(td.From.Comment), rubyString(c.NewComment)),
+			Down:       fmt.Sprintf("change_table_comment %s, from: %s, to: %s", table, rubyString(c.NewComment), rubyString(td.From.Comment)),
+			Reversible: true,
+		}}
+	case tengo.ChangeStorageEngine:
+		return []railsOperation{alter(c, tengo.ChangeStorageEngine{NewStorageEngine: td.From.Engine})}
+	case tengo.ChangeAutoIncrement:
+		return nil
+	}
+	return []railsOperation{alter(c, nil)}
+}
+
+// modifyColumn returns the command modifying a column, which is undone by
+// modifying it back, in its former position if it's moved.
+func (f *RailsFormatter) modifyColumn(ac AlterColumn, td *TableDiff, mods tengo.StatementModifiers) railsOperation {
+	undo := ac.undo(td)
+	if ac.FromInvisible || ac.ToInvisible || ac.rebuildsColumn() {
+		return railsOperation{
+			Up:   railsExecute(fmt.Sprintf("%s %s", td.From.AlterStatement(), ac.Clause(mods))),
+			Down: railsExecute(fmt.Sprintf("%s %s", td.From.AlterStatement(), undo.Clause(mods))),
+		}
+	}
+	return f.modifyColumnWith(ac.Alter.(tengo.ModifyColumn), undo.Alter.(tengo.ModifyColumn), ac.ToGeneration, ac.FromGeneration, td, mods)
+}
+
+// modifyColumnWith returns the command applying a tengo.ModifyColumn clause,
+// undone by another one, given how the column is generated after each of
+// them.
+func (f *RailsFormatter) modifyColumnWith(mc, undo tengo.ModifyColumn, generation, undoGeneration *Generation, td *TableDiff, mods tengo.StatementModifiers) railsOperation {
+	table := rubySymbol(td.From.Name)
+	def, ok := railsColumn(mc.NewColumn, mc.Table, generation, true)
+	undoDef, undoOK := railsColumn(undo.NewColumn, undo.Table, undoGeneration, true)
+	if !ok || !undoOK {
+		return railsOperation{
+			Up:   railsExecute(fmt.Sprintf("%s %s", td.From.AlterStatement(), AlterColumn{Alter: mc, FromGeneration: undoGeneration, ToGeneration: generation}.Clause(mods))),
+			Down: railsExecute(fmt.Sprintf("%s %s", td.From.AlterStatement(), AlterColumn{Alter: undo, FromGeneration: generation, ToGeneration: undoGeneration}.Clause(mods))),
+		}
+	}
+	return railsOperation{
+		Up:   fmt.Sprintf("change_column %s, %s, %s%s", table, rubySymbol(mc.NewColumn.Name), def, railsPosition(mc.PositionFirst, mc.PositionAfter)),
+		Down: fmt.Sprintf("change_column %s, %s, %s%s", table, rubySymbol(undo.NewColumn.Name), undoDef, railsPosition(undo.PositionFirst, undo.PositionAfter)),
+	}
+}
+
+// addIndex returns the command adding an index, or executing the clause
+// that adds it if the migrations DSL cannot express it.
+func (f *RailsFormatter) addIndex(idx *Index, td *TableDiff, mods tengo.StatementModifiers) railsOperation {
+	table := rubySymbol(td.From.Name)
+	columns, options, ok := railsIndex(idx)
+	if !ok {
+		return railsOperation{
+			Up:    railsExecute(fmt.Sprintf("%s ADD %s", td.From.AlterStatement(), idx.Definition(mods.Flavor))),
+			Down:  railsExecute(fmt.Sprintf("%s %s", td.From.AlterStatement(), tengo.DropIndex{Index: idx.Index}.Clause(mods))),
+			Stage: 1,
+		}
+	}
+	return railsOperation{
+		Up:         fmt.Sprintf("add_index %s, %s, %s", table, columns, options),
+		Down:       fmt.Sprintf("remove_index %s, column: %s, %s", table, columns, options),
+		Reversible: true,
+		Stage:      1,
+	}
+}
+
+// dropIndex returns the command dropping an index, or executing the clause
+// that drops it if the migrations DSL cannot express it.
+func (f *RailsFormatter) dropIndex(idx *Index, td *TableDiff, mods tengo.StatementModifiers) railsOperation {
+	op := f.addIndex(idx, td, mods)
+	op.Up, op.Down, op.Stage = op.Down, op.Up, -op.Stage
+	return op
+}
+
+// addForeignKey returns the command adding a foreign key, or executing the
+// clause that adds it if the migrations DSL cannot express it.
+func (f *RailsFormatter) addForeignKey(fk *tengo.ForeignKey, td *TableDiff, mods tengo.StatementModifiers) railsOperation {
+	args, ok := railsForeignKey(fk)
+	if !ok {
+		return railsOperation{
+			Up:    railsExecute(fmt.Sprintf("%s %s", td.From.AlterStatement(), tengo.AddForeignKey{ForeignKey: fk}.Clause(mods))),
+			Down:  railsExecute(fmt.Sprintf("%s %s", td.From.AlterStatement(), tengo.DropForeignKey{ForeignKey: fk}.Clause(mods))),
+			Stage: 2,
+		}
+	}
+	return railsOperation{
+		Up:         fmt.Sprintf("add_foreign_key %s, %s", rubySymbol(td.From.Name), args),
+		Down:       fmt.Sprintf("remove_foreign_key %s, %s", rubySymbol(td.From.Name), args),
+		Reversible: true,
+		Stage:      2,
+	}
+}
+
+// dropForeignKey returns the command dropping a foreign key, or executing the
+// clause that drops it if the migrations DSL cannot express it.
+func (f *RailsFormatter) dropForeignKey(fk *tengo.ForeignKey, td *TableDiff, mods tengo.StatementModifiers) railsOperation {
+	op := f.addForeignKey(fk, td, mods)
+	op.Up, op.Down, op.Stage = op.Down, op.Up, -op.Stage
+	return op
+}
+
+// addCheck returns the command adding a CHECK constraint, or executing the
+// clause that adds it if it's not enforced, which the migrations DSL cannot
+// express.
+func (f *RailsFormatter) addCheck(check *Check, td *TableDiff, mods tengo.StatementModifiers) railsOperation {
+	if !check.Enforced {
+		return railsOperation{
+			Up:    railsExecute(fmt.Sprintf("%s %s", td.From.AlterStatement(), AlterCheck{To: check}.Clause(mods))),
+			Down:  railsExecute(fmt.Sprintf("%s %s", td.From.AlterStatement(), AlterCheck{From: check}.Clause(mods))),
+			Stage: 1,
+		}
+	}
+	args := fmt.Sprintf("%s, %s, name: %s", rubySymbol(td.From.Name), rubyString(check.Clause), rubyString(check.Name))
+	return railsOperation{
+		Up:         "add_check_constraint " + args,
+		Down:       "remove_check_constraint " + args,
+		Reversible: true,
+		Stage:      1,
+	}
+}
+
+// dropCheck returns the command dropping a CHECK constraint, or executing
+// the clause that drops it if it's not enforced.
+func (f *RailsFormatter) dropCheck(check *Check, td *TableDiff, mods tengo.StatementModifiers) railsOperation {
+	op := f.addCheck(check, td, mods)
+	op.Up, op.Down, op.Stage = op.Down, op.Up, -op.Stage
+	return op
+}
+
+// railsTypes are the types of the migrations DSL given to the column types
+// of MySQL that they are translated into. Other types are given as they are.
+var railsTypes = map[string]string{
+	"int":       ":integer",
+	"bigint":    ":bigint",
+	"varchar":   ":string",
+	"text":      ":text",
+	"blob":      ":binary",
+	"varbinary": ":binary",
+	"decimal":   ":decimal",
+	"float":     ":float",
+	"date":      ":date",
+	"datetime":  ":datetime",
+	"timestamp": ":timestamp",
+	"time":      ":time",
+	"json":      ":json",
+}
+
+// railsColumn returns the type and options of a column in the migrations DSL,
+// or false if the DSL cannot express it. Options left out are set to their
+// default unless explicit is true, as change_column keeps the nullability,
+// default and comment of the column unless they are given.
+func railsColumn(col *tengo.Column, table *tengo.Table, generation *Generation, explicit bool) (string, bool) {
+	if col.OnUpdate != "" {
+		return "", false
+	}
+	typ, options := railsType(col.TypeInDB)
+	if generation != nil {
+		options = append([]string{fmt.Sprintf("type: %s", typ)}, options...)
+		typ = ":virtual"
+		options = append(options, fmt.Sprintf("as: %s", rubyString(generation.Expression)))
+		if generation.Stored {
+			options = append(options, "stored: true")
+		}
+	}
+	if !col.Nullable || explicit {
+		options = append(options, fmt.Sprintf("null: %t", col.Nullable))
+	}
+	switch {
+	case col.AutoIncrement:
+		options = append(options, "auto_increment: true")
+	case col.Default.Quoted && typ == ":boolean":
+		options = append(options, fmt.Sprintf("default: %t", col.Default.Value != "0"))
+	case col.Default.Quoted:
+		options = append(options, fmt.Sprintf("default: %s", rubyString(col.Default.Value)))
+	case !col.Default.Null && col.Default.Value != "":
+		options = append(options, fmt.Sprintf("default: -> { %s }", rubyString(col.Default.Value)))
+	case explicit:
+		options = append(options, "default: nil")
+	}
+	if col.CharSet != "" && (table == nil || col.Collation != table.Collation || col.CharSet != table.CharSet) {
+		options = append(options, fmt.Sprintf("charset: %s", rubyString(col.CharSet)))
+		if !col.CollationIsDefault {
+			options = append(options, fmt.Sprintf("collation: %s", rubyString(col.Collation)))
+		}
+	}
+	if col.Comment != "" {
+		options = append(options, fmt.Sprintf("comment: %s", rubyString(col.Comment)))
+	} else if explicit {
+		options = append(options, "comment: nil")
+	}
+	return strings.Join(append([]string{typ}, options...), ", "), true
+}
+
+// railsType returns the type of the migrations DSL for a column type, along
+// with the options it takes, i.e. :string and limit: 255 for varchar(255)
+func railsType(typeInDB string) (string, []string) {
+	m := columnType.FindStringSubmatch(typeInDB)
+	if m == nil {
+		return rubyString(typeInDB), nil
+	}
+	name, args, attributes := m[1], m[2], strings.TrimSpace(m[3])
+	if name == "tinyint" && args == "1" && attributes == "" {
+		return ":boolean", nil
+	}
+	var options []string
+	switch {
+	case attributes == "unsigned" && (strings.HasSuffix(name, "int") || name == "decimal" || name == "float"):
+		options = append(options, "unsigned: true")
+	case attributes != "":
+		return rubyString(typeInDB), nil
+	}
+	switch name {
+	case "tinyint", "smallint", "mediumint":
+		return ":integer", append([]string{fmt.Sprintf("limit: %d", typeRanks[name])}, options...)
+	case "int", "bigint":
+		return railsTypes[name], options
+	case "float":
+		if args == "" {
+			return railsTypes[name], options
+		}
+	case "varchar", "varbinary":
+		return railsTypes[name], append([]string{fmt.Sprintf("limit: %s", args)}, options...)
+	case "tinytext", "mediumtext", "longtext":
+		return ":text", []string{fmt.Sprintf("size: :%s", strings.TrimSuffix(name, "text"))}
+	case "decimal":
+		precision := strings.Split(args, ",")
+		if len(precision) != 2 {
+			break
+		}
+		return ":decimal", append([]string{fmt.Sprintf("precision: %s", precision[0]), fmt.Sprintf("scale: %s", precision[1])}, options...)
+	case "datetime", "timestamp", "time":
+		if args != "" {
+			return railsTypes[name], []string{fmt.Sprintf("precision: %s", args)}
+		}
+		return railsTypes[name], nil
+	case "text", "blob", "date", "json":
+		if args == "" {
+			return railsTypes[name], nil
+		}
+	}
+	return rubyString(typeInDB), nil
+}
+
+// railsPosition returns the options positioning a column, if any
+func railsPosition(first bool, after *tengo.Column) string {
+	if first {
+		return ", first: true"
+	}
+	if after != nil {
+		return fmt.Sprintf(", after: %s", rubySymbol(after.Name))
+	}
+	return ""
+}
+
+// railsIndex returns the columns and options of an index in the migrations
+// DSL, or false if the DSL cannot express it, as it happens with primary
+// keys, invisible indexes and full-text parsers.
+func railsIndex(idx *Index) (string, string, bool) {
+	if idx.PrimaryKey || idx.Invisible || idx.Parser != "" {
+		return "", "", false
+	}
+	var columns, lengths, orders []string
+	for i, c := range idx.Columns {
+		columns = append(columns, rubySymbol(c.Name))
+		if idx.SubParts[i] > 0 {
+			lengths = append(lengths, fmt.Sprintf("%s%d", rubyKey(c.Name), idx.SubParts[i]))
+		}
+		if i < len(idx.Desc) && idx.Desc[i] {
+			orders = append(orders, fmt.Sprintf("%s:desc", rubyKey(c.Name)))
+		}
+	}
+	cols := columns[0]
+	if len(columns) > 1 {
+		cols = fmt.Sprintf("[%s]", strings.Join(columns, ", "))
+	}
+
+	options := []string{fmt.Sprintf("name: %s", rubyString(idx.Name))}
+	if idx.Unique {
+		options = append(options, "unique: true")
+	}
+	if len(lengths) > 0 {
+		options = append(options, fmt.Sprintf("length: { %s }", strings.Join(lengths, ", ")))
+	}
+	if len(orders) > 0 {
+		options = append(options, fmt.Sprintf("order: { %s }", strings.Join(orders, ", ")))
+	}
+	if idx.Type != "" {
+		options = append(options, fmt.Sprintf("type: :%s", strings.ToLower(idx.Type)))
+	}
+	if idx.Comment != "" {
+		options = append(options, fmt.Sprintf("comment: %s", rubyString(idx.Comment)))
+	}
+	return cols, strings.Join(options, ", "), true
+}
+
+// railsRules are the values of the on_delete and on_update options of the
+// migrations DSL for the referential actions of foreign keys.
+var railsRules = map[string]string{
+	"CASCADE":   ":cascade",
+	"SET NULL":  ":nullify",
+	"RESTRICT":  "",
+	"NO ACTION": "",
+}
+
+// railsForeignKey returns the arguments of the add_foreign_key command adding
+// a foreign key but the table, or false if the migrations DSL cannot express
+// it, as it happens with foreign keys of several columns or referencing other
+// schemas.
+func railsForeignKey(fk *tengo.ForeignKey) (string, bool) {
+	onDelete, deleteOK := railsRules[fk.DeleteRule]
+	onUpdate, updateOK := railsRules[fk.UpdateRule]
+	if len(fk.Columns) != 1 || fk.ReferencedSchemaName != "" || !deleteOK || !updateOK {
+		return "", false
+	}
+	args := []string{
+		rubySymbol(fk.ReferencedTableName),
+		fmt.Sprintf("column: %s", rubySymbol(fk.Columns[0].Name)),
+		fmt.Sprintf("primary_key: %s", rubySymbol(fk.ReferencedColumnNames[0])),
+		fmt.Sprintf("name: %s", rubyString(fk.Name)),
+	}
+	if onDelete != "" {
+		args = append(args, fmt.Sprintf("on_delete: %s", onDelete))
+	}
+	if onUpdate != "" {
+		args = append(args, fmt.Sprintf("on_update: %s", onUpdate))
+	}
+	return strings.Join(args, ", "), true
+}
+
+// railsExecute returns the execute commands running the given SQL statements.
+// Statements spanning several lines are given as heredocs.
+func railsExecute(stmts ...string) string {
+	var res []string
+	for _, stmt := range stmts {
+		if !strings.Contains(stmt, "\n") {
+			res = append(res, fmt.Sprintf("execute %s", rubyString(stmt)))
+			continue
+		}
+		res = append(res, fmt.Sprintf("execute <<~'SQL'\n  %s\nSQL", strings.Replace(stmt, "\n", "\n  ", -1)))
+	}
+	return strings.Join(res, "\n")
+}
+
+// railsIndent indents a command to the body of a method of the migration
+func railsIndent(command string) string {
+	return "    " + strings.Replace(command, "\n", "\n    ", -1) + "\n"
+}
+
+// railsSeverityComment returns the comment preceding the commands of an
+// object diff with the given severity, if any.
+func railsSeverityComment(severity Severity) string {
+	if severity == 0 {
+		return ""
+	}
+	return railsIndent(fmt.Sprintf("# Severity: %s", severity))
+}
+
+// rubyIdentifier matches the names that can be written as Ruby symbols
+// without quotes.
+var rubyIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// rubySymbol returns a Ruby symbol with the given name, i.e. :tasks
+func rubySymbol(name string) string {
+	if rubyIdentifier.MatchString(name) {
+		return ":" + name
+	}
+	return ":" + rubyString(name)
+}
+
+// rubyKey returns the key of a Ruby hash entry with the given symbol as key,
+// i.e. title: for title
+func rubyKey(name string) string {
+	if rubyIdentifier.MatchString(name) {
+		return name + ": "
+	}
+	return rubyString(name) + ": "
+}
+
+// rubyString returns a double quoted Ruby string literal, escaping the # sign
+// so nothing is interpolated.
+func rubyString(s string) string {
+	return strings.Replace(strconv.Quote(s), "#", `\#`, -1)
+}
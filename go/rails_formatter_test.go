@@ -0,0 +1,133 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestRailsFormatter_Format(t *testing.T) {
+	id := &tengo.Column{Name: "id", TypeInDB: "int", AutoIncrement: true}
+	title := &tengo.Column{Name: "title", TypeInDB: "varchar(255)", Nullable: true, Default: tengo.ColumnDefaultNull, CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true}
+	longTitle := &tengo.Column{Name: "title", TypeInDB: "varchar(500)", Default: tengo.ColumnDefaultValue("untitled"), CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true}
+	ownerID := &tengo.Column{Name: "owner_id", TypeInDB: "int", Nullable: true, Default: tengo.ColumnDefaultNull}
+	done := &tengo.Column{Name: "done", TypeInDB: "tinyint(1)", Default: tengo.ColumnDefaultValue("0")}
+	dueAt := &tengo.Column{Name: "due_at", TypeInDB: "datetime(6)", Nullable: true, Default: tengo.ColumnDefaultNull}
+	table := func(columns ...*tengo.Column) *tengo.Table {
+		table := &tengo.Table{Name: "tasks", Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true, Columns: columns}
+		table.PrimaryKey = &tengo.Index{Name: "PRIMARY", Columns: []*tengo.Column{id}, SubParts: []uint16{0}, PrimaryKey: true, Unique: true}
+		for _, c := range columns {
+			if c == ownerID {
+				table.SecondaryIndexes = []*tengo.Index{{Name: "owner_index", Columns: []*tengo.Column{ownerID}, SubParts: []uint16{0}}}
+			}
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorMySQL80)
+		return table
+	}
+	diff := func(to *tengo.Table) *Diff {
+		return &Diff{
+			DSN1:     ParseDSN(DSN1),
+			DSN2:     ParseDSN(DSN2),
+			From:     &tengo.Schema{Name: "shop", Tables: []*tengo.Table{table(id, title, ownerID)}},
+			To:       &tengo.Schema{Name: "shop", Tables: []*tengo.Table{to}},
+			Flavor1:  tengo.FlavorMySQL80,
+			Flavor2:  tengo.FlavorMySQL80,
+			objects1: &Objects{},
+			objects2: &Objects{},
+		}
+	}
+	header := "# Differences between `shop` in 127.0.0.1:33060 and `shop` in 127.0.0.1:33062\n"
+
+	t.Run("Reversible", func(t *testing.T) {
+		Equal(t, header+
+			"class ReconcileSchemas < ActiveRecord::Migration[6.1]\n"+
+			"  def change\n"+
+			"    # Severity: additive\n"+
+			"    add_column :tasks, :due_at, :datetime, precision: 6\n"+
+			"    add_column :tasks, :done, :boolean, null: false, default: false\n"+
+			"  end\n"+
+			"end\n", (&RailsFormatter{}).Format(diff(table(id, title, ownerID, dueAt, done))))
+	})
+
+	t.Run("Up and down", func(t *testing.T) {
+		Equal(t, header+
+			"class ReconcileSchemas < ActiveRecord::Migration[6.1]\n"+
+			"  def up\n"+
+			"    # Severity: breaking\n"+
+			"    remove_index :tasks, column: :owner_id, name: \"owner_index\"\n"+
+			"    remove_column :tasks, :owner_id, :integer, after: :title\n"+
+			"    change_column :tasks, :title, :string, limit: 500, null: false, default: \"untitled\", comment: nil\n"+
+			"    add_column :tasks, :due_at, :datetime, precision: 6\n"+
+			"  end\n"+
+			"\n"+
+			"  def down\n"+
+			"    remove_column :tasks, :due_at\n"+
+			"    change_column :tasks, :title, :string, limit: 255, null: true, default: nil, comment: nil\n"+
+			"    add_column :tasks, :owner_id, :integer, after: :title\n"+
+			"    add_index :tasks, :owner_id, name: \"owner_index\"\n"+
+			"  end\n"+
+			"end\n", (&RailsFormatter{}).Format(diff(table(id, longTitle, dueAt))))
+	})
+
+	t.Run("Raw SQL", func(t *testing.T) {
+		d := diff(table(id, title, ownerID))
+		d.To.Tables = nil
+		Equal(t, header+
+			"class ReconcileSchemas < ActiveRecord::Migration[6.1]\n"+
+			"  def up\n"+
+			"    # Severity: breaking\n"+
+			"    drop_table :tasks\n"+
+			"  end\n"+
+			"\n"+
+			"  def down\n"+
+			"    execute <<~'SQL'\n"+
+			"      CREATE TABLE `tasks` (\n"+
+			"        `id` int NOT NULL AUTO_INCREMENT,\n"+
+			"        `title` varchar(255) DEFAULT NULL,\n"+
+			"        `owner_id` int DEFAULT NULL,\n"+
+			"        PRIMARY KEY (`id`),\n"+
+			"        KEY `owner_index` (`owner_id`)\n"+
+			"      ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci\n"+
+			"    SQL\n"+
+			"  end\n"+
+			"end\n", (&RailsFormatter{}).Format(d))
+	})
+}
+
+func TestRailsType(t *testing.T) {
+	for typeInDB, expected := range map[string]string{
+		"int":                 ":integer",
+		"int(10) unsigned":    ":integer, unsigned: true",
+		"smallint(5)":         ":integer, limit: 2",
+		"tinyint(1)":          ":boolean",
+		"varchar(64)":         ":string, limit: 64",
+		"mediumtext":          ":text, size: :medium",
+		"decimal(10,2)":       ":decimal, precision: 10, scale: 2",
+		"timestamp(3)":        ":timestamp, precision: 3",
+		"char(2)":             `"char(2)"`,
+		"enum('todo','done')": `"enum('todo','done')"`,
+		"int(10) zerofill":    `"int(10) zerofill"`,
+		"double unsigned":     `"double unsigned"`,
+		"float(7,4)":          `"float(7,4)"`,
+	} {
+		typ, options := railsType(typeInDB)
+		for _, o := range options {
+			typ += ", " + o
+		}
+		Equal(t, expected, typ, typeInDB)
+	}
+}
@@ -0,0 +1,478 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// RenameTableDiff is an implementation of a tengo.ObjectDiff representing
+// a table that exists in both schemas under different names.
+//
+// tengo doesn't support renames yet, and represents them as a DROP TABLE
+// of the old table and a CREATE TABLE of the new one, which would lose all
+// the data in the table if applied.
+type RenameTableDiff struct {
+	From, To *tengo.Table
+	// Hinted is true when the rename was stated in the rename hints,
+	// and false when it was guessed because both tables are identical.
+	Hinted bool
+}
+
+// DiffType (see tengo.ObjectDiff)
+func (r *RenameTableDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeRename
+}
+
+// ObjectKey (see tengo.ObjectDiff)
+func (r *RenameTableDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{
+		Type: tengo.ObjectTypeTable,
+		Name: r.From.Name,
+	}
+}
+
+// Statement (see tengo.ObjectDiff)
+func (r *RenameTableDiff) Statement(tengo.StatementModifiers) (string, error) {
+	return fmt.Sprintf("RENAME TABLE %s TO %s", tengo.EscapeIdentifier(r.From.Name), tengo.EscapeIdentifier(r.To.Name)), nil
+}
+
+// RenameColumn is a tengo.TableAlterClause representing a column that exists
+// in both versions of a table under different names. It replaces the
+// DropColumn and AddColumn pair tengo computes for the renamed column.
+//
+// tengo.RenameColumn exists but is not supported by tengo yet, which is why
+// mydiff provides its own clause.
+type RenameColumn struct {
+	Table         *tengo.Table
+	OldColumn     *tengo.Column
+	NewColumn     *tengo.Column
+	PositionFirst bool
+	PositionAfter *tengo.Column
+	// Hinted is true when the rename was stated in the rename hints,
+	// and false when it was guessed because both columns are identical.
+	Hinted bool
+}
+
+// Clause returns a CHANGE COLUMN clause of an ALTER TABLE statement.
+func (rc RenameColumn) Clause(mods tengo.StatementModifiers) string {
+	var positionClause string
+	if rc.PositionFirst {
+		positionClause = " FIRST"
+	} else if rc.PositionAfter != nil {
+		positionClause = fmt.Sprintf(" AFTER %s", tengo.EscapeIdentifier(rc.PositionAfter.Name))
+	}
+	return fmt.Sprintf("CHANGE COLUMN %s %s%s", tengo.EscapeIdentifier(rc.OldColumn.Name), rc.NewColumn.Definition(mods.Flavor, rc.Table), positionClause)
+}
+
+// Unsafe returns true, for the same reasons tengo.RenameColumn is unsafe:
+// application code may still be using the old column name.
+func (rc RenameColumn) Unsafe() bool {
+	return true
+}
+
+// RenameHint states that an object was (or wasn't, if Negated) renamed
+// between the two schemas being compared.
+type RenameHint struct {
+	Type tengo.ObjectType
+	// Table is the name of the table the renamed column belongs to.
+	// It is empty for table renames.
+	Table    string
+	Old, New string
+	Negated  bool
+}
+
+// RenameHints is the set of hints used to confirm or override the renames
+// mydiff guesses.
+type RenameHints []RenameHint
+
+// LoadRenameHints reads the rename hints in the file at path.
+// See ParseRenameHints for the format of the file.
+func LoadRenameHints(path string) (RenameHints, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseRenameHints(f)
+}
+
+// ParseRenameHints parses rename hints, one per line, in the form:
+//
+//	table <old_name> <new_name>
+//	column <table>.<old_name> <new_name>
+//
+// A line prefixed with ! states that the objects were not renamed, even if
+// they look identical. Empty lines and lines starting with # are ignored.
+//
+// Hints are not directional: a hint matches the rename from old to new as
+// well as the rename from new to old, so the same file can be used when
+// diffing in reverse.
+func ParseRenameHints(r io.Reader) (RenameHints, error) {
+	hints := RenameHints{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var hint RenameHint
+		if strings.HasPrefix(line, "!") {
+			hint.Negated = true
+			line = strings.TrimPrefix(line, "!")
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("Invalid rename hint in line %d: expected 3 fields, got %d", n, len(fields))
+		}
+		switch fields[0] {
+		case "table":
+			hint.Type = tengo.ObjectTypeTable
+			hint.Old = fields[1]
+		case "column":
+			parts := strings.Split(fields[1], ".")
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("Invalid rename hint in line %d: column must be in the form table.column, got %s", n, fields[1])
+			}
+			hint.Type = columnObjectType
+			hint.Table = parts[0]
+			hint.Old = parts[1]
+		default:
+			return nil, fmt.Errorf("Invalid rename hint in line %d: unknown object type %s, only (table,column) are allowed", n, fields[0])
+		}
+		hint.New = fields[2]
+		hints = append(hints, hint)
+	}
+	return hints, scanner.Err()
+}
+
+// columnObjectType is the object type of column rename hints. tengo doesn't
+// define object types for the sub-objects of tables.
+const columnObjectType tengo.ObjectType = "column"
+
+// lookup returns whether a hint exists about the rename between objects a
+// and b, in any direction, and whether the hint confirms the rename.
+func (h RenameHints) lookup(typ tengo.ObjectType, table, a, b string) (found, renamed bool) {
+	for _, hint := range h {
+		if hint.Type != typ || hint.Table != table {
+			continue
+		}
+		if (hint.Old == a && hint.New == b) || (hint.Old == b && hint.New == a) {
+			return true, !hint.Negated
+		}
+	}
+	return false, false
+}
+
+// renamePair is a rename found between an object dropped and an object created
+type renamePair struct {
+	drop, create int
+	hinted       bool
+}
+
+// pairRenames pairs the dropped and created objects, named after drops and
+// creates respectively, which were renamed.
+//
+// Pairs confirmed by a hint are returned first. Then a dropped object is
+// guessed to be renamed to a created one when both are identical, unless a
+// hint denies it. Only unambiguous guesses are returned: a dropped object
+// identical to several created ones (or the other way around) is not paired.
+func pairRenames(drops, creates []string, identical func(drop, create int) bool, hinted func(drop, create string) (found, renamed bool)) []renamePair {
+	var pairs []renamePair
+	paired := map[int]bool{}
+	created := map[int]bool{}
+	for i, d := range drops {
+		for j, c := range creates {
+			if created[j] {
+				continue
+			}
+			if found, renamed := hinted(d, c); found && renamed {
+				pairs = append(pairs, renamePair{drop: i, create: j, hinted: true})
+				paired[i] = true
+				created[j] = true
+				break
+			}
+		}
+	}
+
+	candidates := func(i int) (matches []int) {
+		for j, c := range creates {
+			if created[j] {
+				continue
+			}
+			if found, _ := hinted(drops[i], c); !found && identical(i, j) {
+				matches = append(matches, j)
+			}
+		}
+		return
+	}
+	for i := range drops {
+		if paired[i] {
+			continue
+		}
+		matches := candidates(i)
+		if len(matches) != 1 {
+			continue
+		}
+		j := matches[0]
+		var ambiguous bool
+		for k := range drops {
+			if k != i && !paired[k] && identical(k, j) {
+				if found, _ := hinted(drops[k], creates[j]); !found {
+					ambiguous = true
+					break
+				}
+			}
+		}
+		if !ambiguous {
+			pairs = append(pairs, renamePair{drop: i, create: j})
+			paired[i] = true
+			created[j] = true
+		}
+	}
+	return pairs
+}
+
+// detectTableRenames replaces the DROP TABLE and CREATE TABLE diffs of the
+// tables that were renamed by a RenameTableDiff. If a hinted rename also
+// changed the definition of the table, the RenameTableDiff is followed by
+// a TableDiff altering the renamed table.
+func detectTableRenames(ods []tengo.ObjectDiff, hints RenameHints) []tengo.ObjectDiff {
+	var drops, creates []*TableDiff
+	for _, od := range ods {
+		if td, ok := od.(*TableDiff); ok {
+			switch td.DiffType() {
+			case tengo.DiffTypeDrop:
+				drops = append(drops, td)
+			case tengo.DiffTypeCreate:
+				creates = append(creates, td)
+			}
+		}
+	}
+	if len(drops) == 0 || len(creates) == 0 {
+		return ods
+	}
+	sort.Slice(drops, func(i, j int) bool { return drops[i].From.Name < drops[j].From.Name })
+	sort.Slice(creates, func(i, j int) bool { return creates[i].To.Name < creates[j].To.Name })
+
+	dropNames := make([]string, len(drops))
+	for i, td := range drops {
+		dropNames[i] = td.From.Name
+	}
+	createNames := make([]string, len(creates))
+	for i, td := range creates {
+		createNames[i] = td.To.Name
+	}
+	identical := func(i, j int) bool {
+		from, _ := tengo.ParseCreateAutoInc(renamedTable(drops[i].From, creates[j].To.Name).CreateStatement)
+		to, _ := tengo.ParseCreateAutoInc(creates[j].To.CreateStatement)
+		return from == to
+	}
+	hinted := func(drop, create string) (bool, bool) {
+		return hints.lookup(tengo.ObjectTypeTable, "", drop, create)
+	}
+	pairs := pairRenames(dropNames, createNames, identical, hinted)
+	if len(pairs) == 0 {
+		return ods
+	}
+
+	renames := map[*TableDiff][]tengo.ObjectDiff{}
+	removed := map[*TableDiff]bool{}
+	for _, p := range pairs {
+		from, to := drops[p.drop].From, creates[p.create].To
+		renames[drops[p.drop]] = []tengo.ObjectDiff{&RenameTableDiff{From: from, To: to, Hinted: p.hinted}}
+		if alter := tengo.NewAlterTable(renamedTable(from, to.Name), to); alter != nil && !onlyAutoIncrement(alter) {
+			renames[drops[p.drop]] = append(renames[drops[p.drop]], &TableDiff{TableDiff: alter, FromFlavor: drops[p.drop].FromFlavor, ToFlavor: creates[p.create].ToFlavor, StrictForeignKeyNaming: drops[p.drop].StrictForeignKeyNaming})
+		}
+		removed[creates[p.create]] = true
+	}
+
+	res := make([]tengo.ObjectDiff, 0, len(ods))
+	for _, od := range ods {
+		if td, ok := od.(*TableDiff); ok {
+			if replacement, ok := renames[td]; ok {
+				res = append(res, replacement...)
+				continue
+			}
+			if removed[td] {
+				continue
+			}
+		}
+		res = append(res, od)
+	}
+	return res
+}
+
+// detectColumnRenames replaces the DropColumn and AddColumn clauses of the
+// columns that were renamed in the given table diff by a RenameColumn.
+//
+// The rename takes the place of the AddColumn, so it is positioned after the
+// columns it's supposed to follow. Index drops and adds that only existed
+// because the indexed column was renamed are removed, as MySQL updates the
+// index when renaming the column.
+func detectColumnRenames(td *TableDiff, hints RenameHints) {
+	if td.DiffType() != tengo.DiffTypeAlter {
+		return
+	}
+	clauses := td.AlterClauses()
+	var drops []tengo.DropColumn
+	var adds []tengo.AddColumn
+	for _, c := range clauses {
+		switch c := c.(type) {
+		case tengo.DropColumn:
+			drops = append(drops, c)
+		case tengo.AddColumn:
+			adds = append(adds, c)
+		}
+	}
+	if len(drops) == 0 || len(adds) == 0 {
+		return
+	}
+
+	dropNames := make([]string, len(drops))
+	for i, dc := range drops {
+		dropNames[i] = dc.Column.Name
+	}
+	addNames := make([]string, len(adds))
+	for i, ac := range adds {
+		addNames[i] = ac.Column.Name
+	}
+	identical := func(i, j int) bool {
+		renamed := *drops[i].Column
+		renamed.Name = adds[j].Column.Name
+		return renamed.Equals(adds[j].Column)
+	}
+	hinted := func(drop, add string) (bool, bool) {
+		if found, renamed := hints.lookup(columnObjectType, td.From.Name, drop, add); found {
+			return found, renamed
+		}
+		return hints.lookup(columnObjectType, td.To.Name, drop, add)
+	}
+	pairs := pairRenames(dropNames, addNames, identical, hinted)
+	if len(pairs) == 0 {
+		return
+	}
+
+	renamedDrops := map[string]bool{}
+	renamedAdds := map[string]RenameColumn{}
+	newNames := map[string]string{}
+	for _, p := range pairs {
+		dc, ac := drops[p.drop], adds[p.create]
+		renamedDrops[dc.Column.Name] = true
+		renamedAdds[ac.Column.Name] = RenameColumn{
+			Table:         ac.Table,
+			OldColumn:     dc.Column,
+			NewColumn:     ac.Column,
+			PositionFirst: ac.PositionFirst,
+			PositionAfter: ac.PositionAfter,
+			Hinted:        p.hinted,
+		}
+		newNames[dc.Column.Name] = ac.Column.Name
+	}
+
+	res := make([]tengo.TableAlterClause, 0, len(clauses))
+	for _, c := range clauses {
+		switch c := c.(type) {
+		case tengo.DropColumn:
+			if renamedDrops[c.Column.Name] {
+				continue
+			}
+		case tengo.AddColumn:
+			if rc, ok := renamedAdds[c.Column.Name]; ok {
+				res = append(res, rc)
+				continue
+			}
+		}
+		res = append(res, c)
+	}
+	td.SetAlterClauses(withoutRenamedIndexes(res, newNames))
+}
+
+// withoutRenamedIndexes removes the DropIndex and AddIndex clauses of an index
+// whose only difference is that some of its columns were renamed.
+func withoutRenamedIndexes(clauses []tengo.TableAlterClause, newNames map[string]string) []tengo.TableAlterClause {
+	adds := map[string]*tengo.Index{}
+	for _, c := range clauses {
+		if ai, ok := c.(tengo.AddIndex); ok {
+			adds[ai.Index.Name] = ai.Index
+		}
+	}
+	unchanged := map[string]bool{}
+	for _, c := range clauses {
+		if di, ok := c.(tengo.DropIndex); ok {
+			if add, ok := adds[di.Index.Name]; ok && sameIndexAfterRename(di.Index, add, newNames) {
+				unchanged[di.Index.Name] = true
+			}
+		}
+	}
+
+	res := make([]tengo.TableAlterClause, 0, len(clauses))
+	for _, c := range clauses {
+		switch c := c.(type) {
+		case tengo.DropIndex:
+			if unchanged[c.Index.Name] {
+				continue
+			}
+		case tengo.AddIndex:
+			if unchanged[c.Index.Name] {
+				continue
+			}
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+func sameIndexAfterRename(from, to *tengo.Index, newNames map[string]string) bool {
+	if from.PrimaryKey != to.PrimaryKey || from.Unique != to.Unique || from.Comment != to.Comment || len(from.Columns) != len(to.Columns) {
+		return false
+	}
+	for n, col := range from.Columns {
+		name := col.Name
+		if newName, ok := newNames[name]; ok {
+			name = newName
+		}
+		if name != to.Columns[n].Name || from.SubParts[n] != to.SubParts[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// onlyAutoIncrement returns true if the only difference found by a tengo
+// table diff is the next auto-increment value of the table.
+func onlyAutoIncrement(td *tengo.TableDiff) bool {
+	for _, c := range (&TableDiff{TableDiff: td}).AlterClauses() {
+		if _, ok := c.(tengo.ChangeAutoIncrement); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// renamedTable returns a copy of table t named after the given name.
+func renamedTable(t *tengo.Table, name string) *tengo.Table {
+	renamed := *t
+	renamed.Name = name
+	renamed.CreateStatement = strings.Replace(t.CreateStatement, "CREATE TABLE "+tengo.EscapeIdentifier(t.Name), "CREATE TABLE "+tengo.EscapeIdentifier(name), 1)
+	return &renamed
+}
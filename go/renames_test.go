@@ -0,0 +1,97 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestParseRenameHints(t *testing.T) {
+	hints, err := ParseRenameHints(strings.NewReader(`
+		# renamed in 20190901000000_rename_tasks.rb
+		table tasks todos
+		column todos.title name
+		!table logs logs_archive
+	`))
+	Nil(t, err)
+	Equal(t, RenameHints{
+		{Type: tengo.ObjectTypeTable, Old: "tasks", New: "todos"},
+		{Type: columnObjectType, Table: "todos", Old: "title", New: "name"},
+		{Type: tengo.ObjectTypeTable, Old: "logs", New: "logs_archive", Negated: true},
+	}, hints)
+
+	_, err = ParseRenameHints(strings.NewReader("view tasks todos"))
+	Error(t, err)
+	_, err = ParseRenameHints(strings.NewReader("column title name"))
+	Error(t, err)
+}
+
+func TestDiff_RenameHints(t *testing.T) {
+	sql1 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS logs (
+			id INT AUTO_INCREMENT,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+	}
+
+	sql2 := []string{
+		`CREATE TABLE IF NOT EXISTS todos (
+			id BIGINT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS logs_archive (
+			id INT AUTO_INCREMENT,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+	}
+
+	s1Name, s2Name := TestCluster.LoadSchemas(t, sql1, sql2)
+	from := NewServer1Schema(s1Name)
+	to := NewServer2Schema(s2Name)
+
+	diff := NewDiff(DSN1, DSN2, from, to, false, "")
+	diff.RenameHints = RenameHints{
+		{Type: tengo.ObjectTypeTable, Old: "tasks", New: "todos"},
+		{Type: tengo.ObjectTypeTable, Old: "logs", New: "logs_archive", Negated: true},
+	}
+	changes := diff.Changes()
+	kinds := map[ChangeKind]int{}
+	for _, c := range changes {
+		kinds[c.Kind]++
+	}
+	Equal(t, map[ChangeKind]int{
+		ChangeRenameTable:  1,
+		ChangeModifyColumn: 1,
+		ChangeDropTable:    1,
+		ChangeCreateTable:  1,
+	}, kinds)
+
+	for _, c := range changes {
+		if c.Kind == ChangeRenameTable {
+			Equal(t, "tasks", c.Table)
+			Equal(t, "todos", c.RenamedTo)
+			False(t, c.Probable)
+		}
+	}
+}
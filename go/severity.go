@@ -0,0 +1,292 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// Severity tells how much a change can affect the applications using a
+// schema. Severities are ordered, the zero value being lower than any of them.
+type Severity int
+
+// Severities of the changes computed by a Diff, from the lowest to the highest
+const (
+	// SeverityCosmetic changes don't affect the data or how it's accessed,
+	// like comments.
+	SeverityCosmetic Severity = iota + 1
+	// SeverityAdditive changes only add to the schema, like new tables or
+	// nullable columns.
+	SeverityAdditive
+	// SeverityRisky changes may fail on existing data, or alter how it's
+	// stored or accessed, like narrowing the type of a column.
+	SeverityRisky
+	// SeverityBreaking changes remove or rename parts of the schema
+	// applications may rely on, like dropping a column.
+	SeverityBreaking
+)
+
+var severityNames = map[Severity]string{
+	SeverityCosmetic: "cosmetic",
+	SeverityAdditive: "additive",
+	SeverityRisky:    "risky",
+	SeverityBreaking: "breaking",
+}
+
+// ParseSeverity returns the severity with the given name
+func ParseSeverity(name string) (Severity, error) {
+	for s, n := range severityNames {
+		if n == strings.ToLower(name) {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown severity %s, only (cosmetic,additive,risky,breaking) are allowed", name)
+}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// MarshalText encodes the severity as its name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a severity from its name
+func (s *Severity) UnmarshalText(text []byte) error {
+	severity, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+// kindSeverities are the severities of the kinds of changes that don't
+// depend on the details of the change, and of the ones that do when the
+// change has no Origin to look at, see Change.severity.
+var kindSeverities = map[ChangeKind]Severity{
+	ChangeCreateTable:        SeverityAdditive,
+	ChangeDropTable:          SeverityBreaking,
+	ChangeRenameTable:        SeverityBreaking,
+	ChangeAlterTable:         SeverityRisky,
+	ChangeAddColumn:          SeverityAdditive,
+	ChangeModifyColumn:       SeverityRisky,
+	ChangeDropColumn:         SeverityBreaking,
+	ChangeRenameColumn:       SeverityBreaking,
+	ChangeColumnGeneration:   SeverityRisky,
+	ChangeMoveColumn:         SeverityRisky,
+	ChangeAddIndex:           SeverityAdditive,
+	ChangeDropIndex:          SeverityRisky,
+	ChangeModifyIndex:        SeverityRisky,
+	ChangeAddForeignKey:      SeverityRisky,
+	ChangeDropForeignKey:     SeverityRisky,
+	ChangeModifyForeignKey:   SeverityRisky,
+	ChangeAddCheck:           SeverityRisky,
+	ChangeDropCheck:          SeverityRisky,
+	ChangeModifyCheck:        SeverityRisky,
+	ChangeCharSet:            SeverityRisky,
+	ChangeCreateOptions:      SeverityRisky,
+	ChangeComment:            SeverityCosmetic,
+	ChangeStorageEngine:      SeverityRisky,
+	ChangeAlterDatabase:      SeverityRisky,
+	ChangeCreateRoutine:      SeverityAdditive,
+	ChangeDropRoutine:        SeverityBreaking,
+	ChangeCreateTrigger:      SeverityRisky,
+	ChangeDropTrigger:        SeverityRisky,
+	ChangeAlterTrigger:       SeverityRisky,
+	ChangeCreateView:         SeverityAdditive,
+	ChangeDropView:           SeverityBreaking,
+	ChangeAlterView:          SeverityRisky,
+	ChangeCreateEvent:        SeverityAdditive,
+	ChangeDropEvent:          SeverityRisky,
+	ChangeAlterEvent:         SeverityRisky,
+	ChangePartitioning:       SeverityRisky,
+	ChangeRemovePartitioning: SeverityRisky,
+	ChangeAddPartition:       SeverityAdditive,
+	ChangeDropPartition:      SeverityBreaking,
+	ChangeModifyPartition:    SeverityRisky,
+}
+
+// severity returns the severity of the change. Added columns and indexes,
+// and modified columns, are classified according to their Origin.
+func (c Change) severity() Severity {
+	switch origin := c.Origin.(type) {
+	case tengo.AddColumn:
+		return addedColumnSeverity(origin.Column)
+	case tengo.ModifyColumn:
+		return modifiedColumnSeverity(origin.OldColumn, origin.NewColumn)
+	case AlterColumn:
+		if c.Kind == ChangeModifyColumn || c.Kind == ChangeAddColumn {
+			return Change{Kind: c.Kind, Origin: origin.Alter}.severity()
+		}
+	case tengo.AddIndex:
+		if origin.Index.Unique {
+			return SeverityRisky
+		}
+		return SeverityAdditive
+	case AlterIndex:
+		if c.Kind == ChangeAddIndex && origin.To.Unique {
+			return SeverityRisky
+		}
+		if c.Kind == ChangeAddIndex {
+			return SeverityAdditive
+		}
+	}
+	return kindSeverities[c.Kind]
+}
+
+// addedColumnSeverity returns the severity of adding a column, which is risky
+// if the column must be given a value in every INSERT statement.
+func addedColumnSeverity(col *tengo.Column) Severity {
+	if !col.Nullable && !col.AutoIncrement && col.Default == (tengo.ColumnDefault{}) {
+		return SeverityRisky
+	}
+	return SeverityAdditive
+}
+
+// modifiedColumnSeverity returns the severity of modifying a column: cosmetic
+// if only its comment changes, additive if it's only made nullable or its type
+// widened, and risky otherwise.
+func modifiedColumnSeverity(from, to *tengo.Column) Severity {
+	if onlyCommentDiffers(from, to) {
+		return SeverityCosmetic
+	}
+	widened := *to
+	widened.Comment = from.Comment
+	if widensType(from.TypeInDB, to.TypeInDB) {
+		widened.TypeInDB = from.TypeInDB
+	}
+	if to.Nullable && !from.Nullable {
+		widened.Nullable = false
+		if to.Default == tengo.ColumnDefaultNull {
+			widened.Default = from.Default
+		}
+	}
+	if widened != *to && widened.Equals(from) {
+		return SeverityAdditive
+	}
+	return SeverityRisky
+}
+
+// columnType matches the type of a column, capturing its name, its arguments
+// and its attributes, i.e. int(10) unsigned or varchar(255).
+var columnType = regexp.MustCompile(`^(\w+)(?:\((.*)\))?((?: \w+)*)$`)
+
+// typeRanks orders the integer, text and blob types by the values they hold
+var typeRanks = map[string]int{
+	"tinyint": 1, "smallint": 2, "mediumint": 3, "int": 4, "bigint": 5,
+	"tinytext": 1, "text": 2, "mediumtext": 3, "longtext": 4,
+	"tinyblob": 1, "blob": 2, "mediumblob": 3, "longblob": 4,
+}
+
+// widensType returns true if a column of type to holds every value of a column
+// of type from, and more.
+func widensType(from, to string) bool {
+	f, t := columnType.FindStringSubmatch(from), columnType.FindStringSubmatch(to)
+	if f == nil || t == nil || f[3] != t[3] {
+		return false
+	}
+	family := func(name string) string {
+		switch {
+		case strings.HasSuffix(name, "int"):
+			return "int"
+		case strings.HasSuffix(name, "text"):
+			return "text"
+		case strings.HasSuffix(name, "blob"):
+			return "blob"
+		}
+		return name
+	}
+	if family(f[1]) != family(t[1]) {
+		return false
+	}
+	switch family(f[1]) {
+	case "int", "text", "blob":
+		return typeRanks[t[1]] > typeRanks[f[1]]
+	case "char", "varchar", "binary", "varbinary":
+		fromLength, _ := strconv.Atoi(f[2])
+		toLength, _ := strconv.Atoi(t[2])
+		return toLength > fromLength
+	case "decimal":
+		fromArgs, toArgs := strings.Split(f[2], ","), strings.Split(t[2], ",")
+		if len(fromArgs) != 2 || len(toArgs) != 2 {
+			return false
+		}
+		fromPrecision, _ := strconv.Atoi(fromArgs[0])
+		fromScale, _ := strconv.Atoi(fromArgs[1])
+		toPrecision, _ := strconv.Atoi(toArgs[0])
+		toScale, _ := strconv.Atoi(toArgs[1])
+		return toScale >= fromScale && toPrecision-toScale >= fromPrecision-fromScale && toPrecision > fromPrecision
+	case "enum", "set":
+		// values appended to the list don't change the existing ones
+		return strings.HasPrefix(t[2], f[2]+",")
+	}
+	return false
+}
+
+// maxSeverity returns the highest severity of the given changes
+func maxSeverity(changes []Change) Severity {
+	var res Severity
+	for _, c := range changes {
+		if c.Severity > res {
+			res = c.Severity
+		}
+	}
+	return res
+}
+
+// objectSeverity returns the highest severity of the changes of an object
+// diff. Unlike changes, it accounts for the clauses adding back modified
+// foreign keys, so the statements adding them are given a severity.
+func objectSeverity(od tengo.ObjectDiff) Severity {
+	td, ok := od.(*TableDiff)
+	if !ok || td.DiffType() != tengo.DiffTypeAlter || td.AlterClauses() == nil {
+		return maxSeverity(changes([]tengo.ObjectDiff{od}))
+	}
+	var res Severity
+	for _, c := range td.AlterClauses() {
+		change, _ := clauseChange(c, td)
+		change.Origin = c
+		if change.Kind != "" && change.severity() > res {
+			res = change.severity()
+		}
+	}
+	return res
+}
+
+// Summary returns a one-line summary of the changes, counting them by
+// severity from the highest to the lowest, i.e. "Differences found (3):
+// 1 breaking, 2 risky", or "No differences found".
+func Summary(changes []Change) string {
+	if len(changes) == 0 {
+		return "No differences found"
+	}
+	counts := map[Severity]int{}
+	for _, c := range changes {
+		counts[c.Severity]++
+	}
+	var parts []string
+	for s := SeverityBreaking; s >= SeverityCosmetic; s-- {
+		if counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+	return fmt.Sprintf("Differences found (%d): %s", len(changes), strings.Join(parts, ", "))
+}
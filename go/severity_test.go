@@ -0,0 +1,95 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestModifiedColumnSeverity(t *testing.T) {
+	column := func(typ string, nullable bool, comment string) *tengo.Column {
+		col := &tengo.Column{Name: "c", TypeInDB: typ, Nullable: nullable, Comment: comment}
+		if nullable {
+			col.Default = tengo.ColumnDefaultNull
+		}
+		return col
+	}
+	tests := map[string]struct {
+		from, to *tengo.Column
+		expected Severity
+	}{
+		"Comment":            {column("int", false, ""), column("int", false, "the c"), SeverityCosmetic},
+		"Wider integer":      {column("int(11)", false, ""), column("bigint(20)", false, ""), SeverityAdditive},
+		"Narrower integer":   {column("bigint", false, ""), column("int", false, ""), SeverityRisky},
+		"Signedness":         {column("int unsigned", false, ""), column("bigint", false, ""), SeverityRisky},
+		"Longer varchar":     {column("varchar(10)", false, ""), column("varchar(20)", false, ""), SeverityAdditive},
+		"Shorter varchar":    {column("varchar(20)", false, ""), column("varchar(10)", false, ""), SeverityRisky},
+		"Char to varchar":    {column("char(10)", false, ""), column("varchar(10)", false, ""), SeverityRisky},
+		"Wider decimal":      {column("decimal(10,2)", false, ""), column("decimal(12,3)", false, ""), SeverityAdditive},
+		"Less scale":         {column("decimal(10,2)", false, ""), column("decimal(12,1)", false, ""), SeverityRisky},
+		"Appended value":     {column("enum('a','b')", false, ""), column("enum('a','b','c')", false, ""), SeverityAdditive},
+		"Removed value":      {column("enum('a','b')", false, ""), column("enum('a')", false, ""), SeverityRisky},
+		"Nullable":           {column("int", false, ""), column("int", true, ""), SeverityAdditive},
+		"Not nullable":       {column("int", true, ""), column("int", false, ""), SeverityRisky},
+		"Wider and nullable": {column("int", false, ""), column("bigint", true, "the c"), SeverityAdditive},
+		"Unchanged":          {column("int", false, ""), column("int", false, ""), SeverityRisky},
+	}
+	for name, test := range tests {
+		Equal(t, test.expected, modifiedColumnSeverity(test.from, test.to), name)
+	}
+}
+
+func TestChangeSeverity(t *testing.T) {
+	id := &tengo.Column{Name: "id", TypeInDB: "int", AutoIncrement: true}
+	title := &tengo.Column{Name: "title", TypeInDB: "varchar(255)"}
+	tests := map[string]struct {
+		change   Change
+		expected Severity
+	}{
+		"Added auto-increment column":    {Change{Kind: ChangeAddColumn, Origin: tengo.AddColumn{Column: id}}, SeverityAdditive},
+		"Added required column":          {Change{Kind: ChangeAddColumn, Origin: tengo.AddColumn{Column: title}}, SeverityRisky},
+		"Added column without origin":    {Change{Kind: ChangeAddColumn}, SeverityAdditive},
+		"Modified column":                {Change{Kind: ChangeModifyColumn, Origin: tengo.ModifyColumn{OldColumn: id, NewColumn: id}}, SeverityRisky},
+		"Modified column without origin": {Change{Kind: ChangeModifyColumn}, SeverityRisky},
+		"Added unique index":             {Change{Kind: ChangeAddIndex, Origin: tengo.AddIndex{Index: &tengo.Index{Unique: true}}}, SeverityRisky},
+		"Added index":                    {Change{Kind: ChangeAddIndex, Origin: tengo.AddIndex{Index: &tengo.Index{}}}, SeverityAdditive},
+		"Added index without origin":     {Change{Kind: ChangeAddIndex}, SeverityAdditive},
+		"Dropped table":                  {Change{Kind: ChangeDropTable}, SeverityBreaking},
+	}
+	for name, test := range tests {
+		Equal(t, test.expected, test.change.severity(), name)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	Equal(t, "No differences found", Summary([]Change{}))
+	Equal(t, "Differences found (3): 1 breaking, 2 additive", Summary([]Change{
+		{Kind: ChangeAddColumn, Severity: SeverityAdditive},
+		{Kind: ChangeDropTable, Severity: SeverityBreaking},
+		{Kind: ChangeCreateTable, Severity: SeverityAdditive},
+	}))
+}
+
+func TestParseSeverity(t *testing.T) {
+	severity, err := ParseSeverity("Breaking")
+	Nil(t, err)
+	Equal(t, SeverityBreaking, severity)
+	True(t, SeverityRisky < severity)
+
+	_, err = ParseSeverity("urgent")
+	NotNil(t, err)
+}
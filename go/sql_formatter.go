@@ -13,12 +13,139 @@
 
 package mydiff
 
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
 // SQLFormatter formats a Diff in SQL format
 // (ALTER, CREATE and DROP statements)
 type SQLFormatter struct{}
 
 // Format formats a diff returning a slice of string commands, each of
 // which is an SQL ALTER, CREATE or DROP statement.
+//
+// Like tengo.SchemaDiff.String, no statement modifiers are applied other
+// than the tables ignored by the diff filter, the naming of foreign keys and
+// the flavor of the server denoted by DSN1, where the statements are run, and
+// errors returned by the statements are ignored. Unlike it, the statements
+// come from Diff.Compute, so renames are emitted as such rather than as a
+// DROP and a CREATE.
+//
+// Each statement is preceded by a comment with the highest severity of the
+// changes it applies, and all of them by the warnings of the diff, if any.
 func (f *SQLFormatter) Format(diff *Diff) interface{} {
-	return diff.Raw().String()
+	var buf bytes.Buffer
+	buf.WriteString(sqlWarnings(diff))
+	buf.WriteString(sqlStatements(diff, delimitedStatement))
+	return buf.String()
+}
+
+// sqlWarnings returns the warnings of a diff as SQL comments, one per line.
+func sqlWarnings(diff *Diff) string {
+	var buf bytes.Buffer
+	for _, w := range diff.Warnings() {
+		buf.WriteString(fmt.Sprintf("-- Warning: %s\n", w))
+	}
+	return buf.String()
+}
+
+// sqlStatements returns the statements of SQLFormatter.Format, without the
+// warnings of the diff, for the formatters that report them apart. Each
+// statement is terminated by terminate, either delimitedStatement, for the
+// mysql client, or terminated, for the tools running the statements as they
+// are.
+func sqlStatements(diff *Diff, terminate func(od tengo.ObjectDiff, stmt string) string) string {
+	var buf bytes.Buffer
+	mods := tengo.StatementModifiers{IgnoreTable: diff.Filter.IgnoreTable(), StrictForeignKeyNaming: diff.StrictForeignKeyNaming, Flavor: diff.Flavor1}
+	for _, od := range diff.Compute() {
+		if _, ok := od.(*MigrationsDiff); ok {
+			continue
+		}
+		if stmts := statements(od, mods); len(stmts) > 0 {
+			buf.WriteString(severityComment(objectSeverity(od)))
+			for _, stmt := range stmts {
+				buf.WriteString(terminate(od, stmt))
+			}
+		}
+	}
+	return buf.String()
+}
+
+// statements returns the statements applying an object diff. Unlike
+// tengo.ObjectDiff.Statement, it accounts for the diffs of triggers and
+// events, which may take several statements.
+func statements(od tengo.ObjectDiff, mods tengo.StatementModifiers) []string {
+	switch od := od.(type) {
+	case *TriggerDiff:
+		return od.Statements()
+	case *EventDiff:
+		return od.Statements()
+	}
+	if stmt, _ := od.Statement(mods); stmt != "" {
+		return []string{stmt}
+	}
+	return nil
+}
+
+// reversedDiff returns the diff undoing an object diff, or nil if it cannot
+// be reversed by swapping its sides, as it happens with table diffs.
+func reversedDiff(od tengo.ObjectDiff) tengo.ObjectDiff {
+	switch od := od.(type) {
+	case *tengo.RoutineDiff:
+		return &tengo.RoutineDiff{From: od.To, To: od.From, ForMetadata: od.ForMetadata}
+	case *tengo.DatabaseDiff:
+		return &tengo.DatabaseDiff{From: od.To, To: od.From}
+	case *TriggerDiff:
+		return &TriggerDiff{From: od.To, To: od.From}
+	case *ViewDiff:
+		return &ViewDiff{From: od.To, To: od.From}
+	case *EventDiff:
+		return &EventDiff{From: od.To, To: od.From}
+	case *PartitionDiff:
+		return &PartitionDiff{Table: od.Table, From: od.To, To: od.From, Dropped: od.Added, Added: od.Dropped}
+	}
+	return nil
+}
+
+// severityComment returns the comment preceding a statement with the given
+// severity, if any.
+func severityComment(severity Severity) string {
+	if severity == 0 {
+		return ""
+	}
+	return fmt.Sprintf("-- Severity: %s\n", severity)
+}
+
+// delimitedStatement terminates a statement of an object diff with a
+// semicolon, unless the statement defines the body of a trigger, routine or
+// event, which can be a BEGIN ... END block with semicolons of its own. Then,
+// the statement is terminated by a different delimiter, so it can be run by
+// the mysql client.
+func delimitedStatement(od tengo.ObjectDiff, stmt string) string {
+	if !definesBody(od, stmt) {
+		return terminated(od, stmt)
+	}
+	return fmt.Sprintf("DELIMITER //\n%s//\nDELIMITER ;\n", stmt)
+}
+
+// terminated terminates a statement of an object diff with a semicolon, even
+// if it defines the body of a trigger, routine or event, for the tools that
+// send each statement to the server as it is, rather than splitting them by
+// their delimiters like the mysql client does.
+func terminated(od tengo.ObjectDiff, stmt string) string {
+	return fmt.Sprintf("%s;\n", stmt)
+}
+
+// definesBody returns true if stmt, one of the statements of an object diff,
+// creates or alters a trigger, routine or event.
+func definesBody(od tengo.ObjectDiff, stmt string) bool {
+	switch od.(type) {
+	case *TriggerDiff, *tengo.RoutineDiff, *EventDiff:
+		return strings.HasPrefix(stmt, "CREATE ") || strings.HasPrefix(stmt, "ALTER ")
+	}
+	return false
 }
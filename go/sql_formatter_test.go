@@ -46,7 +46,9 @@ func TestSQLFormatter_Format(t *testing.T) {
 		)  ENGINE=INNODB;`,
 	}
 
-	expected := `ALTER TABLE "tasks" MODIFY COLUMN "id" bigint(20) NOT NULL AUTO_INCREMENT, MODIFY COLUMN "title" varchar(255) NOT NULL, ADD COLUMN "owner_id" int(11) DEFAULT NULL;
+	expected := `-- Severity: risky
+ALTER TABLE "tasks" MODIFY COLUMN "id" bigint(20) NOT NULL AUTO_INCREMENT, MODIFY COLUMN "title" varchar(255) NOT NULL, ADD COLUMN "owner_id" int(11) DEFAULT NULL;
+-- Severity: additive
 CREATE TABLE "owners" (
   "id" int(11) NOT NULL AUTO_INCREMENT,
   "name" varchar(255) NOT NULL,
@@ -59,3 +61,219 @@ CREATE TABLE "owners" (
 
 	Equal(t, expected, sql)
 }
+
+func TestSQLFormatter_Format_Renames(t *testing.T) {
+	schema1 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			title VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS owners (
+			id INT AUTO_INCREMENT,
+			name VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+	}
+
+	schema2 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			name VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TABLE IF NOT EXISTS users (
+			id INT AUTO_INCREMENT,
+			name VARCHAR(255) NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+	}
+
+	sqlFmt, _ := NewFormatter("sql")
+	sql := RunDiff(t, schema1, schema2, sqlFmt)
+
+	Contains(t, sql, "ALTER TABLE `tasks` CHANGE COLUMN `title` `name` varchar(255) NOT NULL;\n")
+	Contains(t, sql, "RENAME TABLE `owners` TO `users`;\n")
+	NotContains(t, sql, "DROP")
+}
+
+func TestSQLFormatter_Format_Triggers(t *testing.T) {
+	schema1 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TRIGGER tasks_audit AFTER INSERT ON tasks FOR EACH ROW SET @last_task = NEW.id;`,
+	}
+
+	schema2 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE TRIGGER tasks_audit AFTER INSERT ON tasks FOR EACH ROW
+		BEGIN
+			SET @last_task = NEW.id;
+			SET @tasks = @tasks + 1;
+		END;`,
+	}
+
+	sqlFmt, _ := NewFormatter("sql")
+	sql := RunDiff(t, schema1, schema2, sqlFmt)
+
+	Regexp(t, "^DROP TRIGGER IF EXISTS `tasks_audit`;\n", sql)
+	Regexp(t, "\nSET SESSION sql_mode = '[A-Z_,]*';\n", sql)
+	Regexp(t, "\nDELIMITER //\nCREATE DEFINER=`[^`]+`@`[^`]+` TRIGGER `tasks_audit` AFTER INSERT ON `tasks` FOR EACH ROW BEGIN\n", sql)
+	Regexp(t, "\n\t\tEND//\nDELIMITER ;\nSET SESSION sql_mode = @mydiff_sql_mode;\n$", sql)
+}
+
+func TestSQLFormatter_Format_Views(t *testing.T) {
+	schema1 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			done TINYINT NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE VIEW done_tasks AS SELECT id FROM tasks WHERE done = 1;`,
+		`CREATE VIEW pending_tasks AS SELECT id FROM tasks WHERE done = 0;`,
+	}
+
+	schema2 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT,
+			done TINYINT NOT NULL,
+			PRIMARY KEY (id)
+		)  ENGINE=INNODB;`,
+		`CREATE VIEW pending_tasks AS SELECT id, done FROM tasks WHERE done = 0;`,
+	}
+
+	sqlFmt, _ := NewFormatter("sql")
+	sql := RunDiff(t, schema1, schema2, sqlFmt)
+
+	Contains(t, sql, "DROP VIEW IF EXISTS `done_tasks`;\n")
+	Regexp(t, "CREATE OR REPLACE ALGORITHM=UNDEFINED DEFINER=`[^`]+`@`[^`]+` SQL SECURITY DEFINER VIEW `pending_tasks` AS select `tasks`.`id` AS `id`,`tasks`.`done` AS `done` from `tasks` where \\(`tasks`.`done` = 0\\);\n", sql)
+	NotContains(t, sql, "schema2_")
+}
+
+func TestSQLFormatter_Format_Events(t *testing.T) {
+	schema1 := []string{
+		`CREATE EVENT purge_tasks ON SCHEDULE EVERY 1 DAY DO DELETE FROM tasks;`,
+		`CREATE EVENT rotate_tasks ON SCHEDULE EVERY 1 DAY DO DELETE FROM tasks;`,
+	}
+
+	schema2 := []string{
+		`CREATE EVENT purge_tasks ON SCHEDULE EVERY 1 DAY DISABLE COMMENT 'see #42' DO DELETE FROM tasks;`,
+	}
+
+	sqlFmt, _ := NewFormatter("sql")
+	sql := RunDiff(t, schema1, schema2, sqlFmt)
+
+	Regexp(t, "\nDELIMITER //\nALTER DEFINER=`[^`]+`@`[^`]+` EVENT `purge_tasks` ON SCHEDULE EVERY '1' DAY STARTS '[0-9: -]+' ON COMPLETION NOT PRESERVE DISABLE COMMENT 'see #42' DO DELETE FROM tasks//\nDELIMITER ;\nSET SESSION sql_mode = @mydiff_sql_mode;\n", sql)
+	Contains(t, sql, "DROP EVENT IF EXISTS `rotate_tasks`;\n")
+}
+
+func TestSQLFormatter_Format_Partitions(t *testing.T) {
+	schema1 := []string{
+		`CREATE TABLE IF NOT EXISTS events (
+			id INT NOT NULL,
+			year INT NOT NULL,
+			PRIMARY KEY (id, year)
+		)  ENGINE=INNODB
+		PARTITION BY RANGE (year) (
+			PARTITION p2018 VALUES LESS THAN (2019),
+			PARTITION p2019 VALUES LESS THAN (2020),
+			PARTITION pmax VALUES LESS THAN MAXVALUE
+		);`,
+	}
+
+	schema2 := []string{
+		`CREATE TABLE IF NOT EXISTS events (
+			id INT NOT NULL,
+			year INT NOT NULL,
+			PRIMARY KEY (id, year)
+		)  ENGINE=INNODB
+		PARTITION BY RANGE (year) (
+			PARTITION p2019 VALUES LESS THAN (2020),
+			PARTITION p2020 VALUES LESS THAN (2021),
+			PARTITION pmax VALUES LESS THAN MAXVALUE
+		);`,
+	}
+
+	expected := `-- Severity: breaking
+ALTER TABLE "events" DROP PARTITION "p2018";
+-- Severity: additive
+ALTER TABLE "events" REORGANIZE PARTITION "pmax" INTO (PARTITION p2020 VALUES LESS THAN (2021) ENGINE = InnoDB, PARTITION pmax VALUES LESS THAN MAXVALUE ENGINE = InnoDB);
+`
+	expected = strings.ReplaceAll(expected, "\"", "`")
+	sqlFmt, _ := NewFormatter("sql")
+	sql := RunDiff(t, schema1, schema2, sqlFmt)
+
+	Equal(t, expected, sql)
+}
+
+func TestSQLFormatter_Format_ListPartitions(t *testing.T) {
+	schema1 := []string{
+		`CREATE TABLE IF NOT EXISTS stores (
+			id INT NOT NULL,
+			region INT NOT NULL,
+			PRIMARY KEY (id, region)
+		)  ENGINE=INNODB
+		PARTITION BY LIST (region) (
+			PARTITION north VALUES IN (1, 2),
+			PARTITION south VALUES IN (3, 4)
+		);`,
+	}
+
+	schema2 := []string{
+		`CREATE TABLE IF NOT EXISTS stores (
+			id INT NOT NULL,
+			region INT NOT NULL,
+			PRIMARY KEY (id, region)
+		)  ENGINE=INNODB
+		PARTITION BY LIST (region) (
+			PARTITION north VALUES IN (1, 2),
+			PARTITION east VALUES IN (5, 6),
+			PARTITION south VALUES IN (3, 4)
+		);`,
+	}
+
+	expected := `-- Severity: additive
+ALTER TABLE "stores" ADD PARTITION (PARTITION east VALUES IN (5,6) ENGINE = InnoDB);
+`
+	expected = strings.ReplaceAll(expected, "\"", "`")
+	sqlFmt, _ := NewFormatter("sql")
+	sql := RunDiff(t, schema1, schema2, sqlFmt)
+
+	Equal(t, expected, sql)
+}
+
+func TestSQLFormatter_Format_ForeignKeys(t *testing.T) {
+	schema1 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id BIGINT AUTO_INCREMENT,
+			parent_id BIGINT NOT NULL,
+			PRIMARY KEY (id),
+			FOREIGN KEY tasks_ibfk_1(parent_id) REFERENCES tasks(id)
+		)  ENGINE=INNODB;`,
+	}
+
+	schema2 := []string{
+		`CREATE TABLE IF NOT EXISTS tasks (
+			id BIGINT AUTO_INCREMENT,
+			parent_id BIGINT NOT NULL,
+			PRIMARY KEY (id),
+			FOREIGN KEY tasks_ibfk_1(parent_id) REFERENCES tasks(id) ON DELETE CASCADE
+		)  ENGINE=INNODB;`,
+	}
+
+	expected := `-- Severity: risky
+ALTER TABLE "tasks" DROP FOREIGN KEY "tasks_ibfk_1";
+-- Severity: risky
+ALTER TABLE "tasks" ADD CONSTRAINT "tasks_ibfk_1" FOREIGN KEY ("parent_id") REFERENCES "tasks" ("id") ON DELETE CASCADE;
+`
+	expected = strings.ReplaceAll(expected, "\"", "`")
+	sqlFmt, _ := NewFormatter("sql")
+	sql := RunDiff(t, schema1, schema2, sqlFmt)
+
+	Equal(t, expected, sql)
+}
@@ -29,6 +29,12 @@ import (
 // is unsafe if the dependency on skeema/tengo is upgraded.
 type TableDiff struct {
 	*tengo.TableDiff
+	// FromFlavor and ToFlavor are the flavors of the servers of the From
+	// and To tables respectively
+	FromFlavor, ToFlavor tengo.Flavor
+	// StrictForeignKeyNaming reports the foreign keys that are only renamed,
+	// see tengo.StatementModifiers.
+	StrictForeignKeyNaming bool
 }
 
 // AlterClauses returns the unexported alterClauses field of the
@@ -43,19 +49,47 @@ type TableDiff struct {
 //	DropIndex
 //	AddForeignKey
 //	DropForeignKey
+//	AlterForeignKey
 //	RenameColumn
 //	ModifyColumn
+//	MoveColumn
 //	ChangeAutoIncrement
 //	ChangeCharSet
 //	ChangeCreateOptions
 //	ChangeComment
 //	ChangeStorageEngine
 func (d *TableDiff) AlterClauses() []tengo.TableAlterClause {
+	return *d.alterClauses()
+}
+
+// SetAlterClauses replaces the alter clauses of the adapted tengo.TableDiff,
+// so the statement generated by tengo reflects any clause mydiff replaced
+// or removed. (i.e. a DropColumn and AddColumn pair replaced by a RenameColumn)
+func (d *TableDiff) SetAlterClauses(clauses []tengo.TableAlterClause) {
+	*d.alterClauses() = clauses
+}
+
+// newAlterTableDiff returns a TableDiff altering table from into table to
+// with the given clauses, for differences that tengo doesn't find, which
+// tengo.NewAlterTable would take as no difference at all. So the diff is
+// created for a copy of table to with a different comment, whose clause is
+// then replaced.
+func newAlterTableDiff(from, to *tengo.Table, clauses []tengo.TableAlterClause, fromFlavor, toFlavor tengo.Flavor) *TableDiff {
+	other := *to
+	other.Comment += " "
+	other.CreateStatement = ""
+	td := &TableDiff{TableDiff: tengo.NewAlterTable(from, &other), FromFlavor: fromFlavor, ToFlavor: toFlavor}
+	td.To = to
+	td.SetAlterClauses(clauses)
+	return td
+}
+
+func (d *TableDiff) alterClauses() *[]tengo.TableAlterClause {
 	val := reflect.ValueOf(d.TableDiff).Elem()
 	f := val.FieldByName("alterClauses")
 
 	// As said above, There's no reason why tengo.TableDiff.alterClauses
 	// is not exported, but until that's fixed in tengo, we return an unsafe
 	// reference to the unexported field.
-	return *reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Interface().(*[]tengo.TableAlterClause)
+	return reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Interface().(*[]tengo.TableAlterClause)
 }
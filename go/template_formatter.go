@@ -0,0 +1,180 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TemplateFormatter formats a diff with a user defined text/template, which
+// is executed with a TemplateData value and can use the functions in
+// TemplateFuncs. The one in AvailableFormatters has no template, so a
+// TemplateFormatter is meant to be created with the template to execute, see
+// ParseTemplate.
+type TemplateFormatter struct {
+	Template *template.Template
+}
+
+// TemplateData is the data model of a diff given to the templates of a
+// TemplateFormatter.
+type TemplateData struct {
+	// From and To are the schemas compared
+	From, To TemplateSchema
+	// Summary counts the changes by severity, see Summary
+	Summary string
+	// Changes are all the changes between the schemas, see Change
+	Changes []Change
+	// Objects are the changes grouped by the object they belong to, in the
+	// order they were computed
+	Objects []TemplateObject
+	// Kinds are the kinds of the changes, in the order they were computed
+	Kinds []ChangeKind
+	// Migrations are the migrations missing in each server, or nil if they
+	// were not diffed or none is missing
+	Migrations *MigrationsDiff
+	Warnings   []string
+	// SQL are the statements reconciling the schemas, see SQLFormatter
+	SQL string
+}
+
+// TemplateSchema describes one of the schemas compared in a TemplateData
+type TemplateSchema struct {
+	Server string
+	Schema string
+	Flavor string
+}
+
+// TemplateObject holds the changes of an object in a TemplateData. Name is
+// the type of the object followed by its name, i.e. table tasks, and
+// Severity the highest severity of its changes.
+type TemplateObject struct {
+	Name     string
+	Severity Severity
+	Changes  []Change
+}
+
+// TemplateFuncs are the functions available to the templates of a
+// TemplateFormatter, besides the predefined ones of text/template:
+//
+//	join     joins a list of strings with a separator: join .Warnings ", "
+//	lower    lower cases a string
+//	upper    upper cases a string
+//	replace  replaces a string by another: replace .Kind "_" " "
+//	indent   indents every line of a string: indent 4 .From
+//	severity returns the severity with a name: ge .Severity (severity "risky")
+//	ofKind   the changes of some kinds: ofKind .Changes "add_column" "drop_column"
+//	atLeast  the changes of a severity or higher: atLeast .Changes "risky"
+var TemplateFuncs = template.FuncMap{
+	"join":    strings.Join,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"replace": func(s, old, new string) string { return strings.Replace(s, old, new, -1) },
+	"indent": func(n int, s string) string {
+		prefix := strings.Repeat(" ", n)
+		return prefix + strings.Replace(s, "\n", "\n"+prefix, -1)
+	},
+	"severity": ParseSeverity,
+	"ofKind": func(cs []Change, kinds ...string) []Change {
+		var res []Change
+		for _, c := range cs {
+			for _, k := range kinds {
+				if string(c.Kind) == k {
+					res = append(res, c)
+				}
+			}
+		}
+		return res
+	},
+	"atLeast": func(cs []Change, name string) ([]Change, error) {
+		severity, err := ParseSeverity(name)
+		if err != nil {
+			return nil, err
+		}
+		var res []Change
+		for _, c := range cs {
+			if c.Severity >= severity {
+				res = append(res, c)
+			}
+		}
+		return res, nil
+	},
+}
+
+// ParseTemplate parses the template file at path, which can use the
+// functions in TemplateFuncs, for a TemplateFormatter.
+func ParseTemplate(path string) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Funcs(TemplateFuncs).ParseFiles(path)
+}
+
+// FormatsMigrations (see MigrationsFormatter)
+func (f *TemplateFormatter) FormatsMigrations() {}
+
+// Format returns the string resulting from executing the template of the
+// formatter with the data of the diff. Errors executing it are logged, and
+// an empty string is returned, see Execute.
+func (f *TemplateFormatter) Format(diff *Diff) interface{} {
+	out, err := f.Execute(diff)
+	if err != nil {
+		log.Error(err)
+		return ""
+	}
+	return out
+}
+
+// Execute returns the string resulting from executing the template of the
+// formatter with the data of the diff, or an error if the formatter has no
+// template or it fails.
+func (f *TemplateFormatter) Execute(diff *Diff) (string, error) {
+	if f.Template == nil {
+		return "", fmt.Errorf("No template given to the template formatter")
+	}
+	var buf bytes.Buffer
+	if err := f.Template.Execute(&buf, newTemplateData(diff)); err != nil {
+		return "", fmt.Errorf("Error executing the template %s: %s", f.Template.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// newTemplateData returns the data model of a diff
+func newTemplateData(diff *Diff) TemplateData {
+	ods := diff.Compute()
+	cs := changes(ods)
+	data := TemplateData{
+		From:     TemplateSchema{Server: diff.DSN1.Addr, Schema: diff.From.Name, Flavor: diff.Flavor1.String()},
+		To:       TemplateSchema{Server: diff.DSN2.Addr, Schema: diff.To.Name, Flavor: diff.Flavor2.String()},
+		Summary:  Summary(cs),
+		Changes:  cs,
+		Warnings: diff.Warnings(),
+		SQL:      (&SQLFormatter{}).Format(diff).(string),
+	}
+
+	groups := groupChanges(cs)
+	data.Kinds = groups.Kinds
+	for _, o := range groups.Objects {
+		data.Objects = append(data.Objects, TemplateObject(o))
+	}
+
+	for _, od := range ods {
+		if md, ok := od.(*MigrationsDiff); ok {
+			data.Migrations = md
+		}
+	}
+	return data
+}
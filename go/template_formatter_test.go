@@ -0,0 +1,82 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestTemplateFormatter_Format(t *testing.T) {
+	table := func(columns ...string) *tengo.Table {
+		table := &tengo.Table{Name: "tasks", Engine: "InnoDB", CharSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci", CollationIsDefault: true}
+		for _, name := range columns {
+			typ := "int"
+			if name == "due_at" {
+				typ = "datetime"
+			}
+			table.Columns = append(table.Columns, &tengo.Column{Name: name, TypeInDB: typ, Nullable: true, Default: tengo.ColumnDefaultNull})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorMySQL80)
+		return table
+	}
+	diff := &Diff{
+		DSN1:     ParseDSN(DSN1),
+		DSN2:     ParseDSN(DSN2),
+		From:     &tengo.Schema{Name: "shop", Tables: []*tengo.Table{table("id", "title", "owner_id")}},
+		To:       &tengo.Schema{Name: "shop", Tables: []*tengo.Table{table("id", "title", "due_at")}},
+		Flavor1:  tengo.FlavorMySQL80,
+		Flavor2:  tengo.FlavorMySQL80,
+		objects1: &Objects{},
+		objects2: &Objects{},
+	}
+
+	dir, err := ioutil.TempDir("", "mydiff")
+	Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "report.tmpl")
+	Nil(t, ioutil.WriteFile(path, []byte(`{{.From.Schema}} on {{.From.Flavor}}: {{.Summary}}
+{{range .Objects}}{{.Name}} ({{.Severity}}):
+{{range .Changes}}- {{replace (print .Kind) "_" " "}} {{.Name}}
+{{end}}{{end}}{{range atLeast .Changes "breaking"}}{{upper .Name}}
+{{indent 2 .From}}
+{{end}}`), 0644))
+
+	tmpl, err := ParseTemplate(path)
+	Nil(t, err)
+	f := &TemplateFormatter{Template: tmpl}
+	Equal(t, "shop on mysql:8.0: Differences found (2): 1 breaking, 1 additive\n"+
+		"table tasks (breaking):\n"+
+		"- drop column owner_id\n"+
+		"- add column due_at\n"+
+		"OWNER_ID\n"+
+		"  `owner_id` int DEFAULT NULL\n", f.Format(diff))
+
+	_, err = ParseTemplate(filepath.Join(dir, "missing.tmpl"))
+	NotNil(t, err)
+
+	// errors executing the template are returned
+	Nil(t, ioutil.WriteFile(path, []byte(`{{range atLeast .Changes "urgent"}}{{.Name}}{{end}}`), 0644))
+	tmpl, err = ParseTemplate(path)
+	Nil(t, err)
+	_, err = (&TemplateFormatter{Template: tmpl}).Execute(diff)
+	NotNil(t, err)
+	_, err = (&TemplateFormatter{}).Execute(diff)
+	NotNil(t, err)
+}
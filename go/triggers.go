@@ -0,0 +1,226 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// ObjectTypeTrigger is the object type of triggers, which tengo doesn't define
+const ObjectTypeTrigger tengo.ObjectType = "trigger"
+
+// Trigger represents a trigger on a table, as introspected from
+// information_schema.TRIGGERS
+type Trigger struct {
+	Name      string
+	Table     string
+	Event     string // INSERT, UPDATE or DELETE
+	Timing    string // BEFORE or AFTER
+	Statement string // the body of the trigger
+	Definer   string
+	SQLMode   string // sql_mode in effect at creation time
+	// Follows is the name of the trigger of the same table, timing and event
+	// preceding this one in their action order, if any.
+	Follows string
+}
+
+// CreateStatement returns a SQL statement that, if run, would create the
+// trigger after the one it follows, if any.
+func (t *Trigger) CreateStatement() string {
+	return t.createStatement("")
+}
+
+// createStatement returns the CREATE statement of the trigger. Unless it
+// follows another trigger, it's created before the given one, if any.
+func (t *Trigger) createStatement(precedes string) string {
+	var order string
+	switch {
+	case t.Follows != "":
+		order = fmt.Sprintf("FOLLOWS %s ", tengo.EscapeIdentifier(t.Follows))
+	case precedes != "":
+		order = fmt.Sprintf("PRECEDES %s ", tengo.EscapeIdentifier(precedes))
+	}
+	return fmt.Sprintf("CREATE %s TRIGGER %s %s %s ON %s FOR EACH ROW %s%s", definerClause(t.Definer), tengo.EscapeIdentifier(t.Name), t.Timing, t.Event, tengo.EscapeIdentifier(t.Table), order, t.Statement)
+}
+
+// DropStatement returns a SQL statement that, if run, would drop the trigger
+func (t *Trigger) DropStatement() string {
+	return fmt.Sprintf("DROP TRIGGER IF EXISTS %s", tengo.EscapeIdentifier(t.Name))
+}
+
+// Equals returns true if two triggers are identical, false otherwise.
+func (t *Trigger) Equals(other *Trigger) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+	return *t == *other
+}
+
+// differences returns the names of the attributes of the triggers that differ
+func (t *Trigger) differences(other *Trigger) []string {
+	var res []string
+	if t.Table != other.Table {
+		res = append(res, "table")
+	}
+	if t.Timing != other.Timing {
+		res = append(res, "timing")
+	}
+	if t.Event != other.Event {
+		res = append(res, "event")
+	}
+	if t.Statement != other.Statement {
+		res = append(res, "body")
+	}
+	if t.Definer != other.Definer {
+		res = append(res, "definer")
+	}
+	if t.SQLMode != other.SQLMode {
+		res = append(res, "sql_mode")
+	}
+	if t.Follows != other.Follows {
+		res = append(res, "order")
+	}
+	return res
+}
+
+// sameEvent returns true if both triggers are run on the same event of the
+// same table, so they are ordered with respect to each other.
+func (t *Trigger) sameEvent(other *Trigger) bool {
+	return t.Table == other.Table && t.Timing == other.Timing && t.Event == other.Event
+}
+
+// TriggerDiff is an implementation of tengo.ObjectDiff representing a
+// trigger that is missing in one of the schemas, or that differs between
+// them, in which case both From and To are set.
+type TriggerDiff struct {
+	From, To *Trigger
+	// Precedes is the name of the existing trigger the created one is placed
+	// before, when it doesn't follow any other, see compareTriggers.
+	Precedes string
+}
+
+// DiffType (see tengo.ObjectDiff)
+func (td *TriggerDiff) DiffType() tengo.DiffType {
+	switch {
+	case td.From == nil:
+		return tengo.DiffTypeCreate
+	case td.To == nil:
+		return tengo.DiffTypeDrop
+	}
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey (see tengo.ObjectDiff)
+func (td *TriggerDiff) ObjectKey() tengo.ObjectKey {
+	if td.To != nil {
+		return tengo.ObjectKey{Type: ObjectTypeTrigger, Name: td.To.Name}
+	}
+	return tengo.ObjectKey{Type: ObjectTypeTrigger, Name: td.From.Name}
+}
+
+// Statement (see tengo.ObjectDiff) returns the statements returned by
+// Statements separated by semicolons and new lines.
+func (td *TriggerDiff) Statement(tengo.StatementModifiers) (string, error) {
+	return strings.Join(td.Statements(), ";\n"), nil
+}
+
+// Statements returns the statements that, if run, would make the trigger in
+// the From schema identical to the one in the To schema. Triggers are
+// created with the sql_mode they were created with in the To schema, which
+// is restored afterwards.
+func (td *TriggerDiff) Statements() []string {
+	var stmts []string
+	if td.From != nil {
+		stmts = append(stmts, td.From.DropStatement())
+	}
+	if td.To != nil {
+		stmts = append(stmts,
+			"SET @mydiff_sql_mode = @@SESSION.sql_mode",
+			fmt.Sprintf("SET SESSION sql_mode = '%s'", td.To.SQLMode),
+			td.To.createStatement(td.Precedes),
+			"SET SESSION sql_mode = @mydiff_sql_mode",
+		)
+	}
+	return stmts
+}
+
+// compareTriggers returns the diffs of the triggers missing in either list,
+// or that differ between them, given in their action order.
+//
+// The triggers missing in the to list are dropped first. Then, the rest are
+// created in the action order of the to list, each of them after the one it
+// follows. The first trigger of a table, timing and event is created before
+// the next one, if it already exists, so its position is kept.
+func compareTriggers(from, to []*Trigger) []tengo.ObjectDiff {
+	var res []tengo.ObjectDiff
+	toByName := map[string]*Trigger{}
+	for _, t := range to {
+		toByName[t.Name] = t
+	}
+	fromByName := map[string]*Trigger{}
+	for _, t := range from {
+		fromByName[t.Name] = t
+		if _, ok := toByName[t.Name]; !ok {
+			res = append(res, &TriggerDiff{From: t})
+		}
+	}
+	for i, t := range to {
+		other, ok := fromByName[t.Name]
+		if ok && t.Equals(other) {
+			continue
+		}
+		td := &TriggerDiff{To: t}
+		if ok {
+			td.From = other
+		}
+		if t.Follows == "" && i+1 < len(to) && to[i+1].Follows == t.Name {
+			if next, ok := fromByName[to[i+1].Name]; ok && next.sameEvent(t) {
+				td.Precedes = next.Name
+			}
+		}
+		res = append(res, td)
+	}
+	return res
+}
+
+// loadTriggers returns the triggers of the given schema, in their action order
+func loadTriggers(db *sql.DB, schema string) ([]*Trigger, error) {
+	rows, err := db.Query(`
+		SELECT trigger_name, event_object_table, event_manipulation, action_timing,
+		       action_statement, definer, sql_mode
+		FROM   information_schema.triggers
+		WHERE  trigger_schema = ?
+		ORDER BY event_object_table, action_timing, event_manipulation, action_order, trigger_name`, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var triggers []*Trigger
+	for rows.Next() {
+		t := &Trigger{}
+		if err := rows.Scan(&t.Name, &t.Table, &t.Event, &t.Timing, &t.Statement, &t.Definer, &t.SQLMode); err != nil {
+			return nil, err
+		}
+		if n := len(triggers); n > 0 && triggers[n-1].sameEvent(t) {
+			t.Follows = triggers[n-1].Name
+		}
+		triggers = append(triggers, t)
+	}
+	return triggers, rows.Err()
+}
@@ -0,0 +1,54 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"testing"
+
+	"github.com/skeema/tengo"
+	. "github.com/stretchr/testify/assert"
+)
+
+func TestCompareTriggers(t *testing.T) {
+	trigger := func(name, follows string) *Trigger {
+		return &Trigger{Name: name, Table: "tasks", Event: "INSERT", Timing: "AFTER", Statement: "SET @last_task = NEW.id", Definer: "root@localhost", Follows: follows}
+	}
+	from := []*Trigger{trigger("audit", ""), trigger("count", "audit"), trigger("notify", "count")}
+	to := []*Trigger{trigger("log", ""), trigger("audit", "log"), trigger("count", "audit")}
+
+	var stmts []string
+	for _, od := range compareTriggers(from, to) {
+		for _, stmt := range statements(od, tengo.StatementModifiers{}) {
+			stmts = append(stmts, delimitedStatement(od, stmt))
+		}
+	}
+	Equal(t, []string{
+		"DROP TRIGGER IF EXISTS `notify`;\n",
+		"SET @mydiff_sql_mode = @@SESSION.sql_mode;\n",
+		"SET SESSION sql_mode = '';\n",
+		"DELIMITER //\nCREATE DEFINER=`root`@`localhost` TRIGGER `log` AFTER INSERT ON `tasks` FOR EACH ROW PRECEDES `audit` SET @last_task = NEW.id//\nDELIMITER ;\n",
+		"SET SESSION sql_mode = @mydiff_sql_mode;\n",
+		"DROP TRIGGER IF EXISTS `audit`;\n",
+		"SET @mydiff_sql_mode = @@SESSION.sql_mode;\n",
+		"SET SESSION sql_mode = '';\n",
+		"DELIMITER //\nCREATE DEFINER=`root`@`localhost` TRIGGER `audit` AFTER INSERT ON `tasks` FOR EACH ROW FOLLOWS `log` SET @last_task = NEW.id//\nDELIMITER ;\n",
+		"SET SESSION sql_mode = @mydiff_sql_mode;\n",
+	}, stmts)
+}
+
+func TestDelimitedStatement(t *testing.T) {
+	Equal(t, "CREATE VIEW `v` AS SELECT 'a;b';\n", delimitedStatement(&ViewDiff{}, "CREATE VIEW `v` AS SELECT 'a;b'"))
+	Equal(t, "DROP TRIGGER IF EXISTS `t`;\n", delimitedStatement(&TriggerDiff{}, "DROP TRIGGER IF EXISTS `t`"))
+	Equal(t, "DELIMITER //\nCREATE TRIGGER `t` AFTER INSERT ON `tasks` FOR EACH ROW SET @n = 1//\nDELIMITER ;\n", delimitedStatement(&TriggerDiff{}, "CREATE TRIGGER `t` AFTER INSERT ON `tasks` FOR EACH ROW SET @n = 1"))
+}
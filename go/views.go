@@ -0,0 +1,219 @@
+// mydiff - Compute the differences between two MySQL schemas.
+//
+// Copyright (c) 2019 Miguel Fernández Fernández
+//
+// This Source Code Form is subject To the terms of MIT License:
+// A short and simple permissive license with conditions only
+// requiring preservation of copyright and license notices.
+// Licensed works, modifications, and larger works may be
+// distributed under different terms and without source code.
+//
+// You can obtain a copy of the license here:
+// https://opensource.org/licenses/MIT
+
+package mydiff
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/skeema/tengo"
+)
+
+// ObjectTypeView is the object type of views, which tengo doesn't define
+const ObjectTypeView tengo.ObjectType = "view"
+
+// viewAlgorithm matches the algorithm in the output of SHOW CREATE VIEW, which
+// information_schema.VIEWS doesn't report.
+var viewAlgorithm = regexp.MustCompile(`ALGORITHM=(\w+)`)
+
+// View represents a view, as introspected from information_schema.VIEWS
+type View struct {
+	Name string
+	// Definition is the SELECT statement of the view, as rewritten by the
+	// server, without qualifying the objects of its own schema.
+	Definition   string
+	Algorithm    string // UNDEFINED, MERGE or TEMPTABLE
+	SecurityType string // DEFINER or INVOKER
+	CheckOption  string // NONE, CASCADED or LOCAL
+	Definer      string
+}
+
+// CreateStatement returns a SQL statement that, if run, would create the
+// view, or replace it if it exists.
+func (v *View) CreateStatement() string {
+	var checkOption string
+	if v.CheckOption != "" && v.CheckOption != "NONE" {
+		checkOption = fmt.Sprintf(" WITH %s CHECK OPTION", v.CheckOption)
+	}
+	return fmt.Sprintf("CREATE OR REPLACE ALGORITHM=%s %s SQL SECURITY %s VIEW %s AS %s%s", v.Algorithm, definerClause(v.Definer), v.SecurityType, tengo.EscapeIdentifier(v.Name), v.Definition, checkOption)
+}
+
+// DropStatement returns a SQL statement that, if run, would drop the view
+func (v *View) DropStatement() string {
+	return fmt.Sprintf("DROP VIEW IF EXISTS %s", tengo.EscapeIdentifier(v.Name))
+}
+
+// Equals returns true if two views are equivalent, even if their
+// definitions are formatted differently.
+func (v *View) Equals(other *View) bool {
+	if v == nil || other == nil {
+		return v == other
+	}
+	return len(v.differences(other)) == 0
+}
+
+// differences returns the names of the attributes of the views that differ
+func (v *View) differences(other *View) []string {
+	var res []string
+	if normalizeViewDefinition(v.Definition) != normalizeViewDefinition(other.Definition) {
+		res = append(res, "definition")
+	}
+	if v.Algorithm != other.Algorithm {
+		res = append(res, "algorithm")
+	}
+	if v.SecurityType != other.SecurityType {
+		res = append(res, "security")
+	}
+	if v.CheckOption != other.CheckOption {
+		res = append(res, "check option")
+	}
+	if v.Definer != other.Definer {
+		res = append(res, "definer")
+	}
+	return res
+}
+
+// normalizeViewDefinition returns the definition of a view without the
+// formatting differences that don't change its meaning: the case of
+// keywords and identifiers, and whitespace. Quoted strings and identifiers
+// are left as they are.
+func normalizeViewDefinition(def string) string {
+	var buf bytes.Buffer
+	var quote rune
+	space := false
+	for _, r := range strings.TrimSpace(def) {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		default:
+			r = unicode.ToLower(r)
+		}
+		if space {
+			buf.WriteRune(' ')
+			space = false
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// ViewDiff is an implementation of tengo.ObjectDiff representing a view that
+// is missing in one of the schemas, or that differs between them, in which
+// case both From and To are set.
+type ViewDiff struct {
+	From, To *View
+}
+
+// DiffType (see tengo.ObjectDiff)
+func (vd *ViewDiff) DiffType() tengo.DiffType {
+	switch {
+	case vd.From == nil:
+		return tengo.DiffTypeCreate
+	case vd.To == nil:
+		return tengo.DiffTypeDrop
+	}
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey (see tengo.ObjectDiff)
+func (vd *ViewDiff) ObjectKey() tengo.ObjectKey {
+	if vd.To != nil {
+		return tengo.ObjectKey{Type: ObjectTypeView, Name: vd.To.Name}
+	}
+	return tengo.ObjectKey{Type: ObjectTypeView, Name: vd.From.Name}
+}
+
+// Statement (see tengo.ObjectDiff). Views that differ are replaced
+// with CREATE OR REPLACE VIEW.
+func (vd *ViewDiff) Statement(tengo.StatementModifiers) (string, error) {
+	if vd.To == nil {
+		return vd.From.DropStatement(), nil
+	}
+	return vd.To.CreateStatement(), nil
+}
+
+// compareViews returns the diffs of the views missing in either list,
+// or that differ between them.
+func compareViews(from, to []*View) []tengo.ObjectDiff {
+	var res []tengo.ObjectDiff
+	toByName := map[string]*View{}
+	for _, v := range to {
+		toByName[v.Name] = v
+	}
+	fromByName := map[string]*View{}
+	for _, v := range from {
+		fromByName[v.Name] = v
+		if other, ok := toByName[v.Name]; !ok {
+			res = append(res, &ViewDiff{From: v})
+		} else if !v.Equals(other) {
+			res = append(res, &ViewDiff{From: v, To: other})
+		}
+	}
+	for _, v := range to {
+		if _, ok := fromByName[v.Name]; !ok {
+			res = append(res, &ViewDiff{To: v})
+		}
+	}
+	return res
+}
+
+// loadViews returns the views of the given schema
+func loadViews(db *sql.DB, schema string) ([]*View, error) {
+	rows, err := db.Query(`
+		SELECT table_name, view_definition, security_type, check_option, definer
+		FROM   information_schema.views
+		WHERE  table_schema = ?
+		ORDER BY table_name`, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []*View
+	for rows.Next() {
+		v := &View{}
+		if err := rows.Scan(&v.Name, &v.Definition, &v.SecurityType, &v.CheckOption, &v.Definer); err != nil {
+			return nil, err
+		}
+		v.Definition = strings.Replace(v.Definition, tengo.EscapeIdentifier(schema)+".", "", -1)
+		views = append(views, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, v := range views {
+		var name, create string
+		query := fmt.Sprintf("SHOW CREATE VIEW %s.%s", tengo.EscapeIdentifier(schema), tengo.EscapeIdentifier(v.Name))
+		var charSet, collation sql.NullString
+		if err := db.QueryRow(query).Scan(&name, &create, &charSet, &collation); err != nil {
+			return nil, err
+		}
+		if m := viewAlgorithm.FindStringSubmatch(create); m != nil {
+			v.Algorithm = m[1]
+		}
+	}
+	return views, nil
+}